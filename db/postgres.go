@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/rotisserie/eris"
+)
+
+// InitPostgres open a PostgreSQL database, the connection is configured by the same DB_* variables
+// as Init, DB_SSLMODE is a sslmode of the connection
+func InitPostgres() *sql.DB {
+	user, ok := os.LookupEnv("DB_USER")
+	if !ok {
+		user = "postgres"
+	}
+
+	pass, ok := os.LookupEnv("DB_PASS")
+	if !ok {
+		pass = "secret"
+	}
+
+	host, ok := os.LookupEnv("DB_HOST")
+	if !ok {
+		host = "localhost"
+	}
+
+	port, ok := os.LookupEnv("DB_PORT")
+	if !ok {
+		port = "5432"
+	}
+
+	name, ok := os.LookupEnv("DB_NAME")
+	if !ok {
+		name = "cake-store"
+	}
+
+	sslmode, ok := os.LookupEnv("DB_SSLMODE")
+	if !ok {
+		sslmode = "disable"
+	}
+
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s", host, port, user, pass, name, sslmode,
+	)
+
+	db, err := OpenPostgres(context.Background(), dsn)
+	if err != nil {
+		log.Fatalf("failed to open postgres db: \n%+v\n", err)
+	}
+
+	return db
+}
+
+// OpenPostgres open a PostgreSQL database. a text column is a CITEXT (see migrations.Postgres),
+// so the citext extension is created when it does not exist yet
+func OpenPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, eris.Wrap(err, "open postgres, an error occurred")
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, eris.Wrap(err, "ping postgres, an error occurred")
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS citext"); err != nil {
+		db.Close()
+		return nil, eris.Wrap(err, "create citext extension, an error occurred")
+	}
+
+	return db, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+
+	"github.com/rotisserie/eris"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteParams enforce a foreign keys like MySQL and make a concurrent writers wait for each other.
+// a transaction begin immediately, so it hold the write lock which replace a row lock (FOR UPDATE)
+const sqliteParams = "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate&_time_format=sqlite"
+
+// InitSQLite open a SQLite database on DB_PATH, it's meant for a local development
+// so the database file is created when it does not exist yet
+func InitSQLite() *sql.DB {
+	path, ok := os.LookupEnv("DB_PATH")
+	if !ok {
+		path = "cake-store.db"
+	}
+
+	db, err := OpenSQLite(context.Background(), path)
+	if err != nil {
+		log.Fatalf("failed to open sqlite db: \n%+v\n", err)
+	}
+
+	return db
+}
+
+// OpenSQLite open a SQLite database file
+func OpenSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+sqliteParams)
+	if err != nil {
+		return nil, eris.Wrap(err, "open sqlite, an error occurred")
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, eris.Wrap(err, "ping sqlite, an error occurred")
+	}
+
+	return db, nil
+}
@@ -0,0 +1,106 @@
+// Package fixtures load a cakes from a YAML or JSON fixtures, it's used by the seed command and by a
+// tests which need a realistic cakes. a fixture is a document with a list of cakes, each cake is
+// written like a body of POST /cakes:
+//
+//	cakes:
+//	  - title: Lemon Cheesecake
+//	    description: A cheesecake made of lemon
+//	    image: https://example.com/lemon.jpeg
+//	    price: 250000
+//	    currency: IDR
+package fixtures
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"gopkg.in/yaml.v3"
+
+	"github.com/zufzuf/cake-store/service"
+)
+
+//go:embed *.yaml
+var files embed.FS
+
+var (
+	ErrUnknownFormat = eris.New("unknown fixture format, use a .yaml, .yml or .json file")
+)
+
+type document struct {
+	Cakes []service.CakeRequest `json:"cakes"`
+}
+
+// Default load the embedded cakes, it's seeded when the seed command does not have any files
+func Default() ([]service.CakeRequest, error) {
+	names, err := fs.Glob(files, "*.yaml")
+	if err != nil {
+		return nil, eris.Wrap(err, "load default fixtures, an error occurred")
+	}
+
+	res := []service.CakeRequest{}
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, eris.Wrapf(err, "read fixture %s, an error occurred", name)
+		}
+
+		cakes, err := Decode(name, data)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cakes...)
+	}
+
+	return res, nil
+}
+
+// Read load the cakes of each files in order
+func Read(paths ...string) ([]service.CakeRequest, error) {
+	res := []service.CakeRequest{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, eris.Wrapf(err, "read fixture %s, an error occurred", path)
+		}
+
+		cakes, err := Decode(path, data)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cakes...)
+	}
+
+	return res, nil
+}
+
+// Decode decode the cakes of a fixture, the format is chosen by an extension of it's name
+func Decode(name string, data []byte) ([]service.CakeRequest, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+	case ".yaml", ".yml":
+		// a YAML is converted into a JSON first, so a cake is decoded by it's json tags like the api
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, eris.Wrapf(err, "decode fixture %s, an error occurred", name)
+		}
+
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, eris.Wrapf(err, "decode fixture %s, an error occurred", name)
+		}
+	default:
+		return nil, eris.Wrap(ErrUnknownFormat, name)
+	}
+
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, eris.Wrapf(err, "decode fixture %s, an error occurred", name)
+	}
+
+	return doc.Cakes, nil
+}
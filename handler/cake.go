@@ -3,9 +3,12 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rotisserie/eris"
@@ -16,24 +19,41 @@ import (
 )
 
 type CakeService interface {
-	Find(ctx context.Context, id int) (*schema.Cake, error)
+	Find(ctx context.Context, id int, embed ...string) (*schema.Cake, error)
 	FindAll(ctx context.Context, fil *service.FindAllRequest) ([]schema.Cake, error)
 	Insert(ctx context.Context, req *service.CakeRequest) error
 	Update(ctx context.Context, req *service.CakeRequest) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+	History(ctx context.Context, id int) ([]schema.CakeRevision, error)
+	Diff(ctx context.Context, id int, from, to int) ([]schema.FieldChange, error)
+	PriceHistory(ctx context.Context, req *service.PriceHistoryRequest) ([]schema.CakePrice, error)
+	Export(ctx context.Context, req *service.FindAllRequest, fn func(rec *schema.Cake) error) error
+	Import(ctx context.Context, rows service.CakeReader, mode string) (*service.ImportReport, error)
+	Batch(ctx context.Context, req *service.BatchRequest) (*service.BatchReport, error)
 }
 
 type Cake struct {
 	Service CakeService
+
+	// StrictIfMatch reject an update or delete without If-Match header
+	StrictIfMatch bool
 }
 
 func (h *Cake) FindCake(rw http.ResponseWriter, r *http.Request) {
 	var (
 		ctx   = r.Context()
 		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		q     = newQueryParser(r.URL.Query())
+		embed = q.List("embed", service.Embeds...)
 	)
 
-	res, err := h.Service.Find(ctx, id)
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.Find(ctx, id, embed...)
 	if err != nil {
 		if !eris.Is(err, repository.ErrRecordNotFound) {
 			util.ErrHTTPResponse(ctx, rw, err)
@@ -44,35 +64,113 @@ func (h *Cake) FindCake(rw http.ResponseWriter, r *http.Request) {
 	msg := "not found"
 	if res != nil {
 		msg = "found"
+		rw.Header().Set("ETag", ETag(res.Version))
 	}
 
 	util.HTTPResponse(rw, http.StatusOK, "search cake "+msg, res)
 }
 
 func (h *Cake) FindAllCake(rw http.ResponseWriter, r *http.Request) {
+	h.findAll(rw, r, false)
+}
+
+func (h *Cake) FindAllTrashedCake(rw http.ResponseWriter, r *http.Request) {
+	h.findAll(rw, r, true)
+}
+
+func (h *Cake) findAll(rw http.ResponseWriter, r *http.Request, trashed bool) {
 	var (
 		ctx = r.Context()
-		q   = r.URL.Query()
-		fil = service.FindAllRequest{
-			Title:       q.Get("title"),
-			Description: q.Get("description"),
-		}
+		q   = newQueryParser(r.URL.Query())
+		fil = cakeFilter(q, trashed)
 	)
 
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
 	res, err := h.Service.FindAll(ctx, &fil)
 	if err != nil {
+		if ok := UnsupportedFilter(rw, err); !ok {
+			return
+		}
+		if eris.Is(err, repository.ErrInvalidCursor) {
+			QueryValidation(rw, []util.ValidationError{{
+				Key:     "cursor",
+				Rule:    "cursor",
+				Message: "cursor is invalid",
+			}})
+			return
+		}
 		if !eris.Is(err, repository.ErrRecordNotFound) {
 			util.ErrHTTPResponse(ctx, rw, err)
 			return
 		}
 	}
 
-	msg := "not found"
+	msg := "search cakes"
+	if trashed {
+		msg = "search trashed cakes"
+	}
+
 	if len(res) > 0 {
-		msg = "found"
+		msg += " found"
+	} else {
+		msg += " not found"
+	}
+
+	util.HTTPResponseWithMeta(rw, http.StatusOK, msg, res, util.Pagination{
+		NextCursor: fil.NextCursor,
+		HasMore:    fil.HasMore,
+	})
+}
+
+// UnsupportedFilter write an unprocessable response for a filter which the storage can not apply,
+// it return false when the response is written
+func UnsupportedFilter(rw http.ResponseWriter, err error) bool {
+	var unsupported *repository.UnsupportedFilterError
+	if !errors.As(err, &unsupported) {
+		return true
+	}
+
+	return QueryValidation(rw, []util.ValidationError{{
+		Key:     unsupported.Key,
+		Rule:    "supported",
+		Message: unsupported.Key + " is not supported by the storage driver",
+	}})
+}
+
+// cakeFilter parse a filters of a cakes from url query, a malformed value is collected on the parser
+func cakeFilter(q *queryParser, trashed bool) service.FindAllRequest {
+	fil := service.FindAllRequest{
+		Trashed:          trashed,
+		Title:            q.String("title"),
+		Description:      q.String("description"),
+		Search:           q.String("q"),
+		SearchMode:       q.OneOf("search_mode", repository.SearchModeNatural, repository.SearchModeBoolean),
+		Category:         q.String("category"),
+		Tag:              q.String("tag"),
+		InStock:          q.Bool("in_stock"),
+		ExcludeAllergens: q.List("exclude_allergens", schema.Allergens...),
+		RatingMin:        q.Float("rating_min"),
+		RatingMax:        q.Float("rating_max"),
+		Currency:         strings.ToUpper(q.String("currency")),
+		PriceMin:         q.Int64("price_min", 0, math.MaxInt64),
+		PriceMax:         q.Int64("price_max", 0, math.MaxInt64),
+		CreatedAfter:     q.Time("created_after"),
+		CreatedBefore:    q.Time("created_before"),
+		UpdatedSince:     q.Time("updated_since"),
+		Sort:             q.Sort("sort"),
+		Limit:            q.Int("limit", 1, repository.MaxLimit),
+		Cursor:           q.String("cursor"),
 	}
 
-	util.HTTPResponse(rw, http.StatusOK, "search cakes "+msg, res)
+	q.Range("rating_min", fil.RatingMin, "rating_max", fil.RatingMax)
+	q.Int64Range("price_min", fil.PriceMin, "price_max", fil.PriceMax)
+	q.TimeRange("created_after", fil.CreatedAfter, "created_before", fil.CreatedBefore)
+	q.SortRequires("sort", fil.Sort, "relevance", "q", fil.Search)
+
+	return fil
 }
 
 func (h *Cake) AddCake(rw http.ResponseWriter, r *http.Request) {
@@ -86,6 +184,10 @@ func (h *Cake) AddCake(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.Service.Insert(ctx, &body); err != nil {
+		if eris.Is(err, service.ErrCategoryNotFound) {
+			CategoryNotFound(rw)
+			return
+		}
 		util.ErrHTTPResponse(ctx, rw, err)
 		return
 	}
@@ -102,18 +204,35 @@ func (h *Cake) UpdateCake(rw http.ResponseWriter, r *http.Request) {
 		body  = service.CakeRequest{}
 	)
 
+	version, ok := IfMatch(rw, r, h.StrictIfMatch)
+	if !ok {
+		return
+	}
+
 	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
 		return
 	}
 
-	body.ID = id
+	body.ID, body.Version = id, version
 	if err := h.Service.Update(ctx, &body); err != nil {
+		if eris.Is(err, repository.ErrVersionConflict) {
+			util.ErrorHTTPResponse(rw, http.StatusPreconditionFailed, "updating a cake, version conflict", nil)
+			return
+		}
+		if eris.Is(err, service.ErrCategoryNotFound) {
+			CategoryNotFound(rw)
+			return
+		}
 		if !eris.Is(err, repository.ErrRecordNotFound) {
 			util.ErrHTTPResponse(ctx, rw, err)
 			return
 		}
 	}
 
+	if body.Version > 0 {
+		rw.Header().Set("ETag", ETag(body.Version))
+	}
+
 	util.HTTPResponse(rw, http.StatusOK, "updating a cake", map[string]int{
 		"id": id,
 	})
@@ -125,7 +244,16 @@ func (h *Cake) DeleteCake(rw http.ResponseWriter, r *http.Request) {
 		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
 	)
 
-	if err := h.Service.Delete(ctx, id); err != nil {
+	version, ok := IfMatch(rw, r, h.StrictIfMatch)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.Delete(ctx, id, version); err != nil {
+		if eris.Is(err, repository.ErrVersionConflict) {
+			util.ErrorHTTPResponse(rw, http.StatusPreconditionFailed, "deleting a cake, version conflict", nil)
+			return
+		}
 		if !eris.Is(err, repository.ErrRecordNotFound) {
 			util.ErrHTTPResponse(ctx, rw, err)
 			return
@@ -137,6 +265,158 @@ func (h *Cake) DeleteCake(rw http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Cake) RestoreCake(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	if err := h.Service.Restore(ctx, id); err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "restoring a cake, record not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "restoring a cake", map[string]int{
+		"id": id,
+	})
+}
+
+func (h *Cake) PurgeCake(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	if err := h.Service.Purge(ctx, id); err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "purging a cake, record not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "purging a cake", map[string]int{
+		"id": id,
+	})
+}
+
+func (h *Cake) FindCakeHistory(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.History(ctx, id)
+	if err != nil {
+		if eris.Is(err, service.ErrNotSupported) {
+			NotSupported(rw, "search cake history")
+			return
+		}
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search cake history "+msg, res)
+}
+
+func (h *Cake) DiffCakeHistory(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		q     = newQueryParser(r.URL.Query())
+		from  = q.RequiredInt("from", 1, math.MaxInt32)
+		to    = q.RequiredInt("to", 1, math.MaxInt32)
+	)
+
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.Diff(ctx, id, from, to)
+	if err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "diff cake history, revision not found", nil)
+			return
+		}
+		if eris.Is(err, service.ErrNotSupported) {
+			NotSupported(rw, "diff cake history")
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "diff cake history", res)
+}
+
+func (h *Cake) FindCakePrices(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		q     = newQueryParser(r.URL.Query())
+		req   = service.PriceHistoryRequest{
+			CakeID: id,
+			Limit:  q.Int("limit", 1, repository.MaxLimit),
+			Before: q.Int("before", 1, math.MaxInt32),
+		}
+	)
+
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.PriceHistory(ctx, &req)
+	if err != nil {
+		if eris.Is(err, service.ErrNotSupported) {
+			NotSupported(rw, "search cake prices")
+			return
+		}
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search cake prices "+msg, res)
+}
+
+// CategoryNotFound write an unprocessable response for an unknown category id on a request body
+func CategoryNotFound(rw http.ResponseWriter) {
+	var (
+		code = http.StatusUnprocessableEntity
+		msg  = "unprocessable request body, an error occured"
+	)
+	util.ErrorHTTPResponse(rw, code, msg, map[string]any{
+		"validation": []util.ValidationError{{
+			Key:     "category_ids",
+			Rule:    "exists",
+			Message: "category_ids must contain an existing category",
+		}},
+	})
+}
+
+// NotSupported write a not implemented response for a feature which is not wired on the server
+func NotSupported(rw http.ResponseWriter, action string) {
+	util.ErrorHTTPResponse(rw, http.StatusNotImplemented, action+", not supported by the server", nil)
+}
+
 func JSONDecodeValidation(rw http.ResponseWriter, reqBody io.ReadCloser, data any) bool {
 	if err := json.NewDecoder(reqBody).Decode(data); err != nil {
 		var (
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func (h *Cake) BatchCake(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		body = service.BatchRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	res, err := h.Service.Batch(ctx, &body)
+	if err != nil {
+		if eris.Is(err, service.ErrBatchFailed) {
+			util.ErrorHTTPResponse(rw, http.StatusUnprocessableEntity, "batching cakes, every operations are rolled back", res)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "batching cakes", res)
+}
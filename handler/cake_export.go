@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/libs/xlsx"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatXLSX   = "xlsx"
+)
+
+var ExportFormats = []string{ExportFormatCSV, ExportFormatNDJSON, ExportFormatXLSX}
+
+// exportWriteTimeout is a time to write the next part of an export, the server WriteTimeout is extended
+// before each write so a large export is not cut while it's still streamed
+const exportWriteTimeout = 30 * time.Second
+
+// formulaPrefixes start a formula in a spreadsheet, a text of a CSV export which start with one of them
+// is prefixed by a quote so it's opened as a text instead of being run (CSV injection)
+const formulaPrefixes = "=+-@\t\r"
+
+// exportColumn is a column of an exported cake, a value is one of nil, int, int64, float64, string or time.Time
+type exportColumn struct {
+	Name  string
+	Value func(rec *schema.Cake) any
+}
+
+// exportColumns is a columns of a CSV and XLSX export, a column of an import has the same name
+// so an export can be imported back. a nutrition column is empty when a cake does not have it
+var exportColumns = []exportColumn{
+	{"id", func(rec *schema.Cake) any { return rec.ID }},
+	{"title", func(rec *schema.Cake) any { return rec.Title }},
+	{"description", func(rec *schema.Cake) any { return rec.Description }},
+	{"image", func(rec *schema.Cake) any { return rec.Image }},
+	{"price", func(rec *schema.Cake) any { return rec.Price }},
+	{"currency", func(rec *schema.Cake) any { return rec.Currency }},
+	{"category_ids", func(rec *schema.Cake) any {
+		ids := make([]string, len(rec.Categories))
+		for i, c := range rec.Categories {
+			ids[i] = strconv.Itoa(c.ID)
+		}
+		return strings.Join(ids, listSeparator)
+	}},
+	{"tags", func(rec *schema.Cake) any {
+		names := make([]string, len(rec.Tags))
+		for i, t := range rec.Tags {
+			names[i] = t.Name
+		}
+		return strings.Join(names, listSeparator)
+	}},
+	{"allergens", func(rec *schema.Cake) any { return strings.Join(rec.Allergens, listSeparator) }},
+	{"serving_size", nutrition(func(n *schema.Nutrition) float64 { return n.ServingSize })},
+	{"energy", nutrition(func(n *schema.Nutrition) float64 { return n.Energy })},
+	{"fat", nutrition(func(n *schema.Nutrition) float64 { return n.Fat })},
+	{"saturated_fat", nutrition(func(n *schema.Nutrition) float64 { return n.SaturatedFat })},
+	{"carbohydrate", nutrition(func(n *schema.Nutrition) float64 { return n.Carbohydrate })},
+	{"sugars", nutrition(func(n *schema.Nutrition) float64 { return n.Sugars })},
+	{"protein", nutrition(func(n *schema.Nutrition) float64 { return n.Protein })},
+	{"salt", nutrition(func(n *schema.Nutrition) float64 { return n.Salt })},
+	{"rating", func(rec *schema.Cake) any { return rec.Rating }},
+	{"version", func(rec *schema.Cake) any { return rec.Version }},
+	{"created_at", func(rec *schema.Cake) any { return rec.CreatedAt }},
+	{"updated_at", func(rec *schema.Cake) any { return rec.UpdatedAt }},
+}
+
+func nutrition(value func(n *schema.Nutrition) float64) func(rec *schema.Cake) any {
+	return func(rec *schema.Cake) any {
+		if rec.Nutrition == nil {
+			return nil
+		}
+		return value(rec.Nutrition)
+	}
+}
+
+// cakeExporter write an exported cakes in a format, Close complete the file
+type cakeExporter interface {
+	Write(rec *schema.Cake) error
+	Close() error
+}
+
+// ExportCake stream every cakes of the same filters as FindAllCake as a file, limit and cursor is ignored.
+// a response is started on the first cake, so a failure after it abort the response instead
+func (h *Cake) ExportCake(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		q      = newQueryParser(r.URL.Query())
+		fil    = cakeFilter(q, false)
+		format = q.OneOf("format", ExportFormats...)
+	)
+
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	var (
+		exp     cakeExporter
+		started bool
+	)
+	start := func() error {
+		var contentType string
+		switch format {
+		case ExportFormatNDJSON:
+			contentType = ContentTypeNDJSON
+		case ExportFormatXLSX:
+			contentType = xlsx.ContentType
+		default:
+			contentType = ContentTypeCSV + "; charset=utf-8"
+		}
+
+		name := "cakes-" + time.Now().Format("20060102") + "." + format
+		rw.Header().Set("Content-Type", contentType)
+		rw.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		rw.Header().Set("X-Content-Type-Options", "nosniff")
+		rw.WriteHeader(http.StatusOK)
+		started = true
+
+		var err error
+		exp, err = newCakeExporter(format, &deadlineWriter{w: rw, rc: http.NewResponseController(rw), timeout: exportWriteTimeout})
+		return err
+	}
+
+	err := h.Service.Export(ctx, &fil, func(rec *schema.Cake) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return exp.Write(rec)
+	})
+	if err == nil && !started {
+		// an empty export is still a file with a header
+		err = start()
+	}
+	if err == nil {
+		err = exp.Close()
+	}
+
+	if err != nil {
+		if !started {
+			if ok := UnsupportedFilter(rw, err); !ok {
+				return
+			}
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+
+		// the status has been sent, so the connection is aborted to let the client know the file is incomplete
+		util.LogError(ctx, err)
+		abortResponse(rw)
+	}
+}
+
+// abortResponse close a connection of a started response, a panic of http.ErrAbortHandler alone is
+// recovered by the Recoverer middleware which let the response complete as if nothing happened
+func abortResponse(rw http.ResponseWriter) {
+	if hj, ok := rw.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+func newCakeExporter(format string, w io.Writer) (cakeExporter, error) {
+	switch format {
+	case ExportFormatNDJSON:
+		return &ndjsonCakeExporter{enc: json.NewEncoder(w)}, nil
+	case ExportFormatXLSX:
+		xw, err := xlsx.NewWriter(w, "Cakes")
+		if err != nil {
+			return nil, err
+		}
+		exp := &xlsxCakeExporter{w: xw}
+		return exp, exp.header()
+	default:
+		exp := &csvCakeExporter{w: csv.NewWriter(w)}
+		return exp, exp.header()
+	}
+}
+
+// csvCakeExporter write a cake per row with a header of exportColumns, a text is escaped by escapeFormula
+type csvCakeExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvCakeExporter) header() error {
+	record := make([]string, len(exportColumns))
+	for i, col := range exportColumns {
+		record[i] = col.Name
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvCakeExporter) Write(rec *schema.Cake) error {
+	record := make([]string, len(exportColumns))
+	for i, col := range exportColumns {
+		switch v := col.Value(rec).(type) {
+		case nil:
+		case int:
+			record[i] = strconv.Itoa(v)
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		case string:
+			record[i] = escapeFormula(v)
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvCakeExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonCakeExporter write a cake per line like a cake of GET /cakes
+type ndjsonCakeExporter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonCakeExporter) Write(rec *schema.Cake) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *ndjsonCakeExporter) Close() error {
+	return nil
+}
+
+// xlsxCakeExporter write a cake per row of a sheet with a header of exportColumns, a text is written
+// as an inline string which is never run as a formula, so it's written as is
+type xlsxCakeExporter struct {
+	w *xlsx.Writer
+}
+
+func (e *xlsxCakeExporter) header() error {
+	cells := make([]any, len(exportColumns))
+	for i, col := range exportColumns {
+		cells[i] = col.Name
+	}
+	return e.w.Write(cells)
+}
+
+func (e *xlsxCakeExporter) Write(rec *schema.Cake) error {
+	cells := make([]any, len(exportColumns))
+	for i, col := range exportColumns {
+		cells[i] = col.Value(rec)
+	}
+	return e.w.Write(cells)
+}
+
+func (e *xlsxCakeExporter) Close() error {
+	return e.w.Close()
+}
+
+// escapeFormula prefix a text which start with one of formulaPrefixes by a quote
+func escapeFormula(v string) string {
+	if len(v) > 0 && strings.IndexByte(formulaPrefixes, v[0]) >= 0 {
+		return "'" + v
+	}
+	return v
+}
+
+// unescapeFormula remove a quote of escapeFormula, so an exported CSV is imported back as it was
+func unescapeFormula(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.IndexByte(formulaPrefixes, v[1]) >= 0 {
+		return v[1:]
+	}
+	return v
+}
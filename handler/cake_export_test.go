@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+var exportedCakes = []schema.Cake{
+	{
+		ID:          1,
+		Title:       "Lemon cheesecake",
+		Description: "A cheesecake, made of \"lemon\"",
+		Image:       "https://example.com/lemon.jpeg",
+		Rating:      8.5,
+		Price:       150000,
+		Currency:    "IDR",
+		Categories:  []schema.Category{{ID: 1}, {ID: 3}},
+		Tags:        []schema.Tag{{Name: "vegan"}, {Name: "gluten free"}},
+		Allergens:   []string{"eggs", "milk"},
+		Nutrition:   &schema.Nutrition{ServingSize: 100, Energy: 350, Fat: 12.5},
+		Version:     2,
+		CreatedAt:   time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt:   time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC),
+	},
+	{
+		ID:          2,
+		Title:       "=HYPERLINK(\"https://evil.example.com\")",
+		Description: "-2+3",
+		Image:       "@SUM(A1)",
+		Currency:    "+62",
+		Version:     1,
+		CreatedAt:   time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt:   time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+	},
+}
+
+// export write the cakes in a format
+func export(t *testing.T, format string, cakes []schema.Cake) []byte {
+	var buf bytes.Buffer
+
+	exp, err := newCakeExporter(format, &buf)
+	assert.NoError(t, err)
+	for i := range cakes {
+		assert.NoError(t, exp.Write(&cakes[i]))
+	}
+	assert.NoError(t, exp.Close())
+
+	return buf.Bytes()
+}
+
+func Test_Escape_Formula(t *testing.T) {
+	tests := []struct {
+		Value  string
+		Result string
+	}{
+		{Value: "", Result: ""},
+		{Value: "Lemon cheesecake", Result: "Lemon cheesecake"},
+		{Value: "=1+1", Result: "'=1+1"},
+		{Value: "+62", Result: "'+62"},
+		{Value: "-2", Result: "'-2"},
+		{Value: "@SUM(A1)", Result: "'@SUM(A1)"},
+		{Value: "\t=1+1", Result: "'\t=1+1"},
+		{Value: "\r=1+1", Result: "'\r=1+1"},
+		{Value: "'=1+1", Result: "'=1+1"},
+		{Value: "a=1+1", Result: "a=1+1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Value, func(t *testing.T) {
+			assert.Equal(t, test.Result, escapeFormula(test.Value))
+			if test.Value != test.Result {
+				assert.Equal(t, test.Value, unescapeFormula(test.Result))
+			}
+		})
+	}
+
+	// a quote which is not an escape is kept
+	assert.Equal(t, "'quoted'", unescapeFormula("'quoted'"))
+	assert.Equal(t, "'", unescapeFormula("'"))
+}
+
+func Test_CSV_Cake_Exporter(t *testing.T) {
+	res := export(t, ExportFormatCSV, exportedCakes)
+
+	assert.Equal(t, "id,title,description,image,price,currency,category_ids,tags,allergens,"+
+		"serving_size,energy,fat,saturated_fat,carbohydrate,sugars,protein,salt,rating,version,created_at,updated_at\n"+
+		"1,Lemon cheesecake,\"A cheesecake, made of \"\"lemon\"\"\",https://example.com/lemon.jpeg,150000,IDR,1|3,vegan|gluten free,eggs|milk,"+
+		"100,350,12.5,0,0,0,0,0,8.5,2,2022-01-02T03:04:05Z,2022-02-03T04:05:06Z\n"+
+		"2,\"'=HYPERLINK(\"\"https://evil.example.com\"\")\",'-2+3,'@SUM(A1),0,'+62,,,,"+
+		",,,,,,,,0,1,2022-01-02T03:04:05Z,2022-01-02T03:04:05Z\n", string(res))
+
+	// an export is imported back as it was
+	r, err := newCSVCakeReader(bytes.NewReader(res))
+	assert.NoError(t, err)
+	rows, err := readAll(r)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	price := int64(150000)
+	assert.Equal(t, service.CakeRequest{
+		Title:       "Lemon cheesecake",
+		Description: "A cheesecake, made of \"lemon\"",
+		Image:       "https://example.com/lemon.jpeg",
+		Price:       &price,
+		Currency:    "IDR",
+		CategoryIDs: []int{1, 3},
+		Tags:        []string{"vegan", "gluten free"},
+		Allergens:   []string{"eggs", "milk"},
+		Nutrition:   &service.NutritionRequest{ServingSize: 100, Energy: 350, Fat: 12.5},
+	}, rows[0].Request)
+	assert.Equal(t, "=HYPERLINK(\"https://evil.example.com\")", rows[1].Request.Title)
+	assert.Equal(t, "-2+3", rows[1].Request.Description)
+	assert.Equal(t, "@SUM(A1)", rows[1].Request.Image)
+	assert.Equal(t, "+62", rows[1].Request.Currency)
+}
+
+func Test_NDJSON_Cake_Exporter(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(export(t, ExportFormatNDJSON, exportedCakes)), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	// a cake is written as is, like a cake of GET /cakes
+	for i, line := range lines {
+		var res schema.Cake
+		assert.NoError(t, json.Unmarshal([]byte(line), &res))
+		assert.Equal(t, exportedCakes[i].Title, res.Title)
+		assert.Equal(t, exportedCakes[i].Description, res.Description)
+	}
+
+	assert.Empty(t, export(t, ExportFormatNDJSON, nil))
+}
+
+func Test_XLSX_Cake_Exporter(t *testing.T) {
+	res := export(t, ExportFormatXLSX, exportedCakes)
+
+	zr, err := zip.NewReader(bytes.NewReader(res), int64(len(res)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type    string `xml:"t,attr"`
+				Value   string `xml:"v"`
+				Formula string `xml:"f"`
+				Text    string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		assert.NoError(t, err)
+		b, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+		assert.NoError(t, xml.Unmarshal(b, &sheet))
+	}
+
+	assert.Len(t, sheet.Rows, 3)
+	for i, col := range exportColumns {
+		assert.Equal(t, col.Name, sheet.Rows[0].Cells[i].Text)
+	}
+
+	lemon := sheet.Rows[1].Cells
+	assert.Equal(t, "1", lemon[0].Value)
+	assert.Equal(t, "Lemon cheesecake", lemon[1].Text)
+	assert.Equal(t, "150000", lemon[4].Value)
+	assert.Equal(t, "1|3", lemon[6].Text)
+	assert.Equal(t, "12.5", lemon[11].Value)
+	assert.Equal(t, "2022-01-02T03:04:05Z", lemon[19].Text)
+
+	// a formula is written as an inline string without a quote, so it's shown as a text
+	evil := sheet.Rows[2].Cells
+	for i, v := range []string{"=HYPERLINK(\"https://evil.example.com\")", "-2+3", "@SUM(A1)"} {
+		assert.Equal(t, "inlineStr", evil[i+1].Type)
+		assert.Equal(t, v, evil[i+1].Text)
+		assert.Empty(t, evil[i+1].Formula)
+	}
+	assert.Equal(t, "+62", evil[5].Text)
+	assert.Empty(t, evil[9].Value)
+}
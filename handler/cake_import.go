@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/service"
+)
+
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// listSeparator separate a list on a CSV column, e.g. tags
+const listSeparator = "|"
+
+// maxImportLine is a maximum length of a NDJSON line
+const maxImportLine = 1 << 20
+
+// importReadTimeout is a time to read the next part of an import body, the server ReadTimeout is extended
+// before each read so a large import is not cut while it's still sent. importWriteTimeout is a time to
+// write the report after the rows are imported
+const (
+	importReadTimeout  = 30 * time.Second
+	importWriteTimeout = 30 * time.Second
+)
+
+var errMalformedImport = eris.New("malformed import body")
+
+func (h *Cake) ImportCake(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		q    = newQueryParser(r.URL.Query())
+		mode = q.OneOf("mode", service.ImportModes...)
+	)
+
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	var (
+		rc   = http.NewResponseController(rw)
+		body = &deadlineReader{r: r.Body, rc: rc, timeout: importReadTimeout}
+		rows service.CakeReader
+	)
+	switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+	case ContentTypeCSV:
+		reader, err := newCSVCakeReader(body)
+		if err != nil {
+			util.ErrorHTTPResponse(rw, http.StatusBadRequest, "parse request body, an error occured", nil)
+			return
+		}
+		rows = reader
+	case ContentTypeNDJSON, "application/jsonl", "application/x-jsonlines":
+		rows = newNDJSONCakeReader(body)
+	default:
+		util.ErrorHTTPResponse(rw, http.StatusUnsupportedMediaType,
+			"importing cakes, content type must be "+ContentTypeCSV+" or "+ContentTypeNDJSON, nil)
+		return
+	}
+
+	res, err := h.Service.Import(ctx, rows, mode)
+
+	// the server WriteTimeout has been running since the request is read
+	rc.SetWriteDeadline(time.Now().Add(importWriteTimeout))
+
+	if err != nil {
+		switch {
+		case eris.Is(err, service.ErrImportFailed):
+			util.ErrorHTTPResponse(rw, http.StatusUnprocessableEntity, "importing cakes, every rows are rolled back", res)
+		case eris.Is(err, errMalformedImport):
+			// a best effort import report the rows which are inserted before the body is broken
+			util.ErrorHTTPResponse(rw, http.StatusBadRequest, "parse request body, an error occured", res)
+		default:
+			util.ErrHTTPResponse(ctx, rw, err)
+		}
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "importing cakes", res)
+}
+
+// csvCakeReader read a cakes of a CSV with a header, a row is read one by one from the body.
+// a column is named like a body of POST /cakes, a nutrition is flattened into it's own columns
+// and it's empty when serving_size is empty. an unknown column is ignored and a formula escaped by
+// a CSV export is unescaped
+type csvCakeReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVCakeReader(body io.Reader) (*csvCakeReader, error) {
+	r := csv.NewReader(body)
+	r.ReuseRecord = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, eris.Wrap(errMalformedImport, "a header is required")
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	return &csvCakeReader{r: r, columns: columns}, nil
+}
+
+func (c *csvCakeReader) Read() (*service.ImportRow, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		// a malformed row is reported, the reader continue on the next row
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &service.ImportRow{Line: parseErr.StartLine, Errors: []util.ValidationError{{
+				Key:     "row",
+				Rule:    "csv",
+				Message: parseErr.Err.Error(),
+			}}}, nil
+		}
+		return nil, eris.Wrap(errMalformedImport, err.Error())
+	}
+
+	line, _ := c.r.FieldPos(0)
+	p := rowParser{get: func(key string) string {
+		if i, ok := c.columns[key]; ok {
+			return unescapeFormula(strings.TrimSpace(record[i]))
+		}
+		return ""
+	}}
+
+	req := service.CakeRequest{
+		Title:       p.get("title"),
+		Description: p.get("description"),
+		Image:       p.get("image"),
+		Price:       p.Int64("price"),
+		Currency:    strings.ToUpper(p.get("currency")),
+		CategoryIDs: p.Ints("category_ids"),
+		Tags:        p.List("tags"),
+		Allergens:   p.List("allergens"),
+	}
+
+	if len(p.get("serving_size")) > 0 {
+		req.Nutrition = &service.NutritionRequest{
+			ServingSize:  p.Float("serving_size"),
+			Energy:       p.Float("energy"),
+			Fat:          p.Float("fat"),
+			SaturatedFat: p.Float("saturated_fat"),
+			Carbohydrate: p.Float("carbohydrate"),
+			Sugars:       p.Float("sugars"),
+			Protein:      p.Float("protein"),
+			Salt:         p.Float("salt"),
+		}
+	}
+
+	return &service.ImportRow{Line: line, Request: req, Errors: p.errs}, nil
+}
+
+// rowParser parse a typed value of a CSV row, every malformed value is collected as a validation error
+type rowParser struct {
+	get  func(key string) string
+	errs []util.ValidationError
+}
+
+func (p *rowParser) fail(key, rule, message string) {
+	p.errs = append(p.errs, util.ValidationError{
+		Key:     key,
+		Rule:    rule,
+		Message: message,
+	})
+}
+
+// Int64 parse an optional integer, nil is returned when the column is empty
+func (p *rowParser) Int64(key string) *int64 {
+	val := p.get(key)
+	if len(val) == 0 {
+		return nil
+	}
+
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		p.fail(key, "number", fmt.Sprintf("%s must be a valid integer", key))
+		return nil
+	}
+
+	return &n
+}
+
+func (p *rowParser) Float(key string) float64 {
+	val := p.get(key)
+	if len(val) == 0 {
+		return 0
+	}
+
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		p.fail(key, "number", fmt.Sprintf("%s must be a valid number", key))
+		return 0
+	}
+
+	return n
+}
+
+// List parse an optional list separated by listSeparator, nil is returned when the column is empty
+func (p *rowParser) List(key string) []string {
+	val := p.get(key)
+	if len(val) == 0 {
+		return nil
+	}
+
+	res := strings.Split(val, listSeparator)
+	for i := range res {
+		res[i] = strings.TrimSpace(res[i])
+	}
+
+	return res
+}
+
+func (p *rowParser) Ints(key string) []int {
+	items := p.List(key)
+	if items == nil {
+		return nil
+	}
+
+	res := make([]int, len(items))
+	for i, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			p.fail(key, "number", fmt.Sprintf("%s must be a %s separated list of integers", key, listSeparator))
+			return nil
+		}
+		res[i] = n
+	}
+
+	return res
+}
+
+// ndjsonCakeReader read a cakes of a JSON lines, each line is a body of POST /cakes and a blank line is skipped
+type ndjsonCakeReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newNDJSONCakeReader(body io.Reader) *ndjsonCakeReader {
+	s := bufio.NewScanner(body)
+	s.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	return &ndjsonCakeReader{s: s}
+}
+
+func (n *ndjsonCakeReader) Read() (*service.ImportRow, error) {
+	for n.s.Scan() {
+		n.line++
+
+		data := bytes.TrimSpace(n.s.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := service.ImportRow{Line: n.line}
+		if err := json.Unmarshal(data, &row.Request); err != nil {
+			row.Request = service.CakeRequest{}
+			row.Errors = []util.ValidationError{{
+				Key:     "row",
+				Rule:    "json",
+				Message: "row must be a valid JSON object of a cake",
+			}}
+		}
+		return &row, nil
+	}
+
+	if err := n.s.Err(); err != nil {
+		return nil, eris.Wrap(errMalformedImport, err.Error())
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/service"
+)
+
+// readAll read every rows of an import until io.EOF or another error
+func readAll(r service.CakeReader) ([]service.ImportRow, error) {
+	var res []service.ImportRow
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+		res = append(res, *row)
+	}
+}
+
+func Test_Row_Parser(t *testing.T) {
+	row := map[string]string{
+		"price":        "150000",
+		"bad_price":    "150k",
+		"fat":          "12.5",
+		"bad_fat":      "a lot",
+		"tags":         " vegan | gluten free ",
+		"category_ids": "1|2",
+		"bad_ids":      "1|two",
+	}
+	int64p := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		Name   string
+		Parse  func(p *rowParser) any
+		Result any
+		Errors []util.ValidationError
+	}{
+		{Name: "Int64", Parse: func(p *rowParser) any { return p.Int64("price") }, Result: int64p(150000)},
+		{Name: "Int64_Empty", Parse: func(p *rowParser) any { return p.Int64("empty") }, Result: (*int64)(nil)},
+		{
+			Name:   "Int64_Invalid",
+			Parse:  func(p *rowParser) any { return p.Int64("bad_price") },
+			Result: (*int64)(nil),
+			Errors: []util.ValidationError{{Key: "bad_price", Rule: "number", Message: "bad_price must be a valid integer"}},
+		},
+		{Name: "Float", Parse: func(p *rowParser) any { return p.Float("fat") }, Result: 12.5},
+		{Name: "Float_Empty", Parse: func(p *rowParser) any { return p.Float("empty") }, Result: float64(0)},
+		{
+			Name:   "Float_Invalid",
+			Parse:  func(p *rowParser) any { return p.Float("bad_fat") },
+			Result: float64(0),
+			Errors: []util.ValidationError{{Key: "bad_fat", Rule: "number", Message: "bad_fat must be a valid number"}},
+		},
+		{Name: "List", Parse: func(p *rowParser) any { return p.List("tags") }, Result: []string{"vegan", "gluten free"}},
+		{Name: "List_Empty", Parse: func(p *rowParser) any { return p.List("empty") }, Result: []string(nil)},
+		{Name: "Ints", Parse: func(p *rowParser) any { return p.Ints("category_ids") }, Result: []int{1, 2}},
+		{Name: "Ints_Empty", Parse: func(p *rowParser) any { return p.Ints("empty") }, Result: []int(nil)},
+		{
+			Name:   "Ints_Invalid",
+			Parse:  func(p *rowParser) any { return p.Ints("bad_ids") },
+			Result: []int(nil),
+			Errors: []util.ValidationError{{Key: "bad_ids", Rule: "number", Message: "bad_ids must be a | separated list of integers"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			p := rowParser{get: func(key string) string { return row[key] }}
+			assert.Equal(t, test.Result, test.Parse(&p))
+			assert.Equal(t, test.Errors, p.errs)
+		})
+	}
+}
+
+func Test_CSV_Cake_Reader(t *testing.T) {
+	price := int64(150000)
+
+	tests := []struct {
+		Name   string
+		Body   string
+		Result []service.ImportRow
+		Error  error
+	}{
+		{
+			Name: "Rows",
+			Body: "Title, Description ,image,price,currency,category_ids,tags,allergens,unknown\n" +
+				"Lemon cheesecake,A cheesecake made of lemon,https://example.com/lemon.jpeg,150000,idr,1|2,vegan|gluten free,milk,ignored\n",
+			Result: []service.ImportRow{{Line: 2, Request: service.CakeRequest{
+				Title:       "Lemon cheesecake",
+				Description: "A cheesecake made of lemon",
+				Image:       "https://example.com/lemon.jpeg",
+				Price:       &price,
+				Currency:    "IDR",
+				CategoryIDs: []int{1, 2},
+				Tags:        []string{"vegan", "gluten free"},
+				Allergens:   []string{"milk"},
+			}}},
+		},
+		{
+			Name: "Nutrition",
+			Body: "title,serving_size,energy,fat,saturated_fat,carbohydrate,sugars,protein,salt\n" +
+				"Carrot cake,100,350,12.5,3,40,25,4,0.3\n" +
+				"Lemon tart,,350,12.5,3,40,25,4,0.3\n",
+			Result: []service.ImportRow{
+				{Line: 2, Request: service.CakeRequest{Title: "Carrot cake", Nutrition: &service.NutritionRequest{
+					ServingSize:  100,
+					Energy:       350,
+					Fat:          12.5,
+					SaturatedFat: 3,
+					Carbohydrate: 40,
+					Sugars:       25,
+					Protein:      4,
+					Salt:         0.3,
+				}}},
+				{Line: 3, Request: service.CakeRequest{Title: "Lemon tart"}},
+			},
+		},
+		{
+			Name: "Blank_Lines",
+			Body: "title\n\nLemon tart\n\n\nCarrot cake\n",
+			Result: []service.ImportRow{
+				{Line: 3, Request: service.CakeRequest{Title: "Lemon tart"}},
+				{Line: 6, Request: service.CakeRequest{Title: "Carrot cake"}},
+			},
+		},
+		{
+			Name: "Quoted_Multiline",
+			Body: "title,description\n\"Lemon tart\",\"A tart\nwith lemon\"\nCarrot cake,A spiced cake\n",
+			Result: []service.ImportRow{
+				{Line: 2, Request: service.CakeRequest{Title: "Lemon tart", Description: "A tart\nwith lemon"}},
+				{Line: 4, Request: service.CakeRequest{Title: "Carrot cake", Description: "A spiced cake"}},
+			},
+		},
+		{
+			Name: "Malformed_Values",
+			Body: "title,price,category_ids\nLemon tart,cheap,one\n",
+			Result: []service.ImportRow{{Line: 2, Request: service.CakeRequest{Title: "Lemon tart"}, Errors: []util.ValidationError{
+				{Key: "price", Rule: "number", Message: "price must be a valid integer"},
+				{Key: "category_ids", Rule: "number", Message: "category_ids must be a | separated list of integers"},
+			}}},
+		},
+		{
+			Name: "Parse_Error",
+			Body: "title,description\nLemon tart,A \"tart\"\nCarrot cake\nOpera cake,A layered cake\n",
+			Result: []service.ImportRow{
+				{Line: 2, Errors: []util.ValidationError{{Key: "row", Rule: "csv", Message: `bare " in non-quoted-field`}}},
+				{Line: 3, Errors: []util.ValidationError{{Key: "row", Rule: "csv", Message: "wrong number of fields"}}},
+				{Line: 4, Request: service.CakeRequest{Title: "Opera cake", Description: "A layered cake"}},
+			},
+		},
+		{
+			Name:   "Header_Only",
+			Body:   "title,description\n",
+			Result: nil,
+		},
+		{
+			Name:  "Without_Header",
+			Body:  "",
+			Error: errMalformedImport,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			r, err := newCSVCakeReader(strings.NewReader(test.Body))
+			if err != nil {
+				assert.ErrorIs(t, err, test.Error)
+				return
+			}
+
+			res, err := readAll(r)
+			if test.Error != nil {
+				assert.ErrorIs(t, err, test.Error)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Result, res)
+		})
+	}
+}
+
+func Test_NDJSON_Cake_Reader(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Body   string
+		Result []service.ImportRow
+		Error  error
+	}{
+		{
+			Name: "Rows",
+			Body: `{"title": "Lemon tart", "description": "A tart", "tags": ["vegan"]}` + "\n" +
+				`{"title": "Carrot cake"}`,
+			Result: []service.ImportRow{
+				{Line: 1, Request: service.CakeRequest{Title: "Lemon tart", Description: "A tart", Tags: []string{"vegan"}}},
+				{Line: 2, Request: service.CakeRequest{Title: "Carrot cake"}},
+			},
+		},
+		{
+			Name: "Blank_Lines",
+			Body: "\n  \n" + `{"title": "Lemon tart"}` + "\r\n\n" + `{"title": "Carrot cake"}` + "\n\n",
+			Result: []service.ImportRow{
+				{Line: 3, Request: service.CakeRequest{Title: "Lemon tart"}},
+				{Line: 5, Request: service.CakeRequest{Title: "Carrot cake"}},
+			},
+		},
+		{
+			Name: "Invalid_JSON",
+			Body: `{"title": "Lemon tart"` + "\n" + `["Carrot cake"]` + "\n" + `{"title": 7}` + "\n" + `{"title": "Opera cake"}`,
+			Result: []service.ImportRow{
+				{Line: 1, Errors: []util.ValidationError{{Key: "row", Rule: "json", Message: "row must be a valid JSON object of a cake"}}},
+				{Line: 2, Errors: []util.ValidationError{{Key: "row", Rule: "json", Message: "row must be a valid JSON object of a cake"}}},
+				{Line: 3, Errors: []util.ValidationError{{Key: "row", Rule: "json", Message: "row must be a valid JSON object of a cake"}}},
+				{Line: 4, Request: service.CakeRequest{Title: "Opera cake"}},
+			},
+		},
+		{
+			Name:   "Empty",
+			Body:   "",
+			Result: nil,
+		},
+		{
+			Name: "Line_Too_Long",
+			Body: `{"title": "Lemon tart"}` + "\n" + `{"title": "` + strings.Repeat("a", maxImportLine) + `"}`,
+			Result: []service.ImportRow{
+				{Line: 1, Request: service.CakeRequest{Title: "Lemon tart"}},
+			},
+			Error: errMalformedImport,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			res, err := readAll(newNDJSONCakeReader(strings.NewReader(test.Body)))
+			if test.Error != nil {
+				assert.ErrorIs(t, err, test.Error)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.Result, res)
+		})
+	}
+}
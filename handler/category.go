@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type CategoryService interface {
+	Find(ctx context.Context, id int) (*schema.Category, error)
+	FindAll(ctx context.Context) ([]schema.Category, error)
+	Insert(ctx context.Context, req *service.CategoryRequest) error
+	Update(ctx context.Context, req *service.CategoryRequest) error
+	Delete(ctx context.Context, id int) error
+}
+
+type Category struct {
+	Service CategoryService
+}
+
+func (h *Category) FindCategory(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.Find(ctx, id)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if res != nil {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search category "+msg, res)
+}
+
+func (h *Category) FindAllCategory(rw http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := h.Service.FindAll(ctx)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search categories "+msg, res)
+}
+
+func (h *Category) AddCategory(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		body = service.CategoryRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	if err := h.Service.Insert(ctx, &body); err != nil {
+		h.writeError(ctx, rw, "adding new category", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "adding new category", map[string]int{
+		"id": body.ID,
+	})
+}
+
+func (h *Category) UpdateCategory(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.CategoryRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.ID = id
+	if err := h.Service.Update(ctx, &body); err != nil {
+		h.writeError(ctx, rw, "updating a category", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "updating a category", map[string]int{
+		"id": id,
+	})
+}
+
+func (h *Category) DeleteCategory(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	if err := h.Service.Delete(ctx, id); err != nil {
+		h.writeError(ctx, rw, "deleting a category", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "deleting a category", map[string]int{
+		"id": id,
+	})
+}
+
+func (h *Category) writeError(ctx context.Context, rw http.ResponseWriter, action string, err error) {
+	switch {
+	case eris.Is(err, repository.ErrRecordNotFound):
+		util.ErrorHTTPResponse(rw, http.StatusNotFound, action+", record not found", nil)
+	case eris.Is(err, repository.ErrDuplicateRecord):
+		util.ErrorHTTPResponse(rw, http.StatusConflict, action+", slug is already used", nil)
+	case eris.Is(err, service.ErrCategoryCycle):
+		QueryValidation(rw, []util.ValidationError{{
+			Key:     "parent_id",
+			Rule:    "parent_id",
+			Message: "parent_id can not be the category itself or one of it's sub categories",
+		}})
+	default:
+		util.ErrHTTPResponse(ctx, rw, err)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// deadlineReader extend a read deadline of a request by timeout before each read of it's body, so a large
+// body is not cut by the server ReadTimeout while it's still sent. an error of a writer which can not set
+// a deadline (e.g. a recorder of a test) is ignored since it does not have a timeout
+type deadlineReader struct {
+	r       io.ReadCloser
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	d.rc.SetReadDeadline(time.Now().Add(d.timeout))
+	return d.r.Read(p)
+}
+
+func (d *deadlineReader) Close() error {
+	return d.r.Close()
+}
+
+// deadlineWriter extend a write deadline of a response by timeout before each write, like deadlineReader
+type deadlineWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.rc.SetWriteDeadline(time.Now().Add(d.timeout))
+	return d.w.Write(p)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/storage"
+	"github.com/zufzuf/cake-store/libs/util"
+)
+
+type File struct {
+	Storage storage.Storage
+}
+
+// ServeFile serve a stored file, a key is never reused so the file is cached as immutable.
+// conditional and range requests are handled by http.ServeContent
+func (h *File) ServeFile(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		key = chi.URLParam(r, "*")
+	)
+
+	obj, err := h.Storage.Open(ctx, key)
+	if err != nil {
+		if eris.Is(err, storage.ErrNotFound) || eris.Is(err, storage.ErrInvalidKey) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "file not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+	defer obj.Body.Close()
+
+	if len(obj.ContentType) > 0 {
+		rw.Header().Set("Content-Type", obj.ContentType)
+	}
+	rw.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	rw.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, obj.ModTime.Unix(), obj.Size))
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(rw, r, "", obj.ModTime, obj.Body)
+}
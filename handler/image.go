@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type ImageService interface {
+	FindAll(ctx context.Context, cakeID int) ([]schema.CakeImage, error)
+	Upload(ctx context.Context, req *service.UploadRequest) error
+	Update(ctx context.Context, req *service.CakeImageRequest) error
+	Reorder(ctx context.Context, req *service.ReorderRequest) error
+	Delete(ctx context.Context, cakeID, id int) error
+}
+
+type Image struct {
+	Service ImageService
+
+	// MaxSize is a maximum image size in bytes, a request body is limited to it plus a multipart overhead
+	MaxSize int64
+}
+
+// multipartOverhead is an allowance for a multipart boundaries and headers on top of the image size
+const multipartOverhead = 1 << 20
+
+// imageReadTimeout is a time to read the next part of an upload, the server ReadTimeout is extended before
+// each read so a slow upload is not cut. imageWriteTimeout is a time to write the response after the image
+// is processed
+const (
+	imageReadTimeout  = 30 * time.Second
+	imageWriteTimeout = 30 * time.Second
+)
+
+func (h *Image) FindAllCakeImage(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.FindAll(ctx, id)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search cake images "+msg, res)
+}
+
+func (h *Image) UploadCakeImage(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	maxSize := h.MaxSize
+	if maxSize <= 0 {
+		maxSize = service.DefaultMaxImageSize
+	}
+	rc := http.NewResponseController(rw)
+	r.Body = http.MaxBytesReader(rw, &deadlineReader{r: r.Body, rc: rc, timeout: imageReadTimeout}, maxSize+multipartOverhead)
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			util.ErrorHTTPResponse(rw, http.StatusRequestEntityTooLarge, "uploading cake image, image is too large", nil)
+			return
+		}
+
+		QueryValidation(rw, []util.ValidationError{{
+			Key:     "image",
+			Rule:    "required",
+			Message: "image is a required multipart file",
+		}})
+		return
+	}
+	defer file.Close()
+
+	primary, _ := strconv.ParseBool(r.FormValue("primary"))
+	req := service.UploadRequest{
+		CakeID:  id,
+		Body:    file,
+		Alt:     r.FormValue("alt"),
+		Primary: primary,
+	}
+	err = h.Service.Upload(ctx, &req)
+
+	// the server WriteTimeout has been running since the request is read
+	rc.SetWriteDeadline(time.Now().Add(imageWriteTimeout))
+
+	if err != nil {
+		switch {
+		case eris.Is(err, repository.ErrRecordNotFound):
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "uploading cake image, cake not found", nil)
+		case eris.Is(err, service.ErrImageTooLarge):
+			util.ErrorHTTPResponse(rw, http.StatusRequestEntityTooLarge, "uploading cake image, image is too large", nil)
+		case eris.Is(err, service.ErrUnsupportedImage):
+			util.ErrorHTTPResponse(rw, http.StatusUnsupportedMediaType, "uploading cake image, image must be a jpeg, png or webp", nil)
+		default:
+			util.ErrHTTPResponse(ctx, rw, err)
+		}
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "uploading cake image", req.Image)
+}
+
+func (h *Image) UpdateCakeImage(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx        = r.Context()
+		id, _      = strconv.Atoi(chi.URLParam(r, "id"))
+		imageID, _ = strconv.Atoi(chi.URLParam(r, "imageID"))
+		body       = service.CakeImageRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.ID, body.CakeID = imageID, id
+	if err := h.Service.Update(ctx, &body); err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "updating cake image, record not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "updating cake image", map[string]int{
+		"id": imageID,
+	})
+}
+
+func (h *Image) ReorderCakeImage(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.ReorderRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.CakeID = id
+	if err := h.Service.Reorder(ctx, &body); err != nil {
+		switch {
+		case eris.Is(err, repository.ErrRecordNotFound):
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "reordering cake images, cake not found", nil)
+		case eris.Is(err, repository.ErrImageOrderMismatch):
+			QueryValidation(rw, []util.ValidationError{{
+				Key:     "ids",
+				Rule:    "ids",
+				Message: "ids must contain every image of the cake exactly once",
+			}})
+		default:
+			util.ErrHTTPResponse(ctx, rw, err)
+		}
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "reordering cake images", map[string]any{
+		"ids": body.IDs,
+	})
+}
+
+func (h *Image) DeleteCakeImage(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx        = r.Context()
+		id, _      = strconv.Atoi(chi.URLParam(r, "id"))
+		imageID, _ = strconv.Atoi(chi.URLParam(r, "imageID"))
+	)
+
+	if err := h.Service.Delete(ctx, id, imageID); err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "deleting cake image, record not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "deleting cake image", map[string]int{
+		"id": imageID,
+	})
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+// imageService read an uploaded image, the other methods are not used by an upload
+type imageService struct {
+	ImageService
+	body []byte
+}
+
+func (s *imageService) Upload(ctx context.Context, req *service.UploadRequest) error {
+	var err error
+	s.body, err = io.ReadAll(req.Body)
+	req.Image = &schema.CakeImage{CakeID: req.CakeID}
+	return err
+}
+
+func Test_Image_Upload_Slow_Body(t *testing.T) {
+	var (
+		srv    = &imageService{}
+		router = chi.NewRouter()
+	)
+	router.Post("/cakes/{id}/images", (&Image{Service: srv}).UploadCakeImage)
+
+	// the server timeouts are shorter than the upload, like the 5s timeouts of the api with a slow client
+	server := httptest.NewUnstartedServer(router)
+	server.Config.ReadTimeout = 300 * time.Millisecond
+	server.Config.WriteTimeout = 300 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, _ := mw.CreateFormFile("image", "cake.png")
+		for i := 0; i < 10; i++ {
+			time.Sleep(100 * time.Millisecond)
+			part.Write([]byte("0123456789"))
+		}
+		mw.Close()
+		pw.Close()
+	}()
+
+	res, err := http.Post(server.URL+"/cakes/7/images", mw.FormDataContentType(), pr)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, srv.body, 100)
+}
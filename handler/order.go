@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type OrderService interface {
+	Find(ctx context.Context, id int) (*schema.Order, error)
+	FindAll(ctx context.Context, req *service.FindAllOrderRequest) ([]schema.Order, error)
+	Insert(ctx context.Context, req *service.OrderRequest) error
+	Transition(ctx context.Context, req *service.TransitionRequest) error
+}
+
+type Order struct {
+	Service OrderService
+}
+
+func (h *Order) FindOrder(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.Find(ctx, id)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if res != nil {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search order "+msg, res)
+}
+
+func (h *Order) FindAllOrder(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		q   = newQueryParser(r.URL.Query())
+		req = service.FindAllOrderRequest{
+			CakeID:        q.Int("cake_id", 1, math.MaxInt32),
+			CreatedAfter:  q.Time("created_after"),
+			CreatedBefore: q.Time("created_before"),
+			Limit:         q.Int("limit", 1, repository.MaxLimit),
+			Before:        q.Int("before", 1, math.MaxInt32),
+		}
+	)
+
+	if len(q.String("status")) > 0 {
+		req.Status = q.OneOf("status",
+			schema.OrderStatusPending,
+			schema.OrderStatusConfirmed,
+			schema.OrderStatusBaking,
+			schema.OrderStatusReady,
+			schema.OrderStatusPickedUp,
+			schema.OrderStatusCancelled,
+		)
+	}
+	q.TimeRange("created_after", req.CreatedAfter, "created_before", req.CreatedBefore)
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.FindAll(ctx, &req)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search orders "+msg, res)
+}
+
+func (h *Order) AddOrder(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		body = service.OrderRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	if err := h.Service.Insert(ctx, &body); err != nil {
+		if eris.Is(err, service.ErrOrderCakeNotFound) {
+			QueryValidation(rw, []util.ValidationError{{
+				Key:     "lines",
+				Rule:    "exists",
+				Message: "lines must only contain an existing cake",
+			}})
+			return
+		}
+		if eris.Is(err, service.ErrCurrencyMismatch) {
+			QueryValidation(rw, []util.ValidationError{{
+				Key:     "lines",
+				Rule:    "currency",
+				Message: "lines must only contain cakes priced in the same currency",
+			}})
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "adding new order", map[string]any{
+		"id":     body.ID,
+		"status": body.Status,
+	})
+}
+
+func (h *Order) TransitionOrder(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.TransitionRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.ID = id
+	if err := h.Service.Transition(ctx, &body); err != nil {
+		switch {
+		case eris.Is(err, repository.ErrRecordNotFound):
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "updating order status, record not found", nil)
+		case eris.Is(err, service.ErrInvalidTransition):
+			util.ErrorHTTPResponse(rw, http.StatusConflict, "updating order status, the order can not move into "+body.Status, nil)
+		case eris.Is(err, repository.ErrStatusConflict):
+			util.ErrorHTTPResponse(rw, http.StatusConflict, "updating order status, the order has been changed by another request", nil)
+		default:
+			util.ErrHTTPResponse(ctx, rw, err)
+		}
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "updating order status", map[string]any{
+		"id":     id,
+		"status": body.Status,
+	})
+}
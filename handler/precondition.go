@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/zufzuf/cake-store/libs/util"
+)
+
+// ETag format a record version as a strong entity tag
+func ETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseETag parse a strong entity tag into a record version, a weak tag is rejected since
+// If-Match use a strong comparison (RFC 7232 section 3.1)
+func parseETag(tag string) (int, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// IfMatch read a record version from If-Match header, zero version mean the write is unconditional.
+// a missing header is rejected with 428 on strict mode and a malformed or weak one is rejected with 412
+func IfMatch(rw http.ResponseWriter, r *http.Request, strict bool) (int, bool) {
+	val := r.Header.Get("If-Match")
+	if len(val) == 0 {
+		if strict {
+			util.ErrorHTTPResponse(rw, http.StatusPreconditionRequired, "precondition required, If-Match header is missing", nil)
+			return 0, false
+		}
+		return 0, true
+	}
+
+	if strings.TrimSpace(val) == "*" {
+		return 0, true
+	}
+
+	version, ok := parseETag(val)
+	if !ok {
+		util.ErrorHTTPResponse(rw, http.StatusPreconditionFailed, "precondition failed, If-Match header is invalid", nil)
+		return 0, false
+	}
+
+	return version, true
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+)
+
+// queryParser read a typed value from url query,
+// every malformed value is collected as a validation error
+type queryParser struct {
+	q    url.Values
+	errs []util.ValidationError
+}
+
+func newQueryParser(q url.Values) *queryParser {
+	return &queryParser{q: q}
+}
+
+func (p *queryParser) fail(key, rule, message string) {
+	p.errs = append(p.errs, util.ValidationError{
+		Key:     key,
+		Rule:    rule,
+		Message: message,
+	})
+}
+
+func (p *queryParser) String(key string) string {
+	return p.q.Get(key)
+}
+
+// Int parse an optional integer between min and max, zero is returned when the key is empty
+func (p *queryParser) Int(key string, min, max int) int {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return 0
+	}
+
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		p.fail(key, "number", fmt.Sprintf("%s must be a valid integer", key))
+		return 0
+	}
+
+	if n < min || n > max {
+		p.fail(key, "range", fmt.Sprintf("%s must be between %d and %d", key, min, max))
+		return 0
+	}
+
+	return n
+}
+
+// RequiredInt parse a required integer between min and max
+func (p *queryParser) RequiredInt(key string, min, max int) int {
+	if len(p.q.Get(key)) == 0 {
+		p.fail(key, "required", fmt.Sprintf("%s is a required field", key))
+		return 0
+	}
+	return p.Int(key, min, max)
+}
+
+// Bool parse an optional boolean, false is returned when the key is empty
+func (p *queryParser) Bool(key string) bool {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return false
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		p.fail(key, "boolean", fmt.Sprintf("%s must be a valid boolean", key))
+		return false
+	}
+
+	return b
+}
+
+// OneOf parse an optional value which must be one of the values,
+// the first value is used as a default when the key is empty
+func (p *queryParser) OneOf(key string, values ...string) string {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return values[0]
+	}
+
+	for _, v := range values {
+		if v == val {
+			return val
+		}
+	}
+
+	p.fail(key, "oneof", fmt.Sprintf("%s must be one of [%s]", key, strings.Join(values, " ")))
+	return values[0]
+}
+
+// List parse an optional comma separated values which each of them must be one of the values,
+// a duplicate value is ignored
+func (p *queryParser) List(key string, values ...string) []string {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return nil
+	}
+
+	var (
+		res  = []string{}
+		seen = map[string]bool{}
+	)
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if seen[item] {
+			continue
+		}
+
+		ok := false
+		for _, v := range values {
+			ok = ok || v == item
+		}
+		if !ok {
+			p.fail(key, "oneof", fmt.Sprintf("%s must be a comma separated list of [%s]", key, strings.Join(values, " ")))
+			return nil
+		}
+
+		seen[item] = true
+		res = append(res, item)
+	}
+
+	return res
+}
+
+// Int64 parse an optional integer between min and max, nil is returned when the key is empty
+func (p *queryParser) Int64(key string, min, max int64) *int64 {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return nil
+	}
+
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		p.fail(key, "number", fmt.Sprintf("%s must be a valid integer", key))
+		return nil
+	}
+
+	if n < min || n > max {
+		p.fail(key, "range", fmt.Sprintf("%s must be between %d and %d", key, min, max))
+		return nil
+	}
+
+	return &n
+}
+
+// Float parse an optional number, nil is returned when the key is empty
+func (p *queryParser) Float(key string) *float64 {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return nil
+	}
+
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		p.fail(key, "number", fmt.Sprintf("%s must be a valid number", key))
+		return nil
+	}
+
+	return &n
+}
+
+// Time parse an optional RFC3339 datetime or a date (YYYY-MM-DD),
+// zero time is returned when the key is empty
+func (p *queryParser) Time(key string) time.Time {
+	val := p.q.Get(key)
+	if len(val) == 0 {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t
+		}
+	}
+
+	p.fail(key, "datetime", fmt.Sprintf("%s must be a valid RFC3339 datetime or YYYY-MM-DD date", key))
+	return time.Time{}
+}
+
+// Sort parse a sort keys, unknown or duplicate key is reported with a list of supported keys
+func (p *queryParser) Sort(key string) []repository.SortField {
+	fields, err := repository.ParseSort(p.q.Get(key))
+	if err != nil {
+		p.fail(key, "oneof", fmt.Sprintf("%s must be one of [%s]", key, strings.Join(repository.CakeSortKeys, " ")))
+		return nil
+	}
+	return fields
+}
+
+// SortRequires report an error when the sort contains sortKey but the required parameter is empty
+func (p *queryParser) SortRequires(key string, fields []repository.SortField, sortKey, requiredKey, required string) {
+	if len(required) > 0 {
+		return
+	}
+
+	for _, f := range fields {
+		if f.Key == sortKey {
+			p.fail(key, "required_with", fmt.Sprintf("%s by %s requires %s", key, sortKey, requiredKey))
+			return
+		}
+	}
+}
+
+// Range report an error when both of bounds are set and the lower bound is greater than the upper bound
+func (p *queryParser) Range(minKey string, min *float64, maxKey string, max *float64) {
+	if min != nil && max != nil && *min > *max {
+		p.fail(minKey, "ltefield", fmt.Sprintf("%s must be less than or equal to %s", minKey, maxKey))
+	}
+}
+
+// Int64Range report an error when both of bounds are set and the lower bound is greater than the upper bound
+func (p *queryParser) Int64Range(minKey string, min *int64, maxKey string, max *int64) {
+	if min != nil && max != nil && *min > *max {
+		p.fail(minKey, "ltefield", fmt.Sprintf("%s must be less than or equal to %s", minKey, maxKey))
+	}
+}
+
+// TimeRange report an error when both of bounds are set and the lower bound is after the upper bound
+func (p *queryParser) TimeRange(afterKey string, after time.Time, beforeKey string, before time.Time) {
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		p.fail(afterKey, "ltfield", fmt.Sprintf("%s must be less than %s", afterKey, beforeKey))
+	}
+}
+
+func (p *queryParser) Errors() []util.ValidationError {
+	return p.errs
+}
+
+// QueryValidation write an unprocessable response when there is a validation errors
+func QueryValidation(rw http.ResponseWriter, errs []util.ValidationError) bool {
+	if len(errs) == 0 {
+		return true
+	}
+
+	var (
+		code = http.StatusUnprocessableEntity
+		msg  = "unprocessable request query, an error occured"
+	)
+	util.ErrorHTTPResponse(rw, code, msg, map[string]any{
+		"validation": errs,
+	})
+	return false
+}
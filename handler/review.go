@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type ReviewService interface {
+	FindAll(ctx context.Context, req *service.FindAllReviewRequest) ([]schema.Review, error)
+	Summary(ctx context.Context, cakeID int) (*schema.RatingSummary, error)
+	Insert(ctx context.Context, req *service.ReviewRequest) error
+	Delete(ctx context.Context, cakeID, id int) error
+}
+
+type Review struct {
+	Service ReviewService
+}
+
+func (h *Review) FindAllReview(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		q     = newQueryParser(r.URL.Query())
+		req   = service.FindAllReviewRequest{
+			CakeID: id,
+			Score:  q.Int("score", 1, 5),
+			Limit:  q.Int("limit", 1, repository.MaxLimit),
+			Before: q.Int("before", 1, math.MaxInt32),
+		}
+	)
+
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.FindAll(ctx, &req)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search reviews "+msg, res)
+}
+
+func (h *Review) FindReviewSummary(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.Summary(ctx, id)
+	if err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "search review summary, cake not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search review summary found", res)
+}
+
+func (h *Review) AddReview(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.ReviewRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.CakeID = id
+	if err := h.Service.Insert(ctx, &body); err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "adding new review, cake not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "adding new review", map[string]int{
+		"id": body.ID,
+	})
+}
+
+func (h *Review) DeleteReview(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx         = r.Context()
+		id, _       = strconv.Atoi(chi.URLParam(r, "id"))
+		reviewID, _ = strconv.Atoi(chi.URLParam(r, "reviewID"))
+	)
+
+	if err := h.Service.Delete(ctx, id, reviewID); err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "deleting a review, record not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "deleting a review", map[string]int{
+		"id": reviewID,
+	})
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type StockService interface {
+	Find(ctx context.Context, cakeID int) (*schema.Stock, error)
+	FindAll(ctx context.Context, req *service.StockRequest) ([]schema.Stock, error)
+	FindMovements(ctx context.Context, req *service.MovementRequest) ([]schema.StockMovement, error)
+	Move(ctx context.Context, req *service.MoveRequest) error
+}
+
+type Stock struct {
+	Service StockService
+}
+
+func (h *Stock) FindStock(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.Find(ctx, id)
+	if err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "search stock, cake not found", nil)
+			return
+		}
+		util.ErrHTTPResponse(ctx, rw, err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search stock found", res)
+}
+
+func (h *Stock) FindAllStock(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx = r.Context()
+		q   = newQueryParser(r.URL.Query())
+		req = service.StockRequest{}
+	)
+
+	if below := q.Int("below", 1, math.MaxInt32); below > 0 {
+		req.Below = &below
+	}
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.FindAll(ctx, &req)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search stocks "+msg, res)
+}
+
+func (h *Stock) FindStockMovements(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		q     = newQueryParser(r.URL.Query())
+		req   = service.MovementRequest{
+			CakeID: id,
+			Limit:  q.Int("limit", 1, repository.MaxLimit),
+			Before: q.Int("before", 1, math.MaxInt32),
+		}
+	)
+
+	if len(q.String("kind")) > 0 {
+		req.Kind = q.OneOf("kind",
+			schema.StockMovementBaked,
+			schema.StockMovementSold,
+			schema.StockMovementWasted,
+			schema.StockMovementAdjusted,
+		)
+	}
+	if ok := QueryValidation(rw, q.Errors()); !ok {
+		return
+	}
+
+	res, err := h.Service.FindMovements(ctx, &req)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search stock movements "+msg, res)
+}
+
+func (h *Stock) AddStockMovement(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.MoveRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.CakeID = id
+	if err := h.Service.Move(ctx, &body); err != nil {
+		switch {
+		case eris.Is(err, repository.ErrRecordNotFound):
+			util.ErrorHTTPResponse(rw, http.StatusNotFound, "adding stock movement, cake not found", nil)
+		case eris.Is(err, repository.ErrInsufficientStock):
+			util.ErrorHTTPResponse(rw, http.StatusConflict, "adding stock movement, insufficient stock", nil)
+		case eris.Is(err, service.ErrInvalidMovement):
+			QueryValidation(rw, []util.ValidationError{{
+				Key:     "quantity",
+				Rule:    "gt",
+				Message: "quantity must be greater than 0 for " + body.Kind,
+			}})
+		default:
+			util.ErrHTTPResponse(ctx, rw, err)
+		}
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "adding stock movement", map[string]int{
+		"id":      body.ID,
+		"balance": body.Balance,
+	})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type TagService interface {
+	Find(ctx context.Context, id int) (*schema.Tag, error)
+	FindAll(ctx context.Context) ([]schema.Tag, error)
+	Insert(ctx context.Context, req *service.TagRequest) error
+	Update(ctx context.Context, req *service.TagRequest) error
+	Delete(ctx context.Context, id int) error
+}
+
+type Tag struct {
+	Service TagService
+}
+
+func (h *Tag) FindTag(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.Find(ctx, id)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if res != nil {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search tag "+msg, res)
+}
+
+func (h *Tag) FindAllTag(rw http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := h.Service.FindAll(ctx)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search tags "+msg, res)
+}
+
+func (h *Tag) AddTag(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		body = service.TagRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	if err := h.Service.Insert(ctx, &body); err != nil {
+		h.writeError(ctx, rw, "adding new tag", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "adding new tag", map[string]int{
+		"id": body.ID,
+	})
+}
+
+func (h *Tag) UpdateTag(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.TagRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.ID = id
+	if err := h.Service.Update(ctx, &body); err != nil {
+		h.writeError(ctx, rw, "updating a tag", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "updating a tag", map[string]int{
+		"id": id,
+	})
+}
+
+func (h *Tag) DeleteTag(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	if err := h.Service.Delete(ctx, id); err != nil {
+		h.writeError(ctx, rw, "deleting a tag", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "deleting a tag", map[string]int{
+		"id": id,
+	})
+}
+
+func (h *Tag) writeError(ctx context.Context, rw http.ResponseWriter, action string, err error) {
+	switch {
+	case eris.Is(err, repository.ErrRecordNotFound):
+		util.ErrorHTTPResponse(rw, http.StatusNotFound, action+", record not found", nil)
+	case eris.Is(err, repository.ErrDuplicateRecord):
+		util.ErrorHTTPResponse(rw, http.StatusConflict, action+", name is already used", nil)
+	default:
+		util.ErrHTTPResponse(ctx, rw, err)
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+type VariantService interface {
+	Find(ctx context.Context, cakeID, id int) (*schema.CakeVariant, error)
+	FindAll(ctx context.Context, cakeID int) ([]schema.CakeVariant, error)
+	Insert(ctx context.Context, req *service.VariantRequest) error
+	Update(ctx context.Context, req *service.VariantRequest) error
+	Delete(ctx context.Context, cakeID, id int) error
+}
+
+type Variant struct {
+	Service VariantService
+}
+
+func (h *Variant) FindCakeVariant(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx          = r.Context()
+		id, _        = strconv.Atoi(chi.URLParam(r, "id"))
+		variantID, _ = strconv.Atoi(chi.URLParam(r, "variantID"))
+	)
+
+	res, err := h.Service.Find(ctx, id, variantID)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if res != nil {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search cake variant "+msg, res)
+}
+
+func (h *Variant) FindAllCakeVariant(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+	)
+
+	res, err := h.Service.FindAll(ctx, id)
+	if err != nil {
+		if !eris.Is(err, repository.ErrRecordNotFound) {
+			util.ErrHTTPResponse(ctx, rw, err)
+			return
+		}
+	}
+
+	msg := "not found"
+	if len(res) > 0 {
+		msg = "found"
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "search cake variants "+msg, res)
+}
+
+func (h *Variant) AddCakeVariant(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		id, _ = strconv.Atoi(chi.URLParam(r, "id"))
+		body  = service.VariantRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.CakeID = id
+	if err := h.Service.Insert(ctx, &body); err != nil {
+		h.writeError(ctx, rw, "adding new cake variant", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "adding new cake variant", map[string]int{
+		"id": body.ID,
+	})
+}
+
+func (h *Variant) UpdateCakeVariant(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx          = r.Context()
+		id, _        = strconv.Atoi(chi.URLParam(r, "id"))
+		variantID, _ = strconv.Atoi(chi.URLParam(r, "variantID"))
+		body         = service.VariantRequest{}
+	)
+
+	if ok := JSONDecodeValidation(rw, r.Body, &body); !ok {
+		return
+	}
+
+	body.ID, body.CakeID = variantID, id
+	if err := h.Service.Update(ctx, &body); err != nil {
+		h.writeError(ctx, rw, "updating a cake variant", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "updating a cake variant", map[string]int{
+		"id": variantID,
+	})
+}
+
+func (h *Variant) DeleteCakeVariant(rw http.ResponseWriter, r *http.Request) {
+	var (
+		ctx          = r.Context()
+		id, _        = strconv.Atoi(chi.URLParam(r, "id"))
+		variantID, _ = strconv.Atoi(chi.URLParam(r, "variantID"))
+	)
+
+	if err := h.Service.Delete(ctx, id, variantID); err != nil {
+		h.writeError(ctx, rw, "deleting a cake variant", err)
+		return
+	}
+
+	util.HTTPResponse(rw, http.StatusOK, "deleting a cake variant", map[string]int{
+		"id": variantID,
+	})
+}
+
+func (h *Variant) writeError(ctx context.Context, rw http.ResponseWriter, action string, err error) {
+	switch {
+	case eris.Is(err, repository.ErrRecordNotFound):
+		util.ErrorHTTPResponse(rw, http.StatusNotFound, action+", record not found", nil)
+	case eris.Is(err, repository.ErrDuplicateRecord):
+		util.ErrorHTTPResponse(rw, http.StatusConflict, action+", sku is already used", nil)
+	default:
+		util.ErrHTTPResponse(ctx, rw, err)
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// Local store a files on the local filesystem under Root,
+// BaseURL is a prefix of a public url which is served by the files route
+type Local struct {
+	Root    string
+	BaseURL string
+}
+
+// path resolve a key into a file path, a key which escape the root is rejected
+func (s *Local) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
+
+// Put write a file into a temporary file first then rename it, so a reader never see a partial file
+func (s *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return eris.Wrap(err, "put object, an error occurred")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return eris.Wrap(err, "put object, an error occurred")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return eris.Wrap(err, "put object, an error occurred")
+	}
+	if err := tmp.Close(); err != nil {
+		return eris.Wrap(err, "put object, an error occurred")
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return eris.Wrap(err, "put object, an error occurred")
+	}
+
+	return nil
+}
+
+func (s *Local) Open(ctx context.Context, key string) (*Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, eris.Wrap(err, "open object, an error occurred")
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, eris.Wrap(err, "open object, an error occurred")
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+
+	return &Object{
+		Body:        f,
+		ContentType: mime.TypeByExtension(filepath.Ext(name)),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (s *Local) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return eris.Wrap(err, "delete object, an error occurred")
+	}
+
+	return nil
+}
+
+func (s *Local) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + key
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+var (
+	ErrNotFound   = eris.New("object not found")
+	ErrInvalidKey = eris.New("invalid object key")
+)
+
+// Storage is a blob storage of an uploaded files, a key is a slash separated path
+// e.g. `cakes/1/9bsv0s24le2002put6ig.jpg`
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+
+	// URL return a public url of the key
+	URL(key string) string
+}
+
+// Object is a stored file, Body must be closed by the caller
+type Object struct {
+	Body        io.ReadSeekCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
@@ -2,8 +2,10 @@ package util
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/go-playground/locales/en"
@@ -13,6 +15,7 @@ import (
 	"github.com/rotisserie/eris"
 	"github.com/unrolled/render"
 	"github.com/zufzuf/cake-store/libs/logger"
+	"github.com/zufzuf/cake-store/schema"
 	"go.uber.org/zap"
 )
 
@@ -35,9 +38,15 @@ type Response struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Payload any    `json:"payload"`
+	Meta    any    `json:"meta,omitempty"`
 	Err     any    `json:"error"`
 }
 
+type Pagination struct {
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
 type Error struct {
 	TrackerID string `json:"tracker_id"`
 	Cause     any    `json:"cause,omitempty"`
@@ -51,6 +60,15 @@ func HTTPResponse(rw http.ResponseWriter, code int, message string, payload any)
 	})
 }
 
+func HTTPResponseWithMeta(rw http.ResponseWriter, code int, message string, payload any, meta any) error {
+	return Render.JSON(rw, code, Response{
+		Code:    code,
+		Message: message,
+		Payload: payload,
+		Meta:    meta,
+	})
+}
+
 func ErrHTTPResponse(ctx context.Context, rw http.ResponseWriter, err error) {
 	var (
 		trackerId = CTXTracker(ctx)
@@ -58,10 +76,7 @@ func ErrHTTPResponse(ctx context.Context, rw http.ResponseWriter, err error) {
 		unpack    = eris.Unpack(err)
 	)
 
-	logger.Log.With(
-		zap.String("tracker_id", trackerId),
-		zap.Any("error", eris.ToJSON(err, true)),
-	).Error(unpack.ErrRoot.Msg)
+	LogError(ctx, err)
 
 	Render.JSON(rw, code, Response{
 		Code:    code,
@@ -72,6 +87,21 @@ func ErrHTTPResponse(ctx context.Context, rw http.ResponseWriter, err error) {
 	})
 }
 
+// LogError log an error of a request with it's tracker id, it's used when a response can not be written
+// anymore, e.g. a streamed response which has been started, or when an error is not exposed
+func LogError(ctx context.Context, err error) {
+	// the logger is started by the server, a test or another command log into the standard logger
+	if logger.Log == nil {
+		log.Printf("tracker_id=%s %s", CTXTracker(ctx), eris.ToString(err, true))
+		return
+	}
+
+	logger.Log.With(
+		zap.String("tracker_id", CTXTracker(ctx)),
+		zap.Any("error", eris.ToJSON(err, true)),
+	).Error(eris.Unpack(err).ErrRoot.Msg)
+}
+
 func ErrorHTTPResponse(rw http.ResponseWriter, code int, message string, err any) error {
 	return Render.JSON(rw, code, Response{
 		Code:    code,
@@ -90,6 +120,26 @@ var (
 	Trans, _ = uni.GetTranslator("en")
 )
 
+func init() {
+	// allergen validate an allergen against schema.Allergens, it's registered on init
+	// since a request can be validated before NewValidator is called
+	Validate.RegisterValidation("allergen", func(fl validator.FieldLevel) bool {
+		val := fl.Field().String()
+		for _, allergen := range schema.Allergens {
+			if val == allergen {
+				return true
+			}
+		}
+		return false
+	})
+	Validate.RegisterTranslation("allergen", Trans, func(ut ut.Translator) error {
+		return ut.Add("allergen", "{0} must be one of ["+strings.Join(schema.Allergens, " ")+"]", true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		msg, _ := ut.T("allergen", fe.Field())
+		return msg
+	})
+}
+
 type ValidationError struct {
 	Key     string `json:"key"`
 	Rule    string `json:"rule"`
@@ -188,3 +238,32 @@ func (q *Query) Build() (string, []any) {
 	}
 	return "WHERE " + strings.Join(q.val, " AND "), q.args
 }
+
+// Rebind replace every ? placeholders of a query with a numbered placeholders ($1, $2, ...) of PostgreSQL,
+// so a query built by Query can be run on it. a question mark in a quoted string is kept as it is
+func Rebind(query string) string {
+	var (
+		b     strings.Builder
+		n     int
+		quote rune
+	)
+	b.Grow(len(query) + 8)
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
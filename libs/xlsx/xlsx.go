@@ -0,0 +1,151 @@
+// Package xlsx write a single sheet workbook as a stream, a row is written into the zip as soon as it's
+// added so a large sheet is never held in memory. a string is written inline instead of into a shared
+// strings table, which would need every strings before the sheet
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+
+	rels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// Writer write a rows of a sheet, Close must be called to complete the workbook
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewWriter write every parts of a workbook with the sheet name into w, except the rows of the sheet
+func NewWriter(w io.Writer, name string) (*Writer, error) {
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+		`<sheet name="` + escape(name) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, eris.Wrap(err, "write xlsx, an error occurred")
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, eris.Wrap(err, "write xlsx, an error occurred")
+		}
+	}
+
+	// the sheet is the last part, so it's kept open while the rows are written
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, eris.Wrap(err, "write xlsx, an error occurred")
+	}
+
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(sheetHeader); err != nil {
+		return nil, eris.Wrap(err, "write xlsx, an error occurred")
+	}
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// Write write a row of cells, a number is written as a number, a time as RFC 3339 text and nil as an empty cell.
+// another value is written as an inline string, so a text starting with = is never run as a formula
+func (w *Writer) Write(cells []any) error {
+	w.rows++
+	w.sheet.WriteString(`<row r="` + strconv.Itoa(w.rows) + `">`)
+
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+			w.sheet.WriteString(`<c/>`)
+		case int:
+			w.number(strconv.Itoa(v))
+		case int64:
+			w.number(strconv.FormatInt(v, 10))
+		case float64:
+			w.number(strconv.FormatFloat(v, 'f', -1, 64))
+		case time.Time:
+			w.text(v.Format(time.RFC3339))
+		case string:
+			w.text(v)
+		default:
+			w.text(fmt.Sprint(v))
+		}
+	}
+
+	if _, err := w.sheet.WriteString(`</row>`); err != nil {
+		return eris.Wrap(err, "write xlsx row, an error occurred")
+	}
+
+	return nil
+}
+
+// Close complete the sheet and the workbook, it does not close the underlying writer
+func (w *Writer) Close() error {
+	if _, err := w.sheet.WriteString(sheetFooter); err != nil {
+		return eris.Wrap(err, "write xlsx, an error occurred")
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return eris.Wrap(err, "write xlsx, an error occurred")
+	}
+	if err := w.zw.Close(); err != nil {
+		return eris.Wrap(err, "write xlsx, an error occurred")
+	}
+	return nil
+}
+
+func (w *Writer) number(v string) {
+	w.sheet.WriteString(`<c><v>` + v + `</v></c>`)
+}
+
+func (w *Writer) text(v string) {
+	w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(w.sheet, []byte(v))
+	w.sheet.WriteString(`</t></is></c>`)
+}
+
+func escape(v string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(v))
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/libs/xlsx"
+)
+
+type cell struct {
+	Type    string `xml:"t,attr"`
+	Value   string `xml:"v"`
+	Formula string `xml:"f"`
+	Text    string `xml:"is>t"`
+}
+
+type sheet struct {
+	Rows []struct {
+		R     int    `xml:"r,attr"`
+		Cells []cell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// open read the parts of a workbook by their names
+func open(t *testing.T, b []byte) map[string][]byte {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		parts[f.Name], err = io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+	}
+	return parts
+}
+
+func Test_Writer(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := xlsx.NewWriter(&buf, "Cakes & <Tarts>")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]any{"title", "price", "fat", "created_at", "nutrition"}))
+	assert.NoError(t, w.Write([]any{
+		"Lemon <tart> & \"cake\"",
+		int64(150000),
+		12.5,
+		time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		nil,
+	}))
+	assert.NoError(t, w.Write([]any{"=HYPERLINK(\"https://example.com\")", 7, "  padded  ", true}))
+	assert.NoError(t, w.Close())
+
+	parts := open(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		assert.Contains(t, parts, name)
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	assert.NoError(t, xml.Unmarshal(parts["xl/workbook.xml"], &workbook))
+	assert.Equal(t, "Cakes & <Tarts>", workbook.Sheets[0].Name)
+
+	var res sheet
+	assert.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &res))
+	assert.Len(t, res.Rows, 3)
+	for i, row := range res.Rows {
+		assert.Equal(t, i+1, row.R)
+	}
+
+	text := func(v string) cell { return cell{Type: "inlineStr", Text: v} }
+	number := func(v string) cell { return cell{Value: v} }
+
+	assert.Equal(t, []cell{text("title"), text("price"), text("fat"), text("created_at"), text("nutrition")}, res.Rows[0].Cells)
+	assert.Equal(t, []cell{
+		text("Lemon <tart> & \"cake\""),
+		number("150000"),
+		number("12.5"),
+		text("2022-01-02T03:04:05Z"),
+		{},
+	}, res.Rows[1].Cells)
+
+	// a text is never a formula
+	assert.Equal(t, []cell{
+		text("=HYPERLINK(\"https://example.com\")"),
+		number("7"),
+		text("  padded  "),
+		text("true"),
+	}, res.Rows[2].Cells)
+}
+
+func Test_Writer_Empty(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := xlsx.NewWriter(&buf, "Cakes")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	var res sheet
+	assert.NoError(t, xml.Unmarshal(open(t, buf.Bytes())["xl/worksheets/sheet1.xml"], &res))
+	assert.Empty(t, res.Rows)
+}
@@ -10,6 +10,20 @@ import (
 	"github.com/zufzuf/cake-store/server"
 )
 
+const usage = `usage: cake-store [command]
+
+commands:
+  serve                  serve the api, it's the default command
+  migrate up [N]         apply all or N pending migrations
+  migrate down [N]       roll back 1 or N applied migrations
+  migrate goto VERSION   migrate up or down into a version, 0 roll back every migrations
+  migrate force VERSION  set a version without migrating after a failed migration is fixed
+  migrate status         show a current version and a pending migrations
+  migrate create NAME    create an empty migration in ./migrations
+  seed [--reset] [FILE]  insert a cakes of YAML or JSON fixtures which does not exist yet,
+                         the default fixtures are inserted without a file. --reset delete
+                         every cakes and their revisions first`
+
 func main() {
 	ctx, cancel := signal.NotifyContext(
 		context.Background(),
@@ -21,7 +35,27 @@ func main() {
 	)
 	defer cancel()
 
-	if err := server.NewHTTPServer().Run(ctx); err != nil {
-		log.Fatalf("failed starting server, err : \n%+v", err)
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		if err := server.NewHTTPServer().Run(ctx); err != nil {
+			log.Fatalf("failed starting server, err : \n%+v", err)
+		}
+	case "migrate":
+		if err := migrate(ctx, args); err != nil {
+			log.Fatalf("failed migrating database, err : \n%+v", err)
+		}
+	case "seed":
+		if err := seed(ctx, args); err != nil {
+			log.Fatalf("failed seeding database, err : \n%+v", err)
+		}
+	case "help", "-h", "--help":
+		log.Print(usage)
+	default:
+		log.Fatalf("unknown command %q\n%s", command, usage)
 	}
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/rotisserie/eris"
+
+	"github.com/zufzuf/cake-store/db"
+	"github.com/zufzuf/cake-store/migrations"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/server"
+)
+
+// migrationsDir is a directory of a migrations source, a new migration is created on it
+const migrationsDir = "migrations"
+
+var ErrInvalidArgs = eris.New("invalid arguments")
+
+// migrate run a migrate subcommand on a database of STORAGE_DRIVER
+func migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return eris.Wrap(ErrInvalidArgs, usage)
+	}
+
+	command, args := args[0], args[1:]
+	if command == "create" {
+		if len(args) != 1 {
+			return eris.Wrap(ErrInvalidArgs, "migrate create NAME")
+		}
+
+		paths, err := migrations.Create(migrationsDir, args[0])
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return nil
+	}
+
+	conn, dialect, _, err := database()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	switch command {
+	case "up":
+		if len(args) == 0 {
+			err = migrations.Up(ctx, conn, dialect)
+			break
+		}
+
+		var n int
+		if n, err = steps(args); err != nil {
+			return err
+		}
+		err = migrations.Steps(ctx, conn, dialect, n)
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			if n, err = steps(args); err != nil {
+				return err
+			}
+		}
+		err = migrations.Steps(ctx, conn, dialect, -n)
+	case "goto", "force":
+		if len(args) != 1 {
+			return eris.Wrapf(ErrInvalidArgs, "migrate %s VERSION", command)
+		}
+
+		var version uint64
+		if version, err = strconv.ParseUint(args[0], 10, 64); err != nil {
+			return eris.Wrapf(ErrInvalidArgs, "migrate %s VERSION", command)
+		}
+
+		if command == "goto" {
+			err = migrations.Goto(ctx, conn, dialect, version)
+		} else {
+			err = migrations.Force(ctx, conn, version)
+		}
+	case "status":
+	default:
+		return eris.Wrapf(ErrInvalidArgs, "unknown migrate command %q\n%s", command, usage)
+	}
+	if err != nil {
+		return err
+	}
+
+	return status(ctx, conn)
+}
+
+// steps parse a number of migrations of up and down
+func steps(args []string) (int, error) {
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 || len(args) > 1 {
+		return 0, eris.Wrap(ErrInvalidArgs, "a number of migrations must be a positive number")
+	}
+	return n, nil
+}
+
+// status print a current schema version and every migrations with it's state
+func status(ctx context.Context, conn *sql.DB) error {
+	ms, err := migrations.Load()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrations.Version(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	state := "clean"
+	if dirty {
+		state = "dirty"
+	}
+	fmt.Printf("version %d (%s)\n", version, state)
+
+	for _, m := range ms {
+		applied := "pending"
+		if m.Version <= version {
+			applied = "applied"
+		}
+		fmt.Printf("  %06d_%s\t%s\n", m.Version, m.Name, applied)
+	}
+
+	return nil
+}
+
+// database open a database of STORAGE_DRIVER without migrating it, with it's migrations and repository dialect
+func database() (*sql.DB, migrations.Dialect, repository.Dialect, error) {
+	switch driver := server.StorageDriver(); driver {
+	case server.StorageDriverMySQL:
+		return db.Init(), migrations.MySQL, repository.DialectMySQL, nil
+	case server.StorageDriverSQLite:
+		return db.InitSQLite(), migrations.SQLite, repository.DialectSQLite, nil
+	case server.StorageDriverPostgres:
+		return db.InitPostgres(), migrations.Postgres, repository.DialectPostgres, nil
+	default:
+		return nil, nil, "", eris.Wrapf(ErrInvalidArgs, "STORAGE_DRIVER %q does not have a database", driver)
+	}
+}
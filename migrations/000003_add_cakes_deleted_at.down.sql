@@ -0,0 +1,3 @@
+ALTER TABLE cakes
+    DROP INDEX idx_cakes_deleted_at,
+    DROP COLUMN deleted_at;
\ No newline at end of file
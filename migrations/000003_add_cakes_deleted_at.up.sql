@@ -0,0 +1,3 @@
+ALTER TABLE cakes
+    ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL,
+    ADD INDEX idx_cakes_deleted_at (deleted_at);
\ No newline at end of file
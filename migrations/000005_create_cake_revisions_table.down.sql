@@ -0,0 +1 @@
+DROP TABLE IF EXISTS cake_revisions;
\ No newline at end of file
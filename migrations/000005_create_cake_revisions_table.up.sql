@@ -0,0 +1,10 @@
+CREATE TABLE cake_revisions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cake_id INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    tracker_id VARCHAR(32) NOT NULL DEFAULT '',
+    before_data JSON NULL,
+    after_data JSON NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_cake_revisions_cake_id (cake_id, id)
+);
\ No newline at end of file
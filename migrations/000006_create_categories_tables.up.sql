@@ -0,0 +1,19 @@
+CREATE TABLE categories (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    parent_id INT NULL,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(120) NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_categories_slug (slug),
+    CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id) ON DELETE SET NULL
+);
+
+CREATE TABLE cake_categories (
+    cake_id INT NOT NULL,
+    category_id INT NOT NULL,
+    PRIMARY KEY (cake_id, category_id),
+    INDEX idx_cake_categories_category_id (category_id),
+    CONSTRAINT fk_cake_categories_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_cake_categories_category FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_tags_name (name)
+);
+
+CREATE TABLE cake_tags (
+    cake_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    PRIMARY KEY (cake_id, tag_id),
+    INDEX idx_cake_tags_tag_id (tag_id),
+    CONSTRAINT fk_cake_tags_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_cake_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
\ No newline at end of file
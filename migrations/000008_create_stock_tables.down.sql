@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS cake_stocks;
\ No newline at end of file
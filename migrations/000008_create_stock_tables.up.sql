@@ -0,0 +1,21 @@
+CREATE TABLE cake_stocks (
+    cake_id INT PRIMARY KEY,
+    quantity INT NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_cake_stocks_quantity (quantity),
+    CONSTRAINT chk_cake_stocks_quantity CHECK (quantity >= 0),
+    CONSTRAINT fk_cake_stocks_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
+
+CREATE TABLE stock_movements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cake_id INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    quantity INT NOT NULL,
+    balance INT NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    tracker_id VARCHAR(32) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    INDEX idx_stock_movements_cake_id (cake_id, id),
+    CONSTRAINT fk_stock_movements_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    customer_name VARCHAR(100) NOT NULL,
+    customer_phone VARCHAR(32) NOT NULL DEFAULT '',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL,
+    total BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_orders_status (status, id),
+    INDEX idx_orders_created_at (created_at)
+);
+
+CREATE TABLE order_lines (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    cake_id INT NOT NULL,
+    cake_title VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL,
+    unit_price BIGINT NOT NULL,
+    INDEX idx_order_lines_order_id (order_id),
+    INDEX idx_order_lines_cake_id (cake_id),
+    CONSTRAINT fk_order_lines_order FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE reviews (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cake_id INT NOT NULL,
+    author VARCHAR(100) NOT NULL,
+    score TINYINT NOT NULL,
+    body TEXT NULL,
+    created_at DATETIME NOT NULL,
+    INDEX idx_reviews_cake_id (cake_id, id),
+    CONSTRAINT chk_reviews_score CHECK (score BETWEEN 1 AND 5),
+    CONSTRAINT fk_reviews_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS cake_images;
\ No newline at end of file
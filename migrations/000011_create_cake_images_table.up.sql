@@ -0,0 +1,20 @@
+CREATE TABLE cake_images (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cake_id INT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL DEFAULT '',
+    url VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    thumbnails JSON NULL,
+    alt VARCHAR(255) NOT NULL DEFAULT '',
+    position INT NOT NULL DEFAULT 0,
+    is_primary TINYINT(1) NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    INDEX idx_cake_images_cake_id (cake_id, position),
+    CONSTRAINT fk_cake_images_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
+
+INSERT INTO cake_images (cake_id, url, alt, position, is_primary, created_at)
+SELECT id, image, title, 0, 1, created_at FROM cakes WHERE image <> '';
\ No newline at end of file
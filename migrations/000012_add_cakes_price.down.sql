@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS cake_prices;
+
+ALTER TABLE orders DROP COLUMN currency;
+
+ALTER TABLE cakes
+    DROP INDEX idx_cakes_price,
+    DROP COLUMN currency,
+    DROP COLUMN price;
\ No newline at end of file
@@ -0,0 +1,22 @@
+ALTER TABLE cakes
+    ADD COLUMN price BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR',
+    ADD INDEX idx_cakes_price (currency, price);
+
+ALTER TABLE orders
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+CREATE TABLE cake_prices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cake_id INT NOT NULL,
+    price BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    effective_from DATETIME NOT NULL,
+    tracker_id VARCHAR(32) NOT NULL DEFAULT '',
+    INDEX idx_cake_prices_cake_id (cake_id, effective_from),
+    CONSTRAINT chk_cake_prices_price CHECK (price >= 0),
+    CONSTRAINT fk_cake_prices_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
+
+INSERT INTO cake_prices (cake_id, price, currency, effective_from)
+SELECT id, price, currency, created_at FROM cakes;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE cake_variants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cake_id INT NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    options JSON NULL,
+    price BIGINT NOT NULL DEFAULT 0,
+    stock INT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE INDEX uq_cake_variants_sku (sku),
+    INDEX idx_cake_variants_cake_id (cake_id),
+    CONSTRAINT chk_cake_variants_price CHECK (price >= 0),
+    CONSTRAINT chk_cake_variants_stock CHECK (stock >= 0),
+    CONSTRAINT fk_cake_variants_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
\ No newline at end of file
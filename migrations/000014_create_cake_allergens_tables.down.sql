@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cake_nutrition;
+DROP TABLE IF EXISTS cake_allergens;
\ No newline at end of file
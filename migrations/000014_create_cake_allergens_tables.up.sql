@@ -0,0 +1,20 @@
+CREATE TABLE cake_allergens (
+    cake_id INT NOT NULL,
+    allergen VARCHAR(32) NOT NULL,
+    PRIMARY KEY (cake_id, allergen),
+    INDEX idx_cake_allergens_allergen (allergen),
+    CONSTRAINT fk_cake_allergens_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
+
+CREATE TABLE cake_nutrition (
+    cake_id INT NOT NULL PRIMARY KEY,
+    serving_size DECIMAL(8,2) NOT NULL DEFAULT 0,
+    energy DECIMAL(8,2) NOT NULL DEFAULT 0,
+    fat DECIMAL(8,2) NOT NULL DEFAULT 0,
+    saturated_fat DECIMAL(8,2) NOT NULL DEFAULT 0,
+    carbohydrate DECIMAL(8,2) NOT NULL DEFAULT 0,
+    sugars DECIMAL(8,2) NOT NULL DEFAULT 0,
+    protein DECIMAL(8,2) NOT NULL DEFAULT 0,
+    salt DECIMAL(8,2) NOT NULL DEFAULT 0,
+    CONSTRAINT fk_cake_nutrition_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/rotisserie/eris"
+)
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Create write an empty up and down scripts of a next version into a migrations directory, a new
+// migration is embedded on the next build. it return a paths of the scripts
+func Create(dir, name string) ([]string, error) {
+	if !migrationName.MatchString(name) {
+		return nil, eris.Wrap(ErrInvalidName, name)
+	}
+
+	ms, err := load(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	version := uint64(1)
+	if len(ms) > 0 {
+		version = ms[len(ms)-1].Version + 1
+	}
+
+	var res []string
+	for _, kind := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", version, name, kind))
+
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return nil, eris.Wrap(err, "create migration, an error occurred")
+		}
+		if err := f.Close(); err != nil {
+			return nil, eris.Wrap(err, "create migration, an error occurred")
+		}
+
+		res = append(res, path)
+	}
+
+	return res, nil
+}
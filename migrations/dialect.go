@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Dialect split a migration script into statements which can be run on a database
+type Dialect func(script string) []string
+
+// MySQL is the dialect the migrations are written in, a script is only split into statements
+func MySQL(script string) []string {
+	return statements(script)
+}
+
+var (
+	createTable   = regexp.MustCompile(`(?is)^CREATE TABLE (\w+) \((.*)\)$`)
+	alterTable    = regexp.MustCompile(`(?is)^ALTER TABLE (\w+)\s+(.*)$`)
+	index         = regexp.MustCompile(`(?is)^(?:ADD )?(UNIQUE |FULLTEXT )?INDEX (\w+) (\(.*\))$`)
+	dropIndex     = regexp.MustCompile(`(?i)^DROP INDEX (\w+)$`)
+	autoIncrement = regexp.MustCompile(`(?i)\bINT AUTO_INCREMENT PRIMARY KEY\b`)
+	textType      = regexp.MustCompile(`(?i)^(\w+) (VARCHAR\(\d+\)|CHAR\(\d+\)|TEXT)`)
+	dateTimeType  = regexp.MustCompile(`(?i)\bDATETIME\b`)
+	tinyIntType   = regexp.MustCompile(`(?i)\bTINYINT(?:\(\d+\))?`)
+)
+
+// SQLite translate a MySQL script into SQLite statements, see translate for a statements rewrite.
+//   - an INT AUTO_INCREMENT primary key become an INTEGER PRIMARY KEY AUTOINCREMENT
+//   - a text column is case-insensitive (COLLATE NOCASE) like the default MySQL collation
+func SQLite(script string) []string {
+	return translate(script, func(def string) string {
+		def = autoIncrement.ReplaceAllString(def, "INTEGER PRIMARY KEY AUTOINCREMENT")
+		return textType.ReplaceAllString(def, "$1 $2 COLLATE NOCASE")
+	})
+}
+
+// Postgres translate a MySQL script into PostgreSQL statements, see translate for a statements rewrite.
+//   - an INT AUTO_INCREMENT primary key become a SERIAL PRIMARY KEY
+//   - a DATETIME become a TIMESTAMP and a TINYINT become a SMALLINT, a flag is still 0 or 1
+//   - a text column is a CITEXT, so it's case-insensitive like the default MySQL collation.
+//     the citext extension is created by db.OpenPostgres
+func Postgres(script string) []string {
+	return translate(script, func(def string) string {
+		def = autoIncrement.ReplaceAllString(def, "SERIAL PRIMARY KEY")
+		def = dateTimeType.ReplaceAllString(def, "TIMESTAMP")
+		def = tinyIntType.ReplaceAllString(def, "SMALLINT")
+		return textType.ReplaceAllString(def, "$1 CITEXT")
+	})
+}
+
+// translate rewrite a MySQL table statements which another database does not have, a column
+// definition is rewritten by the column function.
+//   - an index in a CREATE TABLE or an ALTER TABLE become a CREATE INDEX statement
+//   - an ALTER TABLE with many clauses is split into one statement per clause
+//   - a FULLTEXT index is dropped, a full-text search is only backed by an index on MySQL
+func translate(script string, column func(def string) string) []string {
+	var res []string
+	for _, stmt := range statements(script) {
+		if m := createTable.FindStringSubmatch(stmt); m != nil {
+			res = append(res, translateCreateTable(m[1], m[2], column)...)
+			continue
+		}
+		if m := alterTable.FindStringSubmatch(stmt); m != nil {
+			res = append(res, translateAlterTable(m[1], m[2], column)...)
+			continue
+		}
+		res = append(res, stmt)
+	}
+	return res
+}
+
+func translateCreateTable(table, body string, column func(def string) string) []string {
+	var (
+		defs    []string
+		indexes []string
+	)
+	for _, def := range splitDefinitions(body) {
+		if m := index.FindStringSubmatch(def); m != nil {
+			indexes = append(indexes, createIndex(table, m[1], m[2], m[3])...)
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(def), "CONSTRAINT ") || strings.HasPrefix(strings.ToUpper(def), "PRIMARY KEY ") {
+			defs = append(defs, def)
+			continue
+		}
+		defs = append(defs, column(def))
+	}
+
+	stmt := "CREATE TABLE " + table + " (\n    " + strings.Join(defs, ",\n    ") + "\n)"
+	return append([]string{stmt}, indexes...)
+}
+
+func translateAlterTable(table, body string, column func(def string) string) []string {
+	var res []string
+	for _, clause := range splitDefinitions(body) {
+		if m := index.FindStringSubmatch(clause); m != nil {
+			res = append(res, createIndex(table, m[1], m[2], m[3])...)
+			continue
+		}
+		if m := dropIndex.FindStringSubmatch(clause); m != nil {
+			// a FULLTEXT index is never created, so it may not exist
+			res = append(res, "DROP INDEX IF EXISTS "+m[1])
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(clause), "ADD COLUMN ") {
+			clause = "ADD COLUMN " + column(clause[len("ADD COLUMN "):])
+		}
+		res = append(res, "ALTER TABLE "+table+" "+clause)
+	}
+	return res
+}
+
+func createIndex(table, kind, name, columns string) []string {
+	switch strings.ToUpper(strings.TrimSpace(kind)) {
+	case "FULLTEXT":
+		return nil
+	case "UNIQUE":
+		return []string{"CREATE UNIQUE INDEX " + name + " ON " + table + " " + columns}
+	}
+	return []string{"CREATE INDEX " + name + " ON " + table + " " + columns}
+}
+
+// statements split a script by a semicolon, a migration does not have a semicolon in a string literal
+func statements(script string) []string {
+	var res []string
+	for _, stmt := range strings.Split(script, ";") {
+		if stmt = strings.TrimSpace(stmt); len(stmt) > 0 {
+			res = append(res, stmt)
+		}
+	}
+	return res
+}
+
+// splitDefinitions split a comma separated definitions, a comma in parentheses is not a separator
+func splitDefinitions(body string) []string {
+	var (
+		res   []string
+		depth int
+		start int
+	)
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(res, strings.TrimSpace(body[start:]))
+}
@@ -0,0 +1,169 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Statements(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Script string
+		Result []string
+	}{
+		{Name: "Empty", Script: " \n ", Result: nil},
+		{Name: "Single_Without_Semicolon", Script: "DROP TABLE cakes", Result: []string{"DROP TABLE cakes"}},
+		{
+			Name:   "Many",
+			Script: "CREATE TABLE a (id INT);\n\n  DROP TABLE b;\n;",
+			Result: []string{"CREATE TABLE a (id INT)", "DROP TABLE b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Result, statements(test.Script))
+		})
+	}
+}
+
+func Test_Split_Definitions(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Body   string
+		Result []string
+	}{
+		{Name: "Single", Body: "id INT", Result: []string{"id INT"}},
+		{
+			Name:   "Trimmed",
+			Body:   "\n    id INT,\n    title VARCHAR(255)\n",
+			Result: []string{"id INT", "title VARCHAR(255)"},
+		},
+		{
+			Name:   "Comma_In_Parentheses",
+			Body:   "price DECIMAL(10,2), INDEX cakes_price_rating (price, rating), CONSTRAINT fk FOREIGN KEY (cake_id) REFERENCES cakes (id)",
+			Result: []string{"price DECIMAL(10,2)", "INDEX cakes_price_rating (price, rating)", "CONSTRAINT fk FOREIGN KEY (cake_id) REFERENCES cakes (id)"},
+		},
+		{
+			Name:   "Nested_Parentheses",
+			Body:   "CHECK ((rating >= 0) AND (rating <= 10)), id INT",
+			Result: []string{"CHECK ((rating >= 0) AND (rating <= 10))", "id INT"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Result, splitDefinitions(test.Body))
+		})
+	}
+}
+
+func Test_Dialect(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Script   string
+		SQLite   []string
+		Postgres []string
+	}{
+		{
+			Name: "Create_Table",
+			Script: `CREATE TABLE cakes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    available TINYINT(1) NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL,
+    UNIQUE INDEX cakes_title (title),
+    INDEX cakes_created_at (created_at),
+    FULLTEXT INDEX cakes_search (title, description)
+);`,
+			SQLite: []string{
+				"CREATE TABLE cakes (\n" +
+					"    id INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+					"    title VARCHAR(255) COLLATE NOCASE NOT NULL,\n" +
+					"    description TEXT COLLATE NOCASE,\n" +
+					"    available TINYINT(1) NOT NULL DEFAULT 1,\n" +
+					"    created_at DATETIME NOT NULL\n)",
+				"CREATE UNIQUE INDEX cakes_title ON cakes (title)",
+				"CREATE INDEX cakes_created_at ON cakes (created_at)",
+			},
+			Postgres: []string{
+				"CREATE TABLE cakes (\n" +
+					"    id SERIAL PRIMARY KEY,\n" +
+					"    title CITEXT NOT NULL,\n" +
+					"    description CITEXT,\n" +
+					"    available SMALLINT NOT NULL DEFAULT 1,\n" +
+					"    created_at TIMESTAMP NOT NULL\n)",
+				"CREATE UNIQUE INDEX cakes_title ON cakes (title)",
+				"CREATE INDEX cakes_created_at ON cakes (created_at)",
+			},
+		},
+		{
+			Name:   "Create_Table_Constraint",
+			Script: "CREATE TABLE cake_tags (cake_id INT NOT NULL, tag_id INT NOT NULL, PRIMARY KEY (cake_id, tag_id), CONSTRAINT fk_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE)",
+			SQLite: []string{
+				"CREATE TABLE cake_tags (\n" +
+					"    cake_id INT NOT NULL,\n" +
+					"    tag_id INT NOT NULL,\n" +
+					"    PRIMARY KEY (cake_id, tag_id),\n" +
+					"    CONSTRAINT fk_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE\n)",
+			},
+			Postgres: []string{
+				"CREATE TABLE cake_tags (\n" +
+					"    cake_id INT NOT NULL,\n" +
+					"    tag_id INT NOT NULL,\n" +
+					"    PRIMARY KEY (cake_id, tag_id),\n" +
+					"    CONSTRAINT fk_cake FOREIGN KEY (cake_id) REFERENCES cakes (id) ON DELETE CASCADE\n)",
+			},
+		},
+		{
+			Name:   "Alter_Table",
+			Script: "ALTER TABLE cakes ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR', ADD COLUMN deleted_at DATETIME NULL, ADD INDEX cakes_currency (currency), ADD FULLTEXT INDEX cakes_title (title)",
+			SQLite: []string{
+				"ALTER TABLE cakes ADD COLUMN currency CHAR(3) COLLATE NOCASE NOT NULL DEFAULT 'IDR'",
+				"ALTER TABLE cakes ADD COLUMN deleted_at DATETIME NULL",
+				"CREATE INDEX cakes_currency ON cakes (currency)",
+			},
+			Postgres: []string{
+				"ALTER TABLE cakes ADD COLUMN currency CITEXT NOT NULL DEFAULT 'IDR'",
+				"ALTER TABLE cakes ADD COLUMN deleted_at TIMESTAMP NULL",
+				"CREATE INDEX cakes_currency ON cakes (currency)",
+			},
+		},
+		{
+			Name:     "Alter_Table_Drop",
+			Script:   "ALTER TABLE cakes DROP INDEX cakes_search, DROP COLUMN currency",
+			SQLite:   []string{"DROP INDEX IF EXISTS cakes_search", "ALTER TABLE cakes DROP COLUMN currency"},
+			Postgres: []string{"DROP INDEX IF EXISTS cakes_search", "ALTER TABLE cakes DROP COLUMN currency"},
+		},
+		{
+			Name:     "Other_Statement",
+			Script:   "UPDATE cakes SET rating = 0; DROP TABLE IF EXISTS reviews",
+			SQLite:   []string{"UPDATE cakes SET rating = 0", "DROP TABLE IF EXISTS reviews"},
+			Postgres: []string{"UPDATE cakes SET rating = 0", "DROP TABLE IF EXISTS reviews"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.SQLite, SQLite(test.Script))
+			assert.Equal(t, test.Postgres, Postgres(test.Script))
+			assert.Equal(t, statements(test.Script), MySQL(test.Script))
+		})
+	}
+}
+
+func Test_Dialect_Embedded_Migrations(t *testing.T) {
+	ms, err := Load()
+	assert.NoError(t, err)
+
+	// every table statement of the embedded migrations is translated
+	for _, m := range ms {
+		for _, stmt := range append(SQLite(m.Up), Postgres(m.Up)...) {
+			assert.NotContains(t, strings.ToUpper(stmt), "AUTO_INCREMENT", "%d_%s", m.Version, m.Name)
+			assert.NotContains(t, strings.ToUpper(stmt), "FULLTEXT", "%d_%s", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/rotisserie/eris"
+)
+
+var ErrNotLocked = eris.New("migration lock is not acquired")
+
+// lock is a session lock which is held for a whole migration run, so two processes (e.g. two replicas
+// starting together) do not apply the same migration twice. acquire wait for the lock and return 1
+type lock struct {
+	acquire string
+	release string
+}
+
+var (
+	// a MySQL lock is server wide, so it's named after the database
+	mysqlLock = &lock{
+		acquire: "SELECT GET_LOCK(CONCAT(DATABASE(), '.schema_migrations'), -1)",
+		release: "SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.schema_migrations'))",
+	}
+
+	// a PostgreSQL advisory lock is per database, the key is a checksum of schema_migrations
+	postgresLockKey = strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte("schema_migrations"))), 10)
+	postgresLock    = &lock{
+		acquire: "SELECT 1 FROM pg_advisory_lock(" + postgresLockKey + ")",
+		release: "SELECT pg_advisory_unlock(" + postgresLockKey + ")",
+	}
+)
+
+// lockOf return a lock of the database driver, SQLite does not have a session lock and it's a local
+// database which is migrated by a single process, so nil is returned
+func lockOf(db *sql.DB) *lock {
+	switch db.Driver().(type) {
+	case *mysql.MySQLDriver:
+		return mysqlLock
+	case *pq.Driver:
+		return postgresLock
+	}
+	return nil
+}
+
+// locked run fn while the migration lock of the database is held
+func locked(ctx context.Context, db *sql.DB, fn func() error) error {
+	return lockOf(db).run(ctx, db, fn)
+}
+
+// run hold the lock on a dedicated connection until fn return, fn is run without a lock when l is nil
+func (l *lock) run(ctx context.Context, db *sql.DB, fn func() error) (err error) {
+	if l == nil {
+		return fn()
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return eris.Wrap(err, "acquire migration lock, an error occurred")
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, l.acquire).Scan(&acquired); err != nil {
+		return eris.Wrap(err, "acquire migration lock, an error occurred")
+	}
+	if acquired.Int64 != 1 {
+		return ErrNotLocked
+	}
+
+	defer func() {
+		// the run may be canceled, the lock is still released
+		if _, rerr := conn.ExecContext(context.Background(), l.release); rerr != nil && err == nil {
+			err = eris.Wrap(rerr, "release migration lock, an error occurred")
+		}
+	}()
+
+	return fn()
+}
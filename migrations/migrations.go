@@ -0,0 +1,349 @@
+// Package migrations embed the database migrations, so the api can migrate a database by itself.
+// the migrations are written in MySQL, a Dialect translate them into another database.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+var (
+	ErrInvalidFileName = eris.New("invalid migration file name")
+	ErrInvalidName     = eris.New("invalid migration name, use a lower case letters, digits and underscores")
+	ErrDirty           = eris.New("database is dirty, fix the failed migration manually then force it's version")
+	ErrPending         = eris.New("database has pending migrations")
+	ErrUnknownVersion  = eris.New("unknown migration version")
+)
+
+// Migration is a pair of up and down scripts of a version
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load read every embedded migrations ordered by it's version
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, eris.Wrap(err, "load migrations, an error occurred")
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, name := range names {
+		// a file is named as {version}_{name}.{up|down}.sql, like golang-migrate
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+		version, rest, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, eris.Wrap(ErrInvalidFileName, name)
+		}
+
+		v, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return nil, eris.Wrap(ErrInvalidFileName, name)
+		}
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, eris.Wrap(err, "load migrations, an error occurred")
+		}
+
+		m, ok := byVersion[v]
+		if !ok {
+			m = &Migration{Version: v}
+			byVersion[v] = m
+		}
+
+		switch {
+		case strings.HasSuffix(rest, ".up"):
+			m.Name, m.Up = strings.TrimSuffix(rest, ".up"), string(b)
+		case strings.HasSuffix(rest, ".down"):
+			m.Name, m.Down = strings.TrimSuffix(rest, ".down"), string(b)
+		default:
+			return nil, eris.Wrap(ErrInvalidFileName, name)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+
+	return res, nil
+}
+
+// Version return a current schema version and whether it's dirty, zero mean no migration is applied.
+// a version is tracked in schema_migrations the same way as golang-migrate, so a database can be
+// migrated by either of them
+func Version(ctx context.Context, db *sql.DB) (uint64, bool, error) {
+	query := "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)"
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return 0, false, eris.Wrap(err, "create schema migrations, an error occurred")
+	}
+
+	return readVersion(ctx, db)
+}
+
+// readVersion return a current schema version without creating schema_migrations,
+// a database without it has no migration applied
+func readVersion(ctx context.Context, db *sql.DB) (uint64, bool, error) {
+	var (
+		version uint64
+		dirty   bool
+	)
+	query := "SELECT version, dirty FROM schema_migrations LIMIT 1"
+	if err := db.QueryRowContext(ctx, query).Scan(&version, &dirty); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		if isUndefinedTable(err) {
+			return 0, false, nil
+		}
+		return 0, false, eris.Wrap(err, "find schema version, an error occurred")
+	}
+
+	return version, dirty, nil
+}
+
+// Check return ErrDirty when the last migration failed and ErrPending when there is a migration
+// which is not applied yet, a database is only served by an up to date schema. it's read-only, so a database
+// without schema_migrations is pending instead of being created
+func Check(ctx context.Context, db *sql.DB) error {
+	ms, err := Load()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := readVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return eris.Wrapf(ErrDirty, "version %d", version)
+	}
+	if latest := ms[len(ms)-1].Version; version < latest {
+		return eris.Wrapf(ErrPending, "version %d, latest version %d", version, latest)
+	}
+
+	return nil
+}
+
+// Up apply every pending migrations in a dialect, the migration lock is held for the whole run
+func Up(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	ms, err := Load()
+	if err != nil {
+		return err
+	}
+
+	return locked(ctx, db, func() error {
+		version, err := clean(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		return migrate(ctx, db, dialect, ms, version, ms[len(ms)-1].Version)
+	})
+}
+
+// Steps apply n pending migrations when n is positive or roll back -n applied migrations when n is
+// negative in a dialect, it stop at the latest or the first migration
+func Steps(ctx context.Context, db *sql.DB, dialect Dialect, n int) error {
+	ms, err := Load()
+	if err != nil {
+		return err
+	}
+
+	return locked(ctx, db, func() error {
+		version, err := clean(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		i, err := position(ms, version)
+		if err != nil {
+			return err
+		}
+
+		target := uint64(0)
+		switch i += n; {
+		case i >= len(ms):
+			target = ms[len(ms)-1].Version
+		case i >= 0:
+			target = ms[i].Version
+		}
+
+		return migrate(ctx, db, dialect, ms, version, target)
+	})
+}
+
+// Goto migrate up or down into a version in a dialect, version zero roll back every migrations
+func Goto(ctx context.Context, db *sql.DB, dialect Dialect, version uint64) error {
+	ms, err := Load()
+	if err != nil {
+		return err
+	}
+
+	if _, err := position(ms, version); err != nil {
+		return err
+	}
+
+	return locked(ctx, db, func() error {
+		current, err := clean(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		return migrate(ctx, db, dialect, ms, current, version)
+	})
+}
+
+// Force set a schema version without running a migration and clear the dirty flag,
+// it's used after a failed migration is fixed manually
+func Force(ctx context.Context, db *sql.DB, version uint64) error {
+	ms, err := Load()
+	if err != nil {
+		return err
+	}
+
+	if _, err := position(ms, version); err != nil {
+		return err
+	}
+
+	return locked(ctx, db, func() error {
+		if _, _, err := Version(ctx, db); err != nil {
+			return err
+		}
+
+		return markVersion(ctx, db, version, false)
+	})
+}
+
+// clean return a current schema version, ErrDirty is returned when the last migration failed
+func clean(ctx context.Context, db *sql.DB) (uint64, error) {
+	version, dirty, err := Version(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, eris.Wrapf(ErrDirty, "version %d", version)
+	}
+	return version, nil
+}
+
+// position return an index of a version in ms, version zero is an index before the first migration
+func position(ms []Migration, version uint64) (int, error) {
+	if version == 0 {
+		return -1, nil
+	}
+	for i, m := range ms {
+		if m.Version == version {
+			return i, nil
+		}
+	}
+	return 0, eris.Wrapf(ErrUnknownVersion, "version %d", version)
+}
+
+// migrate apply an up migrations after the current version until the target version,
+// or a down migrations from the current version until the target version
+func migrate(ctx context.Context, db *sql.DB, dialect Dialect, ms []Migration, current, target uint64) error {
+	for _, m := range ms {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		if err := apply(ctx, db, m.Version, dialect(m.Up)); err != nil {
+			return eris.Wrapf(err, "migrate up %d_%s", m.Version, m.Name)
+		}
+	}
+
+	for i := len(ms) - 1; i >= 0; i-- {
+		m := ms[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+
+		prev := uint64(0)
+		if i > 0 {
+			prev = ms[i-1].Version
+		}
+		if err := apply(ctx, db, prev, dialect(m.Down)); err != nil {
+			return eris.Wrapf(err, "migrate down %d_%s", m.Version, m.Name)
+		}
+	}
+
+	return nil
+}
+
+// apply run a migration statements which migrate into a version. the version is marked dirty before,
+// so a failed migration on a database without a transactional DDL (e.g. MySQL) is not silently skipped.
+// the statements and a clean version is committed together
+func apply(ctx context.Context, db *sql.DB, version uint64, stmts []string) error {
+	if err := markVersion(ctx, db, version, true); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return eris.Wrap(err, stmt)
+		}
+	}
+
+	if err := setVersion(ctx, tx, version, false); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// markVersion replace a schema version in it's own transaction
+func markVersion(ctx context.Context, db *sql.DB, version uint64, dirty bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return eris.Wrap(err, "set schema version, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := setVersion(ctx, tx, version, dirty); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setVersion replace a schema version, version zero without a dirty flag mean no migration is applied
+func setVersion(ctx context.Context, tx *sql.Tx, version uint64, dirty bool) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return eris.Wrap(err, "set schema version, an error occurred")
+	}
+	if version == 0 && !dirty {
+		return nil
+	}
+
+	// the version is formatted into the statement, a placeholder is written differently on each database
+	query := "INSERT INTO schema_migrations (version, dirty) VALUES (" + strconv.FormatUint(version, 10) + ", " + strings.ToUpper(strconv.FormatBool(dirty)) + ")"
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return eris.Wrap(err, "set schema version, an error occurred")
+	}
+
+	return nil
+}
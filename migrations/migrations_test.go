@@ -0,0 +1,267 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/db"
+)
+
+func NewMock() (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		log.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return db, mock
+}
+
+func NewSQLite(t *testing.T) *sql.DB {
+	conn, err := db.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "migrations.db"))
+	if err != nil {
+		t.Fatalf("an error '%+v' was not expected when opening a sqlite database", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func Test_Load(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Files  fstest.MapFS
+		Result []Migration
+		Error  error
+	}{
+		{
+			Name: "Ordered_By_Version",
+			Files: fstest.MapFS{
+				"000010_create_reviews.up.sql":   {Data: []byte("CREATE TABLE reviews (id INT)")},
+				"000010_create_reviews.down.sql": {Data: []byte("DROP TABLE reviews")},
+				"000002_create_cakes.up.sql":     {Data: []byte("CREATE TABLE cakes (id INT)")},
+				"000002_create_cakes.down.sql":   {Data: []byte("DROP TABLE cakes")},
+			},
+			Result: []Migration{
+				{Version: 2, Name: "create_cakes", Up: "CREATE TABLE cakes (id INT)", Down: "DROP TABLE cakes"},
+				{Version: 10, Name: "create_reviews", Up: "CREATE TABLE reviews (id INT)", Down: "DROP TABLE reviews"},
+			},
+		},
+		{
+			Name:  "Without_Name",
+			Files: fstest.MapFS{"000001.up.sql": {}},
+			Error: ErrInvalidFileName,
+		},
+		{
+			Name:  "Invalid_Version",
+			Files: fstest.MapFS{"first_create_cakes.up.sql": {}},
+			Error: ErrInvalidFileName,
+		},
+		{
+			Name:  "Invalid_Direction",
+			Files: fstest.MapFS{"000001_create_cakes.sql": {}},
+			Error: ErrInvalidFileName,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			res, err := load(test.Files)
+			if test.Error != nil {
+				assert.ErrorIs(t, err, test.Error)
+				assert.Nil(t, res)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Result, res)
+		})
+	}
+}
+
+func Test_Migrate(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		conn = NewSQLite(t)
+		ms   = []Migration{
+			{Version: 1, Name: "create_cakes", Up: "CREATE TABLE cakes (id INT AUTO_INCREMENT PRIMARY KEY, title VARCHAR(255))", Down: "DROP TABLE cakes"},
+			{Version: 2, Name: "add_rating", Up: "ALTER TABLE cakes ADD COLUMN rating INT NOT NULL DEFAULT 0", Down: "ALTER TABLE cakes DROP COLUMN rating"},
+			{Version: 3, Name: "broken", Up: "CREATE TABLE reviews (id INT); INSERT INTO unknown VALUES (1)", Down: "DROP TABLE reviews"},
+		}
+		tables = func() []string {
+			rows, err := conn.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence') ORDER BY name")
+			assert.NoError(t, err)
+			defer rows.Close()
+
+			var res []string
+			for rows.Next() {
+				var name string
+				assert.NoError(t, rows.Scan(&name))
+				res = append(res, name)
+			}
+			return res
+		}
+	)
+
+	version, dirty, err := Version(ctx, conn)
+	assert.NoError(t, err)
+	assert.Zero(t, version)
+	assert.False(t, dirty)
+
+	// up until the target
+	assert.NoError(t, migrate(ctx, conn, SQLite, ms, 0, 2))
+	version, dirty, _ = Version(ctx, conn)
+	assert.Equal(t, uint64(2), version)
+	assert.False(t, dirty)
+	assert.Equal(t, []string{"cakes"}, tables())
+
+	// down into the previous version
+	assert.NoError(t, migrate(ctx, conn, SQLite, ms, 2, 1))
+	version, _, _ = Version(ctx, conn)
+	assert.Equal(t, uint64(1), version)
+	_, err = conn.ExecContext(ctx, "SELECT rating FROM cakes")
+	assert.Error(t, err)
+
+	// a failed migration is rolled back and it's version is left dirty
+	err = migrate(ctx, conn, SQLite, ms, 1, 3)
+	assert.ErrorContains(t, err, "migrate up 3_broken")
+	version, dirty, _ = Version(ctx, conn)
+	assert.Equal(t, uint64(3), version)
+	assert.True(t, dirty)
+	assert.Equal(t, []string{"cakes"}, tables())
+
+	_, err = clean(ctx, conn)
+	assert.ErrorIs(t, err, ErrDirty)
+
+	// down into zero after the version is forced back
+	assert.NoError(t, markVersion(ctx, conn, 2, false))
+	assert.NoError(t, migrate(ctx, conn, SQLite, ms, 2, 0))
+	version, dirty, _ = Version(ctx, conn)
+	assert.Zero(t, version)
+	assert.False(t, dirty)
+	assert.Empty(t, tables())
+}
+
+func Test_Check_Read_Only(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		conn = NewSQLite(t)
+	)
+
+	// a fresh database is pending and schema_migrations is not created
+	assert.ErrorIs(t, Check(ctx, conn), ErrPending)
+
+	var n int
+	assert.NoError(t, conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&n))
+	assert.Zero(t, n)
+}
+
+func Test_Read_Version_Undefined_Table(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Err   error
+		Error bool
+	}{
+		{Name: "MySQL", Err: &mysql.MySQLError{Number: 1146, Message: "Table 'cake-store.schema_migrations' doesn't exist"}},
+		{Name: "Postgres", Err: &pq.Error{Code: "42P01", Message: `relation "schema_migrations" does not exist`}},
+		{Name: "Other", Err: &mysql.MySQLError{Number: 1045, Message: "Access denied"}, Error: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			conn, mock := NewMock()
+			defer conn.Close()
+
+			mock.ExpectQuery("SELECT version, dirty FROM schema_migrations LIMIT 1").WillReturnError(test.Err)
+
+			version, dirty, err := readVersion(context.Background(), conn)
+			if test.Error {
+				assert.ErrorIs(t, err, test.Err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Zero(t, version)
+			assert.False(t, dirty)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_Position(t *testing.T) {
+	ms := []Migration{{Version: 1}, {Version: 5}}
+
+	i, err := position(ms, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, i)
+
+	i, err = position(ms, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+
+	_, err = position(ms, 3)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func Test_Lock(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		failed = errors.New("migration failed")
+	)
+
+	for _, l := range []*lock{mysqlLock, postgresLock} {
+		t.Run(l.acquire, func(t *testing.T) {
+			conn, mock := NewMock()
+			defer conn.Close()
+
+			// the lock is held while fn run and it's released after
+			mock.ExpectQuery(l.acquire).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+			mock.ExpectExec("UPDATE cakes SET rating = 0").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(l.release).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			err := l.run(ctx, conn, func() error {
+				_, err := conn.ExecContext(ctx, "UPDATE cakes SET rating = 0")
+				return err
+			})
+			assert.NoError(t, err)
+
+			// the lock is released when fn failed
+			mock.ExpectQuery(l.acquire).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+			mock.ExpectExec(l.release).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			err = l.run(ctx, conn, func() error { return failed })
+			assert.ErrorIs(t, err, failed)
+
+			// fn is not run without the lock
+			mock.ExpectQuery(l.acquire).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(nil))
+
+			err = l.run(ctx, conn, func() error { return failed })
+			assert.ErrorIs(t, err, ErrNotLocked)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_Lock_Of(t *testing.T) {
+	mysqlDB, _ := sql.Open("mysql", "root:secret@tcp(localhost:3306)/cake-store")
+	defer mysqlDB.Close()
+	assert.Same(t, mysqlLock, lockOf(mysqlDB))
+
+	postgresDB, _ := sql.Open("postgres", "postgres://localhost:5432/cake-store")
+	defer postgresDB.Close()
+	assert.Same(t, postgresLock, lockOf(postgresDB))
+
+	// SQLite is run without a lock
+	sqliteDB := NewSQLite(t)
+	assert.Nil(t, lockOf(sqliteDB))
+	assert.NoError(t, Up(context.Background(), sqliteDB, SQLite))
+	assert.NoError(t, Check(context.Background(), sqliteDB))
+}
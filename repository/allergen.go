@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type Allergen struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// FindByCakeIDs list an allergens of each cakes, keyed by cake id
+func (s *Allergen) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]string, error) {
+	res := map[int][]string{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := "SELECT cake_id, allergen FROM cake_allergens WHERE cake_id IN (" + placeholders(len(ids)) + ") ORDER BY allergen ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), intArgs(ids)...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find allergens by cake ids, an error occurred")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cakeID   int
+			allergen string
+		)
+		if err := rows.Scan(&cakeID, &allergen); err != nil {
+			return nil, eris.Wrap(err, "find allergens by cake ids, an error occurred")
+		}
+		res[cakeID] = append(res[cakeID], allergen)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, eris.Wrap(err, "find allergens by cake ids, an error occurred")
+	}
+
+	return res, nil
+}
+
+// SetCakeAllergens replace an allergens of a cake
+func (s *Allergen) SetCakeAllergens(ctx context.Context, cakeID int, allergens []string) error {
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "set cake allergens, an error occurred")
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM cake_allergens WHERE cake_id = ?"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), cakeID); err != nil {
+		return eris.Wrap(err, "set cake allergens, an error occurred")
+	}
+
+	if len(allergens) > 0 {
+		var (
+			values = make([]string, len(allergens))
+			args   = make([]any, 0, len(allergens)*2)
+		)
+		for i, allergen := range allergens {
+			values[i] = "(?, ?)"
+			args = append(args, cakeID, allergen)
+		}
+
+		query = "INSERT INTO cake_allergens (cake_id, allergen) VALUES " + strings.Join(values, ", ")
+		log.Print(query)
+
+		if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), args...); err != nil {
+			return eris.Wrap(err, "set cake allergens, an error occurred")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "set cake allergens, an error occurred")
+	}
+
+	return nil
+}
+
+type Nutrition struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// FindByCakeIDs find a nutrition facts of each cakes, keyed by cake id.
+// a cake without nutrition facts is not on the result
+func (s *Nutrition) FindByCakeIDs(ctx context.Context, ids []int) (map[int]*schema.Nutrition, error) {
+	res := map[int]*schema.Nutrition{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := "SELECT * FROM cake_nutrition WHERE cake_id IN (" + placeholders(len(ids)) + ")"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), intArgs(ids)...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find nutrition by cake ids, an error occurred")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cakeID int
+			o      schema.Nutrition
+		)
+		if err := rows.Scan(
+			&cakeID,
+			&o.ServingSize,
+			&o.Energy,
+			&o.Fat,
+			&o.SaturatedFat,
+			&o.Carbohydrate,
+			&o.Sugars,
+			&o.Protein,
+			&o.Salt,
+		); err != nil {
+			return nil, eris.Wrap(err, "find nutrition by cake ids, an error occurred")
+		}
+		res[cakeID] = &o
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, eris.Wrap(err, "find nutrition by cake ids, an error occurred")
+	}
+
+	return res, nil
+}
+
+// SetCakeNutrition insert or replace a nutrition facts of a cake
+func (s *Nutrition) SetCakeNutrition(ctx context.Context, cakeID int, rec *schema.Nutrition) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	query := "INSERT INTO cake_nutrition (cake_id, serving_size, energy, fat, saturated_fat, carbohydrate, sugars, protein, salt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) " +
+		s.Dialect.upsert("cake_id", "serving_size", "energy", "fat", "saturated_fat", "carbohydrate", "sugars", "protein", "salt")
+	log.Print(query)
+
+	if _, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query),
+		cakeID,
+		rec.ServingSize,
+		rec.Energy,
+		rec.Fat,
+		rec.SaturatedFat,
+		rec.Carbohydrate,
+		rec.Sugars,
+		rec.Protein,
+		rec.Salt,
+	); err != nil {
+		return eris.Wrap(err, "set cake nutrition, an error occurred")
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type AllergenMock struct {
+	mock.Mock
+}
+
+func (m *AllergenMock) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]string, error) {
+	args := m.Called(ctx, ids)
+	res, _ := args.Get(0).(map[int][]string)
+	return res, args.Error(1)
+}
+
+func (m *AllergenMock) SetCakeAllergens(ctx context.Context, cakeID int, allergens []string) error {
+	args := m.Called(ctx, cakeID, allergens)
+	return args.Error(0)
+}
+
+type NutritionMock struct {
+	mock.Mock
+}
+
+func (m *NutritionMock) FindByCakeIDs(ctx context.Context, ids []int) (map[int]*schema.Nutrition, error) {
+	args := m.Called(ctx, ids)
+	res, _ := args.Get(0).(map[int]*schema.Nutrition)
+	return res, args.Error(1)
+}
+
+func (m *NutritionMock) SetCakeNutrition(ctx context.Context, cakeID int, rec *schema.Nutrition) error {
+	args := m.Called(ctx, cakeID, rec)
+	return args.Error(0)
+}
@@ -0,0 +1,120 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var nutritionColumns = []string{
+	"cake_id",
+	"serving_size",
+	"energy",
+	"fat",
+	"saturated_fat",
+	"carbohydrate",
+	"sugars",
+	"protein",
+	"salt",
+}
+
+var nutrition = schema.Nutrition{
+	ServingSize:  120,
+	Energy:       410,
+	Fat:          24.5,
+	SaturatedFat: 14.2,
+	Carbohydrate: 42,
+	Sugars:       30.1,
+	Protein:      6.3,
+	Salt:         0.4,
+}
+
+func Test_Allergen_Repository_Find_By_Cake_IDs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"cake_id", "allergen"}).
+		AddRow(1, "dairy").
+		AddRow(1, "gluten").
+		AddRow(2, "nuts")
+	mock.ExpectQuery("SELECT cake_id, allergen FROM cake_allergens WHERE cake_id IN (?, ?) ORDER BY allergen ASC").
+		WithArgs(1, 2).WillReturnRows(rows)
+
+	repo := &repository.Allergen{DB: db}
+	res, err := repo.FindByCakeIDs(context.Background(), []int{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int][]string{1: {"dairy", "gluten"}, 2: {"nuts"}}, res)
+}
+
+func Test_Allergen_Repository_Set_Cake_Allergens(t *testing.T) {
+	t.Run("Replaced", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM cake_allergens WHERE cake_id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO cake_allergens (cake_id, allergen) VALUES (?, ?), (?, ?)").
+			WithArgs(1, "dairy", 1, "eggs").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := &repository.Allergen{DB: db}
+		assert.NoError(t, repo.SetCakeAllergens(context.Background(), 1, []string{"dairy", "eggs"}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Cleared", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM cake_allergens WHERE cake_id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := &repository.Allergen{DB: db}
+		assert.NoError(t, repo.SetCakeAllergens(context.Background(), 1, []string{}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Nutrition_Repository_Find_By_Cake_IDs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(nutritionColumns).AddRow(
+		1,
+		nutrition.ServingSize,
+		nutrition.Energy,
+		nutrition.Fat,
+		nutrition.SaturatedFat,
+		nutrition.Carbohydrate,
+		nutrition.Sugars,
+		nutrition.Protein,
+		nutrition.Salt,
+	)
+	mock.ExpectQuery("SELECT * FROM cake_nutrition WHERE cake_id IN (?, ?)").WithArgs(1, 2).WillReturnRows(rows)
+
+	repo := &repository.Nutrition{DB: db}
+	res, err := repo.FindByCakeIDs(context.Background(), []int{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]*schema.Nutrition{1: &nutrition}, res)
+}
+
+func Test_Nutrition_Repository_Set_Cake_Nutrition(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO cake_nutrition (cake_id, serving_size, energy, fat, saturated_fat, carbohydrate, sugars, protein, salt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE serving_size = VALUES(serving_size), energy = VALUES(energy), fat = VALUES(fat), saturated_fat = VALUES(saturated_fat), "+
+		"carbohydrate = VALUES(carbohydrate), sugars = VALUES(sugars), protein = VALUES(protein), salt = VALUES(salt)").
+		WithArgs(1, nutrition.ServingSize, nutrition.Energy, nutrition.Fat, nutrition.SaturatedFat, nutrition.Carbohydrate, nutrition.Sugars, nutrition.Protein, nutrition.Salt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &repository.Nutrition{DB: db}
+	rec := nutrition
+	assert.NoError(t, repo.SetCakeNutrition(context.Background(), 1, &rec))
+	assert.ErrorIs(t, repo.SetCakeNutrition(context.Background(), 1, nil), repository.ErrRecordNill)
+}
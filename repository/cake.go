@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/rotisserie/eris"
 	"github.com/zufzuf/cake-store/libs/util"
@@ -18,21 +19,29 @@ var (
 	ErrFilterNill     = eris.New("filter is nil")
 	ErrRecordNill     = eris.New("record is nil")
 	ErrRecordNotFound = eris.New("record not found")
+
+	ErrVersionConflict = eris.New("record version conflict")
 )
 
 type Cake struct {
-	DB *sql.DB
+	DB      *sql.DB
+	Dialect Dialect
 }
 
+// Find find a cake by it's id, in a transaction the cake is locked until the transaction end
+// so it can not be changed or deleted between a read and a write
 func (s *Cake) Find(ctx context.Context, id int) (*schema.Cake, error) {
 	if id <= 0 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := "SELECT * FROM cakes WHERE id = ? LIMIT 1"
+	query := "SELECT * FROM cakes WHERE id = ? AND deleted_at IS NULL LIMIT 1"
+	if txFrom(ctx) != nil {
+		query += s.Dialect.forUpdate()
+	}
 	log.Print(query)
 
-	rows, err := s.DB.QueryContext(ctx, query, id)
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), id)
 	if err != nil {
 		return nil, eris.Wrap(err, "find cake by id, an error occurred")
 	}
@@ -49,9 +58,60 @@ func (s *Cake) Find(ctx context.Context, id int) (*schema.Cake, error) {
 	return &res, nil
 }
 
+const (
+	SearchModeNatural = "natural"
+	SearchModeBoolean = "boolean"
+)
+
 type FindAllFilter struct {
+	// Trashed list a soft deleted cakes instead of an active cakes
+	Trashed bool
+
 	Title       string
 	Description string
+
+	// Search is a full-text search on title and description, SearchMode is one of
+	// SearchModeNatural (default) or SearchModeBoolean
+	Search     string
+	SearchMode string
+
+	// Category is a category slug, a cake in it's sub categories is matched as well,
+	// Tag is a tag name
+	Category string
+	Tag      string
+
+	// InStock list a cakes which on-hand quantity is greater than zero
+	InStock bool
+
+	// ExcludeAllergens list a cakes which contain none of the allergens
+	ExcludeAllergens []string
+
+	// RatingMin and RatingMax is an inclusive bounds, nil mean unbounded
+	RatingMin *float64
+	RatingMax *float64
+
+	// PriceMin and PriceMax is an inclusive bounds in a minor unit, nil mean unbounded.
+	// a price is only comparable within a currency, so Currency should be set along with it
+	Currency string
+	PriceMin *int64
+	PriceMax *int64
+
+	// CreatedAfter and CreatedBefore is an exclusive bounds, UpdatedSince is inclusive,
+	// zero time mean unbounded
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedSince  time.Time
+
+	// Sort fallback to CakeDefaultSort when it's empty
+	Sort []SortField
+
+	// Limit is a page size, Cursor is an opaque value from a previous page
+	Limit  int
+	Cursor string
+
+	// NextCursor and HasMore is filled by FindAll
+	NextCursor string
+	HasMore    bool
 }
 
 func (f *FindAllFilter) IsValidTitle() bool {
@@ -62,31 +122,98 @@ func (f *FindAllFilter) IsValidDescription() bool {
 	return len(f.Description) > 0
 }
 
+func (f *FindAllFilter) IsValidSearch() bool {
+	return len(f.Search) > 0
+}
+
+// matchQuery return a full-text match expression and it's argument, on MySQL the expression is backed by
+// ft_cakes_title_description index and the other dialects fallback to substringMatch
+func (f *FindAllFilter) matchQuery(d Dialect) (string, []any) {
+	if !f.IsValidSearch() {
+		return "", nil
+	}
+	if !d.isMySQL() {
+		return substringMatch(f.Search, f.SearchMode)
+	}
+
+	if f.SearchMode == SearchModeBoolean {
+		return "MATCH (title, description) AGAINST (? IN BOOLEAN MODE)", []any{booleanSearch(f.Search)}
+	}
+
+	return "MATCH (title, description) AGAINST (? IN NATURAL LANGUAGE MODE)", []any{f.Search}
+}
+
+func (f *FindAllFilter) IsValidCategory() bool {
+	return len(f.Category) > 0
+}
+
+func (f *FindAllFilter) IsValidTag() bool {
+	return len(f.Tag) > 0
+}
+
+func (f *FindAllFilter) IsValidInStock() bool {
+	return f.InStock
+}
+
+func (f *FindAllFilter) IsValidExcludeAllergens() bool {
+	return len(f.ExcludeAllergens) > 0
+}
+
+func (f *FindAllFilter) IsValidRatingMin() bool {
+	return f.RatingMin != nil
+}
+
+func (f *FindAllFilter) IsValidRatingMax() bool {
+	return f.RatingMax != nil
+}
+
+func (f *FindAllFilter) IsValidCurrency() bool {
+	return len(f.Currency) > 0
+}
+
+func (f *FindAllFilter) IsValidPriceMin() bool {
+	return f.PriceMin != nil
+}
+
+func (f *FindAllFilter) IsValidPriceMax() bool {
+	return f.PriceMax != nil
+}
+
+func (f *FindAllFilter) IsValidCreatedAfter() bool {
+	return !f.CreatedAfter.IsZero()
+}
+
+func (f *FindAllFilter) IsValidCreatedBefore() bool {
+	return !f.CreatedBefore.IsZero()
+}
+
+func (f *FindAllFilter) IsValidUpdatedSince() bool {
+	return !f.UpdatedSince.IsZero()
+}
+
 func (s *Cake) FindAll(ctx context.Context, fil *FindAllFilter) ([]schema.Cake, error) {
 	if fil == nil {
 		return nil, ErrFilterNill
 	}
 
-	q := util.NewQuery()
-	if fil.IsValidTitle() {
-		q.Where("title LIKE ?", "%"+fil.Title+"%")
+	query, args, sort, sortKey, err := s.selectQuery(fil)
+	if err != nil {
+		return nil, err
 	}
 
-	if fil.IsValidDescription() {
-		q.Where("description LIKE ?", "%"+fil.Description+"%")
-	}
+	// fetch one more record to know there is a next page or not
+	limit := NormalizeLimit(fil.Limit)
 
-	where, args := q.Build()
-	query := "SELECT * FROM cakes " + where + " ORDER BY title ASC, rating ASC"
+	query += " LIMIT ?"
 	log.Print(query)
 
-	rows, err := s.DB.QueryContext(ctx, query, args...)
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), append(args, limit+1)...)
 	if err != nil {
 		return nil, eris.Wrap(err, "find cakes, an error occurred")
 	}
 
 	res := []schema.Cake{}
-	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+	if err := s.retrieveRows(rows, &res, fil.IsValidSearch()); err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return nil, eris.Wrap(err, "find cakes, an error occurred")
 	}
 
@@ -94,31 +221,200 @@ func (s *Cake) FindAll(ctx context.Context, fil *FindAllFilter) ([]schema.Cake,
 		return nil, ErrRecordNotFound
 	}
 
+	fil.NextCursor, fil.HasMore = "", len(res) > limit
+	if fil.HasMore {
+		res = res[:limit]
+		if fil.NextCursor, err = encodeCursor(sortKey, sort, &res[limit-1]); err != nil {
+			return nil, err
+		}
+	}
+
 	return res, nil
 }
 
-func (s *Cake) Insert(ctx context.Context, rec *schema.Cake) error {
-	if rec == nil {
-		return ErrRecordNill
+// Stream call fn on each cakes of a filter in it's order, a cake is scanned one by one from the rows
+// so a whole catalog is never loaded at once. fil.Limit is ignored, a cursor is still applied.
+// the rows is kept open while fn is called, so fn must not run on a transaction of ctx
+func (s *Cake) Stream(ctx context.Context, fil *FindAllFilter, fn func(rec *schema.Cake) error) error {
+	if fil == nil {
+		return ErrFilterNill
 	}
 
-	query := "INSERT INTO cakes (title, description, rating, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)"
+	query, args, _, _, err := s.selectQuery(fil)
+	if err != nil {
+		return err
+	}
 	log.Print(query)
 
-	res, err := s.DB.ExecContext(ctx, query, rec.Title, rec.Description, rec.Rating, rec.Image, rec.CreatedAt, rec.UpdatedAt)
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), args...)
 	if err != nil {
-		return eris.Wrap(err, "insert cake, an error occurred")
+		return eris.Wrap(err, "stream cakes, an error occurred")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			o    schema.Cake
+			dest = cakeFields(&o)
+		)
+		if fil.IsValidSearch() {
+			dest = append(dest, &o.Score)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return eris.Wrap(err, "stream cakes, an error occurred")
+		}
+
+		if err := fn(&o); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return eris.Wrap(err, "stream cakes, an error occurred")
+	}
+
+	return nil
+}
+
+// selectQuery build a select of the cakes of a filter which is ordered by it's sort and start after it's cursor,
+// a limit is appended by the caller. the sort columns and key is returned to encode a next cursor
+func (s *Cake) selectQuery(fil *FindAllFilter) (string, []any, []sortColumn, string, error) {
+	var (
+		q                = util.NewQuery()
+		match, matchArgs = fil.matchQuery(s.Dialect)
+	)
+
+	if fil.Trashed {
+		q.Where("deleted_at IS NOT NULL")
+	} else {
+		q.Where("deleted_at IS NULL")
+	}
+
+	if fil.IsValidSearch() {
+		if s.Dialect.isMySQL() {
+			q.Where(match, matchArgs...)
+		} else {
+			q.Where(match+" > 0", matchArgs...)
+		}
+	}
+
+	if fil.IsValidTitle() {
+		q.Where("title LIKE ?", "%"+fil.Title+"%")
+	}
+
+	if fil.IsValidDescription() {
+		q.Where("description LIKE ?", "%"+fil.Description+"%")
+	}
+
+	if fil.IsValidCategory() {
+		q.Where("id IN (SELECT cc.cake_id FROM cake_categories cc WHERE cc.category_id IN ("+
+			"WITH RECURSIVE tree AS ("+
+			"SELECT id FROM categories WHERE slug = ? "+
+			"UNION ALL SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id"+
+			") SELECT id FROM tree))", fil.Category)
+	}
+
+	if fil.IsValidTag() {
+		q.Where("id IN (SELECT ct.cake_id FROM cake_tags ct JOIN tags t ON t.id = ct.tag_id WHERE t.name = ?)", fil.Tag)
+	}
+
+	if fil.IsValidInStock() {
+		q.Where("id IN (SELECT cake_id FROM cake_stocks WHERE quantity > 0)")
+	}
+
+	if fil.IsValidExcludeAllergens() {
+		q.Where("id NOT IN (SELECT cake_id FROM cake_allergens WHERE allergen IN ("+placeholders(len(fil.ExcludeAllergens))+"))",
+			stringArgs(fil.ExcludeAllergens)...)
+	}
+
+	if fil.IsValidRatingMin() {
+		q.Where("rating >= ?", *fil.RatingMin)
+	}
+
+	if fil.IsValidRatingMax() {
+		q.Where("rating <= ?", *fil.RatingMax)
+	}
+
+	if fil.IsValidCurrency() {
+		q.Where("currency = ?", fil.Currency)
+	}
+
+	if fil.IsValidPriceMin() {
+		q.Where("price >= ?", *fil.PriceMin)
+	}
+
+	if fil.IsValidPriceMax() {
+		q.Where("price <= ?", *fil.PriceMax)
+	}
+
+	if fil.IsValidCreatedAfter() {
+		q.Where("created_at > ?", fil.CreatedAfter)
+	}
+
+	if fil.IsValidCreatedBefore() {
+		q.Where("created_at < ?", fil.CreatedBefore)
 	}
 
-	id, err := res.LastInsertId()
+	if fil.IsValidUpdatedSince() {
+		q.Where("updated_at >= ?", fil.UpdatedSince)
+	}
+
+	fields := fil.Sort
+	if len(fields) == 0 {
+		fields = CakeDefaultSort
+		if fil.IsValidSearch() {
+			fields = CakeSearchDefaultSort
+		}
+	}
+
+	sort, err := cakeSort(fields, match, matchArgs...)
+	if err != nil {
+		return "", nil, nil, "", err
+	}
+	sortKey := joinSort(fields)
+
+	if len(fil.Cursor) > 0 {
+		vals, err := decodeCursor(sortKey, sort, fil.Cursor)
+		if err != nil {
+			return "", nil, nil, "", err
+		}
+		keyset, keysetArgs := keysetQuery(sort, vals)
+		q.Where(keyset, keysetArgs...)
+	}
+
+	// the relevance score is selected only on a full-text search
+	selects, args := "*", []any{}
+	if fil.IsValidSearch() {
+		selects, args = "*, "+match+" AS score", append(args, matchArgs...)
+	}
+
+	where, whereArgs := q.Build()
+	query := "SELECT " + selects + " FROM cakes " + where + " " + orderBy(sort)
+
+	return query, append(args, whereArgs...), sort, sortKey, nil
+}
+
+func (s *Cake) Insert(ctx context.Context, rec *schema.Cake) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	query := "INSERT INTO cakes (title, description, rating, image, price, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, conn(ctx, s.DB), query, rec.Title, rec.Description, rec.Rating, rec.Image, rec.Price, rec.Currency, rec.CreatedAt, rec.UpdatedAt)
 	if err != nil {
 		return eris.Wrap(err, "insert cake, an error occurred")
 	}
 	rec.ID = int(id)
+	rec.Version = 1
 
 	return nil
 }
 
+// Update update a cake and increase it's version, when rec.Version is set the update
+// is only applied on the same version, otherwise ErrVersionConflict is returned
 func (s *Cake) Update(ctx context.Context, rec *schema.Cake) error {
 	if rec == nil {
 		return ErrRecordNill
@@ -127,38 +423,163 @@ func (s *Cake) Update(ctx context.Context, rec *schema.Cake) error {
 		return ErrRecordNotFound
 	}
 
-	query := "UPDATE cakes SET title=?, description=?, rating=?, image=?, updated_at=? WHERE id = ?"
-	log.Print(query)
-
-	if _, err := s.DB.ExecContext(ctx, query,
+	query := "UPDATE cakes SET title=?, description=?, image=?, price=?, currency=?, updated_at=?, version=version+1 WHERE id = ? AND deleted_at IS NULL"
+	args := []any{
 		rec.Title,
 		rec.Description,
-		rec.Rating,
 		rec.Image,
+		rec.Price,
+		rec.Currency,
 		rec.UpdatedAt,
 		rec.ID,
-	); err != nil {
+	}
+	if rec.Version > 0 {
+		query += " AND version = ?"
+		args = append(args, rec.Version)
+	}
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+
+	if err := versioned(res, rec.Version); err != nil {
 		return err
 	}
+	if rec.Version > 0 {
+		rec.Version++
+	}
 
 	return nil
 }
 
-func (s *Cake) Delete(ctx context.Context, id int) error {
+// Delete soft delete a cake, the cake is moved into a trash until it's restored or purged,
+// when version is set the delete is only applied on the same version
+func (s *Cake) Delete(ctx context.Context, id int, version int) error {
 	if id <= 0 {
 		return ErrRecordNotFound
 	}
 
-	query := "DELETE FROM cakes WHERE id = ?"
+	query := "UPDATE cakes SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL"
+	args := []any{time.Now(), id}
+	if version > 0 {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
 	log.Print(query)
 
-	if _, err := s.DB.ExecContext(ctx, query, id); err != nil {
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), args...)
+	if err != nil {
 		return err
 	}
 
+	return versioned(res, version)
+}
+
+// Restore move a trashed cake back into an active cakes
+func (s *Cake) Restore(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "UPDATE cakes SET deleted_at = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), time.Now(), id)
+	if err != nil {
+		return eris.Wrap(err, "restore cake, an error occurred")
+	}
+
+	return affected(res)
+}
+
+// Purge permanently delete a trashed cake
+func (s *Cake) Purge(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM cakes WHERE id = ? AND deleted_at IS NOT NULL"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), id)
+	if err != nil {
+		return eris.Wrap(err, "purge cake, an error occurred")
+	}
+
+	return affected(res)
+}
+
+// PurgeTrashed permanently delete every cakes which trashed before the time
+func (s *Cake) PurgeTrashed(ctx context.Context, before time.Time) (int64, error) {
+	query := "DELETE FROM cakes WHERE deleted_at IS NOT NULL AND deleted_at < ?"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), before)
+	if err != nil {
+		return 0, eris.Wrap(err, "purge trashed cakes, an error occurred")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, eris.Wrap(err, "purge trashed cakes, an error occurred")
+	}
+
+	return n, nil
+}
+
+// Truncate permanently delete every cakes including a trashed one, their relations are deleted by a cascade
+func (s *Cake) Truncate(ctx context.Context) error {
+	query := "DELETE FROM cakes"
+	log.Print(query)
+
+	if _, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query)); err != nil {
+		return eris.Wrap(err, "truncate cakes, an error occurred")
+	}
+
 	return nil
 }
 
+// affected return ErrRecordNotFound when the statement does not affect any row
+func affected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return eris.Wrap(err, "rows affected, an error occurred")
+	}
+	if n == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// versioned return ErrVersionConflict when a versioned statement does not affect any row,
+// and ErrRecordNotFound for an unversioned one
+func versioned(res sql.Result, version int) error {
+	err := affected(res)
+	if version > 0 && eris.Is(err, ErrRecordNotFound) {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// cakeFields return a scan destinations of cakes columns in a table order
+func cakeFields(c *schema.Cake) []any {
+	return []any{
+		&c.ID,
+		&c.Title,
+		&c.Description,
+		&c.Rating,
+		&c.Image,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.DeletedAt,
+		&c.Version,
+		&c.Price,
+		&c.Currency,
+	}
+}
+
 func (s *Cake) retrieveRow(rows *sql.Rows, res *schema.Cake) error {
 	if rows == nil {
 		return ErrSQLRowsNill
@@ -168,15 +589,7 @@ func (s *Cake) retrieveRow(rows *sql.Rows, res *schema.Cake) error {
 	}
 
 	for rows.Next() {
-		if err := rows.Scan(
-			&res.ID,
-			&res.Title,
-			&res.Description,
-			&res.Rating,
-			&res.Image,
-			&res.CreatedAt,
-			&res.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(cakeFields(res)...); err != nil {
 			return err
 		}
 	}
@@ -184,7 +597,8 @@ func (s *Cake) retrieveRow(rows *sql.Rows, res *schema.Cake) error {
 	return rows.Err()
 }
 
-func (s *Cake) retrieveRows(rows *sql.Rows, res *[]schema.Cake) error {
+// retrieveRows scan a cakes rows, withScore is used when the rows has an additional score column
+func (s *Cake) retrieveRows(rows *sql.Rows, res *[]schema.Cake, withScore bool) error {
 	if rows == nil {
 		return ErrSQLRowsNill
 	}
@@ -193,16 +607,15 @@ func (s *Cake) retrieveRows(rows *sql.Rows, res *[]schema.Cake) error {
 	}
 
 	for rows.Next() {
-		var o schema.Cake
-		if err := rows.Scan(
-			&o.ID,
-			&o.Title,
-			&o.Description,
-			&o.Rating,
-			&o.Image,
-			&o.CreatedAt,
-			&o.UpdatedAt,
-		); err != nil {
+		var (
+			o    schema.Cake
+			dest = cakeFields(&o)
+		)
+		if withScore {
+			dest = append(dest, &o.Score)
+		}
+
+		if err := rows.Scan(dest...); err != nil {
 			util.ResetSlice(res)
 			return err
 		}
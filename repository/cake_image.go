@@ -0,0 +1,401 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var (
+	ErrImageOrderMismatch = eris.New("image order does not match the cake images")
+)
+
+type CakeImage struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func (s *CakeImage) FindAll(ctx context.Context, cakeID int) ([]schema.CakeImage, error) {
+	query := "SELECT * FROM cake_images WHERE cake_id = ? ORDER BY position ASC, id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake images, an error occurred")
+	}
+
+	res := []schema.CakeImage{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake images, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+// FindByCakeIDs list an images of each cakes, keyed by cake id
+func (s *CakeImage) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.CakeImage, error) {
+	res := map[int][]schema.CakeImage{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := "SELECT * FROM cake_images WHERE cake_id IN (" + placeholders(len(ids)) + ") ORDER BY position ASC, id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), intArgs(ids)...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake images by cake ids, an error occurred")
+	}
+
+	images := []schema.CakeImage{}
+	if err := s.retrieveRows(rows, &images); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake images by cake ids, an error occurred")
+	}
+
+	for _, img := range images {
+		res[img.CakeID] = append(res[img.CakeID], img)
+	}
+
+	return res, nil
+}
+
+// Insert append an image into a cake gallery, the first image of a cake always become the primary image.
+// ID, Position and Primary is filled on success
+func (s *CakeImage) Insert(ctx context.Context, rec *schema.CakeImage) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	thumbnails, err := json.Marshal(rec.Thumbnails)
+	if err != nil {
+		return eris.Wrap(err, "insert cake image, an error occurred")
+	}
+
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "insert cake image, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := lockCake(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+		return err
+	}
+
+	query := "SELECT COALESCE(MAX(position) + 1, 0), COALESCE(SUM(is_primary), 0) FROM cake_images WHERE cake_id = ?"
+	log.Print(query)
+
+	var (
+		position int
+		primary  int
+	)
+	if err := tx.QueryRowContext(ctx, s.Dialect.rebind(query), rec.CakeID).Scan(&position, &primary); err != nil {
+		return eris.Wrap(err, "insert cake image, an error occurred")
+	}
+
+	isPrimary := rec.Primary || primary == 0
+	if isPrimary {
+		if err := unsetPrimary(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+			return err
+		}
+	}
+
+	query = "INSERT INTO cake_images (cake_id, storage_key, url, content_type, size, width, height, thumbnails, alt, position, is_primary, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, tx, query,
+		rec.CakeID,
+		rec.Key,
+		rec.URL,
+		rec.ContentType,
+		rec.Size,
+		rec.Width,
+		rec.Height,
+		thumbnails,
+		rec.Alt,
+		position,
+		s.Dialect.flag(isPrimary),
+		rec.CreatedAt,
+	)
+	if err != nil {
+		return eris.Wrap(err, "insert cake image, an error occurred")
+	}
+
+	if err := syncCakeImage(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "insert cake image, an error occurred")
+	}
+	rec.ID, rec.Position, rec.Primary = int(id), position, isPrimary
+
+	return nil
+}
+
+// Update update an alt text of an image, when rec.Primary is set the image become the primary image.
+// a primary image can not be unset directly, make another image primary instead
+func (s *CakeImage) Update(ctx context.Context, rec *schema.CakeImage) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "update cake image, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := lockCake(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+		return err
+	}
+
+	if rec.Primary {
+		if err := unsetPrimary(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+			return err
+		}
+	}
+
+	query := "UPDATE cake_images SET alt = ?, is_primary = is_primary OR ? WHERE id = ? AND cake_id = ?"
+	if s.Dialect == DialectPostgres {
+		// a boolean operator does not accept a SMALLINT on Postgres
+		query = "UPDATE cake_images SET alt = ?, is_primary = is_primary | ? WHERE id = ? AND cake_id = ?"
+	}
+	log.Print(query)
+
+	res, err := tx.ExecContext(ctx, s.Dialect.rebind(query), rec.Alt, s.Dialect.flag(rec.Primary), rec.ID, rec.CakeID)
+	if err != nil {
+		return eris.Wrap(err, "update cake image, an error occurred")
+	}
+
+	if err := affected(res); err != nil {
+		return err
+	}
+
+	if err := syncCakeImage(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "update cake image, an error occurred")
+	}
+
+	return nil
+}
+
+// Reorder set a position of each image by it's index on ids, ids must contain every image of the cake
+func (s *CakeImage) Reorder(ctx context.Context, cakeID int, ids []int) error {
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "reorder cake images, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := lockCake(ctx, tx, s.Dialect, cakeID); err != nil {
+		return err
+	}
+
+	query := "SELECT id FROM cake_images WHERE cake_id = ?"
+	log.Print(query)
+
+	rows, err := tx.QueryContext(ctx, s.Dialect.rebind(query), cakeID)
+	if err != nil {
+		return eris.Wrap(err, "reorder cake images, an error occurred")
+	}
+
+	current := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return eris.Wrap(err, "reorder cake images, an error occurred")
+		}
+		current[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return eris.Wrap(err, "reorder cake images, an error occurred")
+	}
+
+	if len(ids) != len(current) {
+		return ErrImageOrderMismatch
+	}
+	for _, id := range ids {
+		if !current[id] {
+			return ErrImageOrderMismatch
+		}
+		delete(current, id)
+	}
+
+	query = "UPDATE cake_images SET position = ? WHERE id = ?"
+	log.Print(query)
+
+	for position, id := range ids {
+		if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), position, id); err != nil {
+			return eris.Wrap(err, "reorder cake images, an error occurred")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "reorder cake images, an error occurred")
+	}
+
+	return nil
+}
+
+// Delete remove an image from a cake gallery and return it, so it's files can be removed.
+// when the primary image is removed the first remaining image become the primary image
+func (s *CakeImage) Delete(ctx context.Context, cakeID, id int) (*schema.CakeImage, error) {
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return nil, eris.Wrap(err, "delete cake image, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := lockCake(ctx, tx, s.Dialect, cakeID); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT * FROM cake_images WHERE id = ? AND cake_id = ?"
+	log.Print(query)
+
+	rows, err := tx.QueryContext(ctx, s.Dialect.rebind(query), id, cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "delete cake image, an error occurred")
+	}
+
+	res := []schema.CakeImage{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "delete cake image, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query = "DELETE FROM cake_images WHERE id = ?"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), id); err != nil {
+		return nil, eris.Wrap(err, "delete cake image, an error occurred")
+	}
+
+	if res[0].Primary {
+		query = "UPDATE cake_images SET is_primary = 1 WHERE cake_id = ? ORDER BY position ASC, id ASC LIMIT 1"
+		if !s.Dialect.isMySQL() {
+			query = "UPDATE cake_images SET is_primary = 1 WHERE id = (SELECT id FROM cake_images WHERE cake_id = ? ORDER BY position ASC, id ASC LIMIT 1)"
+		}
+		log.Print(query)
+
+		if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), cakeID); err != nil {
+			return nil, eris.Wrap(err, "delete cake image, an error occurred")
+		}
+	}
+
+	if err := syncCakeImage(ctx, tx, s.Dialect, cakeID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, eris.Wrap(err, "delete cake image, an error occurred")
+	}
+
+	return &res[0], nil
+}
+
+func unsetPrimary(ctx context.Context, tx execer, d Dialect, cakeID int) error {
+	query := "UPDATE cake_images SET is_primary = 0 WHERE cake_id = ? AND is_primary = 1"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, d.rebind(query), cakeID); err != nil {
+		return eris.Wrap(err, "unset primary cake image, an error occurred")
+	}
+
+	return nil
+}
+
+// syncCakeImage copy an url of the primary image into cakes.image, so a client which only read
+// the image field keep working. the cake version is increased only when the image is changed
+func syncCakeImage(ctx context.Context, tx execer, d Dialect, cakeID int) error {
+	query := "UPDATE cakes c " +
+		"LEFT JOIN cake_images i ON i.cake_id = c.id AND i.is_primary = 1 " +
+		"SET c.image = COALESCE(i.url, ''), c.version = c.version + 1 " +
+		"WHERE c.id = ? AND c.image <> COALESCE(i.url, '')"
+	args := []any{cakeID}
+	if !d.isMySQL() {
+		// an aggregate always return one row, so a cake without primary image get an empty url
+		query = "UPDATE cakes SET image = p.url, version = version + 1 " +
+			"FROM (SELECT COALESCE(MAX(url), '') AS url FROM cake_images WHERE cake_id = ? AND is_primary = 1) p " +
+			"WHERE cakes.id = ? AND cakes.image <> p.url"
+		args = append(args, cakeID)
+	}
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, d.rebind(query), args...); err != nil {
+		return eris.Wrap(err, "sync cake image, an error occurred")
+	}
+
+	return nil
+}
+
+func (s *CakeImage) retrieveRows(rows *sql.Rows, res *[]schema.CakeImage) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var (
+			o          schema.CakeImage
+			thumbnails []byte
+		)
+		if err := rows.Scan(
+			&o.ID,
+			&o.CakeID,
+			&o.Key,
+			&o.URL,
+			&o.ContentType,
+			&o.Size,
+			&o.Width,
+			&o.Height,
+			&thumbnails,
+			&o.Alt,
+			&o.Position,
+			&o.Primary,
+			&o.CreatedAt,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		if len(thumbnails) > 0 {
+			if err := json.Unmarshal(thumbnails, &o.Thumbnails); err != nil {
+				util.ResetSlice(res)
+				return err
+			}
+		}
+		if o.Thumbnails == nil {
+			o.Thumbnails = []schema.Thumbnail{}
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakeImageMock struct {
+	mock.Mock
+}
+
+func (m *CakeImageMock) FindAll(ctx context.Context, cakeID int) ([]schema.CakeImage, error) {
+	args := m.Called(ctx, cakeID)
+	res, _ := args.Get(0).([]schema.CakeImage)
+	return res, args.Error(1)
+}
+
+func (m *CakeImageMock) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.CakeImage, error) {
+	args := m.Called(ctx, ids)
+	res, _ := args.Get(0).(map[int][]schema.CakeImage)
+	return res, args.Error(1)
+}
+
+func (m *CakeImageMock) Insert(ctx context.Context, rec *schema.CakeImage) error {
+	rec.CreatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *CakeImageMock) Update(ctx context.Context, rec *schema.CakeImage) error {
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *CakeImageMock) Reorder(ctx context.Context, cakeID int, ids []int) error {
+	args := m.Called(ctx, cakeID, ids)
+	return args.Error(0)
+}
+
+func (m *CakeImageMock) Delete(ctx context.Context, cakeID, id int) (*schema.CakeImage, error) {
+	args := m.Called(ctx, cakeID, id)
+	res, _ := args.Get(0).(*schema.CakeImage)
+	return res, args.Error(1)
+}
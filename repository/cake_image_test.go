@@ -0,0 +1,279 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var cakeImageColumns = []string{
+	"id",
+	"cake_id",
+	"storage_key",
+	"url",
+	"content_type",
+	"size",
+	"width",
+	"height",
+	"thumbnails",
+	"alt",
+	"position",
+	"is_primary",
+	"created_at",
+}
+
+var cakeImage = schema.CakeImage{
+	ID:     1,
+	CakeID: 1,
+	Image: schema.Image{
+		Key:         "cakes/1/a.png",
+		URL:         "/files/cakes/1/a.png",
+		ContentType: "image/png",
+		Size:        2048,
+		Width:       800,
+		Height:      400,
+		Thumbnails: []schema.Thumbnail{
+			{Key: "cakes/1/a_150.png", URL: "/files/cakes/1/a_150.png", Width: 150, Height: 75},
+		},
+	},
+	Alt:       "Lemon cheesecake",
+	Position:  0,
+	Primary:   true,
+	CreatedAt: time.Now(),
+}
+
+const (
+	unsetPrimaryQuery  = "UPDATE cake_images SET is_primary = 0 WHERE cake_id = ? AND is_primary = 1"
+	syncCakeImageQuery = "UPDATE cakes c LEFT JOIN cake_images i ON i.cake_id = c.id AND i.is_primary = 1 SET c.image = COALESCE(i.url, ''), c.version = c.version + 1 WHERE c.id = ? AND c.image <> COALESCE(i.url, '')"
+)
+
+func cakeImageRow(rows *sqlmock.Rows, rec schema.CakeImage, thumbnails string) *sqlmock.Rows {
+	return rows.AddRow(
+		rec.ID,
+		rec.CakeID,
+		rec.Key,
+		rec.URL,
+		rec.ContentType,
+		rec.Size,
+		rec.Width,
+		rec.Height,
+		thumbnails,
+		rec.Alt,
+		rec.Position,
+		rec.Primary,
+		rec.CreatedAt,
+	)
+}
+
+func Test_Cake_Image_Repository_Find_All(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		second := cakeImage
+		second.ID, second.Position, second.Primary, second.Thumbnails = 2, 1, false, []schema.Thumbnail{}
+
+		rows := sqlmock.NewRows(cakeImageColumns)
+		cakeImageRow(rows, cakeImage, `[{"key":"cakes/1/a_150.png","url":"/files/cakes/1/a_150.png","width":150,"height":75}]`)
+		cakeImageRow(rows, second, "")
+
+		mock.ExpectQuery("SELECT * FROM cake_images WHERE cake_id = ? ORDER BY position ASC, id ASC").
+			WithArgs(cakeImage.CakeID).
+			WillReturnRows(rows)
+
+		repo := &repository.CakeImage{DB: db}
+		res, err := repo.FindAll(context.Background(), cakeImage.CakeID)
+		assert.NoError(t, err)
+		assert.Equal(t, []schema.CakeImage{cakeImage, second}, res)
+	})
+
+	t.Run("Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT * FROM cake_images WHERE cake_id = ? ORDER BY position ASC, id ASC").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows(cakeImageColumns))
+
+		repo := &repository.CakeImage{DB: db}
+		_, err := repo.FindAll(context.Background(), 9)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+	})
+}
+
+func Test_Cake_Image_Repository_Insert(t *testing.T) {
+	insertQuery := "INSERT INTO cake_images (cake_id, storage_key, url, content_type, size, width, height, thumbnails, alt, position, is_primary, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	thumbnails := `[{"key":"cakes/1/a_150.png","url":"/files/cakes/1/a_150.png","width":150,"height":75}]`
+
+	t.Run("First_Image_Is_Primary", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(cakeImage.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cakeImage.CakeID))
+		mock.ExpectQuery("SELECT COALESCE(MAX(position) + 1, 0), COALESCE(SUM(is_primary), 0) FROM cake_images WHERE cake_id = ?").
+			WithArgs(cakeImage.CakeID).
+			WillReturnRows(sqlmock.NewRows([]string{"position", "primary"}).AddRow(0, 0))
+		mock.ExpectExec(unsetPrimaryQuery).WithArgs(cakeImage.CakeID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(insertQuery).
+			WithArgs(cakeImage.CakeID, cakeImage.Key, cakeImage.URL, cakeImage.ContentType, cakeImage.Size, cakeImage.Width, cakeImage.Height, []byte(thumbnails), cakeImage.Alt, 0, true, cakeImage.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectExec(syncCakeImageQuery).WithArgs(cakeImage.CakeID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := &repository.CakeImage{DB: db}
+		rec := cakeImage
+		rec.ID, rec.Primary = 0, false
+		assert.NoError(t, repo.Insert(context.Background(), &rec))
+		assert.Equal(t, 4, rec.ID)
+		assert.True(t, rec.Primary)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Appended", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(cakeImage.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cakeImage.CakeID))
+		mock.ExpectQuery("SELECT COALESCE(MAX(position) + 1, 0), COALESCE(SUM(is_primary), 0) FROM cake_images WHERE cake_id = ?").
+			WithArgs(cakeImage.CakeID).
+			WillReturnRows(sqlmock.NewRows([]string{"position", "primary"}).AddRow(3, 1))
+		mock.ExpectExec(insertQuery).
+			WithArgs(cakeImage.CakeID, cakeImage.Key, cakeImage.URL, cakeImage.ContentType, cakeImage.Size, cakeImage.Width, cakeImage.Height, []byte(thumbnails), cakeImage.Alt, 3, false, cakeImage.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectExec(syncCakeImageQuery).WithArgs(cakeImage.CakeID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := &repository.CakeImage{DB: db}
+		rec := cakeImage
+		rec.ID, rec.Primary = 0, false
+		assert.NoError(t, repo.Insert(context.Background(), &rec))
+		assert.Equal(t, 3, rec.Position)
+		assert.False(t, rec.Primary)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Cake_Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		repo := &repository.CakeImage{DB: db}
+		rec := cakeImage
+		rec.CakeID = 9
+		assert.ErrorIs(t, repo.Insert(context.Background(), &rec), repository.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Cake_Image_Repository_Update(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCakeQuery).WithArgs(cakeImage.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cakeImage.CakeID))
+	mock.ExpectExec(unsetPrimaryQuery).WithArgs(cakeImage.CakeID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE cake_images SET alt = ?, is_primary = is_primary OR ? WHERE id = ? AND cake_id = ?").
+		WithArgs("Top view", true, 2, cakeImage.CakeID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(syncCakeImageQuery).WithArgs(cakeImage.CakeID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := &repository.CakeImage{DB: db}
+	rec := cakeImage
+	rec.ID, rec.Alt = 2, "Top view"
+	assert.NoError(t, repo.Update(context.Background(), &rec))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Image_Repository_Reorder(t *testing.T) {
+	prepare := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(cakeImage.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cakeImage.CakeID))
+		mock.ExpectQuery("SELECT id FROM cake_images WHERE cake_id = ?").
+			WithArgs(cakeImage.CakeID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	}
+
+	t.Run("Reordered", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		prepare(mock)
+		mock.ExpectExec("UPDATE cake_images SET position = ? WHERE id = ?").WithArgs(0, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE cake_images SET position = ? WHERE id = ?").WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := &repository.CakeImage{DB: db}
+		assert.NoError(t, repo.Reorder(context.Background(), cakeImage.CakeID, []int{2, 1}))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	for name, ids := range map[string][]int{
+		"Missing_Image": {2},
+		"Unknown_Image": {2, 3},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, mock := NewMock()
+			defer db.Close()
+
+			prepare(mock)
+			mock.ExpectRollback()
+
+			repo := &repository.CakeImage{DB: db}
+			assert.ErrorIs(t, repo.Reorder(context.Background(), cakeImage.CakeID, ids), repository.ErrImageOrderMismatch)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_Cake_Image_Repository_Delete(t *testing.T) {
+	t.Run("Primary_Is_Promoted", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(cakeImage.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cakeImage.CakeID))
+		mock.ExpectQuery("SELECT * FROM cake_images WHERE id = ? AND cake_id = ?").
+			WithArgs(cakeImage.ID, cakeImage.CakeID).
+			WillReturnRows(cakeImageRow(sqlmock.NewRows(cakeImageColumns), cakeImage, `[{"key":"cakes/1/a_150.png","url":"/files/cakes/1/a_150.png","width":150,"height":75}]`))
+		mock.ExpectExec("DELETE FROM cake_images WHERE id = ?").WithArgs(cakeImage.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE cake_images SET is_primary = 1 WHERE cake_id = ? ORDER BY position ASC, id ASC LIMIT 1").
+			WithArgs(cakeImage.CakeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(syncCakeImageQuery).WithArgs(cakeImage.CakeID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := &repository.CakeImage{DB: db}
+		res, err := repo.Delete(context.Background(), cakeImage.CakeID, cakeImage.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, cakeImage, *res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(cakeImage.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cakeImage.CakeID))
+		mock.ExpectQuery("SELECT * FROM cake_images WHERE id = ? AND cake_id = ?").
+			WithArgs(9, cakeImage.CakeID).
+			WillReturnRows(sqlmock.NewRows(cakeImageColumns))
+		mock.ExpectRollback()
+
+		repo := &repository.CakeImage{DB: db}
+		_, err := repo.Delete(context.Background(), cakeImage.CakeID, 9)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
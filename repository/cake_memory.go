@@ -0,0 +1,492 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/zufzuf/cake-store/schema"
+)
+
+// CakeMemory is an in-memory cakes storage which is safe for a concurrent use,
+// it's used to run the api without a database. A zero value is an empty storage.
+//
+// It follows the same semantics as Cake, a text is compared case-insensitively like the default
+// MySQL collation and a full-text search is approximated by counting a matched words.
+// There is no relation table in memory, so a category, tag, in stock or allergen exclusion filter
+// is rejected with UnsupportedFilterError instead of returning a wrong result
+type CakeMemory struct {
+	mu     sync.RWMutex
+	cakes  map[int]*schema.Cake
+	lastID int
+}
+
+func (s *CakeMemory) Find(ctx context.Context, id int) (*schema.Cake, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.cakes[id]
+	if !ok || c.DeletedAt != nil {
+		return nil, ErrRecordNotFound
+	}
+
+	return copyCake(c), nil
+}
+
+func (s *CakeMemory) FindAll(ctx context.Context, fil *FindAllFilter) ([]schema.Cake, error) {
+	if fil == nil {
+		return nil, ErrFilterNill
+	}
+	if err := unsupportedFilter(fil); err != nil {
+		return nil, err
+	}
+
+	fields := fil.Sort
+	if len(fields) == 0 {
+		fields = CakeDefaultSort
+		if fil.IsValidSearch() {
+			fields = CakeSearchDefaultSort
+		}
+	}
+
+	// relevance is resolved into a score instead of a match expression
+	match := ""
+	if fil.IsValidSearch() {
+		match = "score"
+	}
+
+	cols, err := cakeSort(fields, match)
+	if err != nil {
+		return nil, err
+	}
+	sortKey := joinSort(fields)
+
+	var vals []any
+	if len(fil.Cursor) > 0 {
+		if vals, err = decodeCursor(sortKey, cols, fil.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	res := make([]schema.Cake, 0, len(s.cakes))
+	for _, c := range s.cakes {
+		o := *copyCake(c)
+		if !matchFilter(fil, &o) {
+			continue
+		}
+		res = append(res, o)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return compareCakes(cols, &res[i], &res[j]) < 0
+	})
+
+	if vals != nil {
+		n := sort.Search(len(res), func(i int) bool {
+			return afterCursor(cols, &res[i], vals)
+		})
+		res = res[n:]
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	limit := NormalizeLimit(fil.Limit)
+
+	fil.NextCursor, fil.HasMore = "", len(res) > limit
+	if fil.HasMore {
+		res = res[:limit]
+		if fil.NextCursor, err = encodeCursor(sortKey, cols, &res[limit-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// Stream call fn on each cakes of a filter in it's order, the cakes are read page by page
+func (s *CakeMemory) Stream(ctx context.Context, fil *FindAllFilter, fn func(rec *schema.Cake) error) error {
+	if fil == nil {
+		return ErrFilterNill
+	}
+
+	page := *fil
+	page.Limit = MaxLimit
+	for {
+		res, err := s.FindAll(ctx, &page)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		for i := range res {
+			if err := fn(&res[i]); err != nil {
+				return err
+			}
+		}
+
+		if !page.HasMore {
+			return nil
+		}
+		page.Cursor = page.NextCursor
+	}
+}
+
+func (s *CakeMemory) Insert(ctx context.Context, rec *schema.Cake) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cakes == nil {
+		s.cakes = map[int]*schema.Cake{}
+	}
+
+	s.lastID++
+	rec.ID = s.lastID
+	rec.Version = 1
+
+	c := schema.Cake{
+		ID:          rec.ID,
+		Title:       rec.Title,
+		Description: rec.Description,
+		Rating:      rec.Rating,
+		Image:       rec.Image,
+		Price:       rec.Price,
+		Currency:    rec.Currency,
+		CreatedAt:   rec.CreatedAt,
+		UpdatedAt:   rec.UpdatedAt,
+		Version:     rec.Version,
+	}
+	s.cakes[c.ID] = &c
+
+	return nil
+}
+
+// Update update a cake and increase it's version, when rec.Version is set the update
+// is only applied on the same version, otherwise ErrVersionConflict is returned
+func (s *CakeMemory) Update(ctx context.Context, rec *schema.Cake) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.active(rec.ID, rec.Version)
+	if err != nil {
+		return err
+	}
+
+	c.Title = rec.Title
+	c.Description = rec.Description
+	c.Image = rec.Image
+	c.Price = rec.Price
+	c.Currency = rec.Currency
+	c.UpdatedAt = rec.UpdatedAt
+	c.Version++
+
+	if rec.Version > 0 {
+		rec.Version++
+	}
+
+	return nil
+}
+
+// Delete soft delete a cake, when version is set the delete is only applied on the same version
+func (s *CakeMemory) Delete(ctx context.Context, id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.active(id, version)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	c.DeletedAt = &now
+	c.Version++
+
+	return nil
+}
+
+// Restore move a trashed cake back into an active cakes
+func (s *CakeMemory) Restore(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.cakes[id]
+	if !ok || c.DeletedAt == nil {
+		return ErrRecordNotFound
+	}
+
+	c.DeletedAt = nil
+	c.UpdatedAt = time.Now()
+	c.Version++
+
+	return nil
+}
+
+// Purge permanently delete a trashed cake
+func (s *CakeMemory) Purge(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.cakes[id]
+	if !ok || c.DeletedAt == nil {
+		return ErrRecordNotFound
+	}
+
+	delete(s.cakes, id)
+
+	return nil
+}
+
+// PurgeTrashed permanently delete every cakes which trashed before the time
+func (s *CakeMemory) PurgeTrashed(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, c := range s.cakes {
+		if c.DeletedAt != nil && c.DeletedAt.Before(before) {
+			delete(s.cakes, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Truncate delete every cakes including a trashed one
+func (s *CakeMemory) Truncate(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cakes = nil
+	return nil
+}
+
+// active find an active cake to be changed, the caller must hold the write lock.
+// a missing cake is ErrVersionConflict for a versioned change, like versioned
+func (s *CakeMemory) active(id int, version int) (*schema.Cake, error) {
+	c, ok := s.cakes[id]
+	if !ok || c.DeletedAt != nil || (version > 0 && c.Version != version) {
+		if version > 0 {
+			return nil, ErrVersionConflict
+		}
+		return nil, ErrRecordNotFound
+	}
+
+	return c, nil
+}
+
+func copyCake(c *schema.Cake) *schema.Cake {
+	res := *c
+	if c.DeletedAt != nil {
+		deletedAt := *c.DeletedAt
+		res.DeletedAt = &deletedAt
+	}
+	return &res
+}
+
+// unsupportedFilter reject a filter which need a relation table
+func unsupportedFilter(fil *FindAllFilter) error {
+	switch {
+	case fil.IsValidCategory():
+		return &UnsupportedFilterError{Key: "category"}
+	case fil.IsValidTag():
+		return &UnsupportedFilterError{Key: "tag"}
+	case fil.IsValidInStock():
+		return &UnsupportedFilterError{Key: "in_stock"}
+	case fil.IsValidExcludeAllergens():
+		return &UnsupportedFilterError{Key: "exclude_allergens"}
+	}
+	return nil
+}
+
+// matchFilter report whether a cake match the filter, the search score is set on a full-text search
+func matchFilter(fil *FindAllFilter, c *schema.Cake) bool {
+	if fil.Trashed != (c.DeletedAt != nil) {
+		return false
+	}
+
+	if fil.IsValidSearch() {
+		score := searchScore(fil.Search, fil.SearchMode, c.Title+" "+c.Description)
+		if score <= 0 {
+			return false
+		}
+		c.Score = &score
+	}
+
+	if fil.IsValidTitle() && !containsFold(c.Title, fil.Title) {
+		return false
+	}
+
+	if fil.IsValidDescription() && !containsFold(c.Description, fil.Description) {
+		return false
+	}
+
+	if fil.IsValidRatingMin() && c.Rating < *fil.RatingMin {
+		return false
+	}
+
+	if fil.IsValidRatingMax() && c.Rating > *fil.RatingMax {
+		return false
+	}
+
+	if fil.IsValidCurrency() && !strings.EqualFold(c.Currency, fil.Currency) {
+		return false
+	}
+
+	if fil.IsValidPriceMin() && c.Price < *fil.PriceMin {
+		return false
+	}
+
+	if fil.IsValidPriceMax() && c.Price > *fil.PriceMax {
+		return false
+	}
+
+	if fil.IsValidCreatedAfter() && !c.CreatedAt.After(fil.CreatedAfter) {
+		return false
+	}
+
+	if fil.IsValidCreatedBefore() && !c.CreatedAt.Before(fil.CreatedBefore) {
+		return false
+	}
+
+	if fil.IsValidUpdatedSince() && c.UpdatedAt.Before(fil.UpdatedSince) {
+		return false
+	}
+
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchScore approximate a full-text relevance of a text, zero mean the text does not match.
+// a natural search score every occurrence of the words, see searchTerms for a boolean search
+func searchScore(search, mode string, text string) float64 {
+	var (
+		score    float64
+		required = true
+		optional = false
+		count    = map[string]int{}
+		doc      = words(text)
+	)
+	for _, w := range doc {
+		count[w]++
+	}
+
+	for _, t := range searchTerms(search, mode) {
+		n := count[t.word]
+		if t.prefix {
+			n = 0
+			for _, w := range doc {
+				if strings.HasPrefix(w, t.word) {
+					n++
+				}
+			}
+		}
+
+		switch {
+		case t.op == '-' && n > 0:
+			return 0
+		case t.op == '+' && n == 0:
+			required = false
+		case t.op != '-':
+			optional = optional || n > 0
+			score += float64(n)
+		}
+	}
+
+	if !required || !optional {
+		return 0
+	}
+
+	return score
+}
+
+// compareCakes compare two cakes by the sort columns, it return a negative number when a is before b
+func compareCakes(cols []sortColumn, a, b *schema.Cake) int {
+	for _, col := range cols {
+		cmp := compareValues(col.value(a), col.value(b))
+		if col.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// afterCursor report whether a cake is positioned after the cursor values, like keysetQuery
+func afterCursor(cols []sortColumn, c *schema.Cake, vals []any) bool {
+	for i, col := range cols {
+		cmp := compareValues(col.value(c), vals[i])
+		if col.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp > 0
+		}
+	}
+	return false
+}
+
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b.(string)))
+	case int:
+		return compareOrdered(a, b.(int))
+	case int64:
+		return compareOrdered(a, b.(int64))
+	case float64:
+		return compareOrdered(a, toFloat(b))
+	case time.Time:
+		return compareOrdered(a.UnixNano(), b.(time.Time).UnixNano())
+	}
+	return 0
+}
+
+// toFloat convert a relevance score, an unscored cake has an integer zero value
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+func compareOrdered[T int | int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,292 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+func newMemoryCakes(t *testing.T) *repository.CakeMemory {
+	repo := &repository.CakeMemory{}
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, c := range []schema.Cake{
+		{Title: "Lemon cheesecake", Description: "A cheesecake made of lemon", Rating: 8, Price: 150000, Currency: "IDR"},
+		{Title: "chocolate fudge", Description: "A rich chocolate cake", Rating: 9, Price: 120000, Currency: "IDR"},
+		{Title: "Lemon tart", Description: "A tart with lemon curd and lemon zest", Rating: 7, Price: 5, Currency: "USD"},
+		{Title: "Carrot cake", Description: "A spiced cake", Rating: 9, Price: 90000, Currency: "IDR"},
+	} {
+		c.CreatedAt = base.AddDate(0, i, 0)
+		c.UpdatedAt = c.CreatedAt
+		assert.NoError(t, repo.Insert(context.Background(), &c))
+	}
+
+	return repo
+}
+
+func titles(cakes []schema.Cake) []string {
+	res := make([]string, len(cakes))
+	for i, c := range cakes {
+		res[i] = c.Title
+	}
+	return res
+}
+
+func Test_Cake_Memory_Repository_Find(t *testing.T) {
+	repo := newMemoryCakes(t)
+
+	res, err := repo.Find(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lemon cheesecake", res.Title)
+	assert.Equal(t, 1, res.Version)
+
+	// a returned cake is a copy
+	res.Title = "Changed"
+	res, _ = repo.Find(context.Background(), 1)
+	assert.Equal(t, "Lemon cheesecake", res.Title)
+
+	_, err = repo.Find(context.Background(), 9)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	_, err = (&repository.CakeMemory{}).Find(context.Background(), 1)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Cake_Memory_Repository_Find_All(t *testing.T) {
+	ratingMin, priceMax := 8.0, int64(130000)
+
+	tests := []struct {
+		Name   string
+		Filter repository.FindAllFilter
+		Result []string
+		Error  error
+	}{
+		{
+			Name:   "Default_Sort",
+			Result: []string{"Carrot cake", "chocolate fudge", "Lemon cheesecake", "Lemon tart"},
+		},
+		{
+			Name:   "Title_Filter",
+			Filter: repository.FindAllFilter{Title: "LEMON"},
+			Result: []string{"Lemon cheesecake", "Lemon tart"},
+		},
+		{
+			Name:   "Range_Filter",
+			Filter: repository.FindAllFilter{RatingMin: &ratingMin, Currency: "IDR", PriceMax: &priceMax},
+			Result: []string{"Carrot cake", "chocolate fudge"},
+		},
+		{
+			Name:   "Created_After",
+			Filter: repository.FindAllFilter{CreatedAfter: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)},
+			Result: []string{"Carrot cake", "Lemon tart"},
+		},
+		{
+			Name: "Sort",
+			Filter: repository.FindAllFilter{
+				Sort: []repository.SortField{{Key: "rating", Desc: true}, {Key: "price"}},
+			},
+			Result: []string{"Carrot cake", "chocolate fudge", "Lemon cheesecake", "Lemon tart"},
+		},
+		{
+			Name:   "Search",
+			Filter: repository.FindAllFilter{Search: "lemon"},
+			Result: []string{"Lemon tart", "Lemon cheesecake"},
+		},
+		{
+			Name:   "Search_Boolean",
+			Filter: repository.FindAllFilter{Search: "+lemon -tart", SearchMode: repository.SearchModeBoolean},
+			Result: []string{"Lemon cheesecake"},
+		},
+		{
+			Name:   "Search_Boolean_Prefix",
+			Filter: repository.FindAllFilter{Search: "choc*", SearchMode: repository.SearchModeBoolean},
+			Result: []string{"chocolate fudge"},
+		},
+		{
+			Name:   "Relevance_Without_Search",
+			Filter: repository.FindAllFilter{Sort: []repository.SortField{{Key: "relevance"}}},
+			Error:  repository.ErrInvalidSort,
+		},
+		{
+			Name:   "Invalid_Cursor",
+			Filter: repository.FindAllFilter{Cursor: "invalid"},
+			Error:  repository.ErrInvalidCursor,
+		},
+		{
+			Name:   "Trashed",
+			Filter: repository.FindAllFilter{Trashed: true},
+			Error:  repository.ErrRecordNotFound,
+		},
+	}
+
+	repo := newMemoryCakes(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			res, err := repo.FindAll(context.Background(), &test.Filter)
+			if test.Error != nil {
+				assert.ErrorIs(t, err, test.Error)
+				assert.Nil(t, res)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Result, titles(res))
+		})
+	}
+}
+
+func Test_Cake_Memory_Repository_Find_All_Unsupported_Filter(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Filter repository.FindAllFilter
+		Key    string
+	}{
+		{Name: "Category", Filter: repository.FindAllFilter{Category: "cheesecake"}, Key: "category"},
+		{Name: "Tag", Filter: repository.FindAllFilter{Tag: "vegan"}, Key: "tag"},
+		{Name: "In_Stock", Filter: repository.FindAllFilter{InStock: true}, Key: "in_stock"},
+		{Name: "Exclude_Allergens", Filter: repository.FindAllFilter{Title: "cake", ExcludeAllergens: []string{"nuts"}}, Key: "exclude_allergens"},
+	}
+
+	repo := newMemoryCakes(t)
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			res, err := repo.FindAll(context.Background(), &test.Filter)
+			var unsupported *repository.UnsupportedFilterError
+			assert.ErrorAs(t, err, &unsupported)
+			assert.Equal(t, test.Key, unsupported.Key)
+			assert.Nil(t, res)
+		})
+	}
+}
+
+func Test_Cake_Memory_Repository_Find_All_Pagination(t *testing.T) {
+	var (
+		repo = newMemoryCakes(t)
+		ctx  = context.Background()
+		fil  = repository.FindAllFilter{Sort: []repository.SortField{{Key: "rating", Desc: true}}, Limit: 3}
+	)
+
+	res, err := repo.FindAll(ctx, &fil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"chocolate fudge", "Carrot cake", "Lemon cheesecake"}, titles(res))
+	assert.True(t, fil.HasMore)
+
+	// a cake inserted between the page fetches does not shift the next page
+	assert.NoError(t, repo.Insert(ctx, &schema.Cake{Title: "Opera cake", Rating: 10}))
+
+	fil.Cursor = fil.NextCursor
+	res, err = repo.FindAll(ctx, &fil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Lemon tart"}, titles(res))
+	assert.False(t, fil.HasMore)
+	assert.Empty(t, fil.NextCursor)
+
+	// a cursor of another sort is rejected
+	fil.Sort = []repository.SortField{{Key: "title"}}
+	_, err = repo.FindAll(ctx, &fil)
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+}
+
+func Test_Cake_Memory_Repository_Update(t *testing.T) {
+	var (
+		repo = newMemoryCakes(t)
+		ctx  = context.Background()
+	)
+
+	rec := schema.Cake{ID: 1, Title: "Orange cheesecake", Price: 160000, Currency: "IDR", Version: 1}
+	assert.NoError(t, repo.Update(ctx, &rec))
+	assert.Equal(t, 2, rec.Version)
+
+	res, _ := repo.Find(ctx, 1)
+	assert.Equal(t, "Orange cheesecake", res.Title)
+	assert.Equal(t, int64(160000), res.Price)
+	assert.Equal(t, 2, res.Version)
+
+	stale := schema.Cake{ID: 1, Title: "Stale", Version: 1}
+	assert.ErrorIs(t, repo.Update(ctx, &stale), repository.ErrVersionConflict)
+
+	unversioned := schema.Cake{ID: 1, Title: "Unversioned"}
+	assert.NoError(t, repo.Update(ctx, &unversioned))
+	assert.Equal(t, 0, unversioned.Version)
+
+	res, _ = repo.Find(ctx, 1)
+	assert.Equal(t, 3, res.Version)
+
+	assert.ErrorIs(t, repo.Update(ctx, &schema.Cake{ID: 9}), repository.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Update(ctx, nil), repository.ErrRecordNill)
+}
+
+func Test_Cake_Memory_Repository_Trash(t *testing.T) {
+	var (
+		repo = newMemoryCakes(t)
+		ctx  = context.Background()
+	)
+
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 5), repository.ErrVersionConflict)
+	assert.NoError(t, repo.Delete(ctx, 1, 1))
+	assert.ErrorIs(t, repo.Delete(ctx, 1, 0), repository.ErrRecordNotFound)
+
+	_, err := repo.Find(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	res, err := repo.FindAll(ctx, &repository.FindAllFilter{Trashed: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Lemon cheesecake"}, titles(res))
+
+	assert.ErrorIs(t, repo.Purge(ctx, 2), repository.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, 2), repository.ErrRecordNotFound)
+
+	assert.NoError(t, repo.Restore(ctx, 1))
+	found, err := repo.Find(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, found.Version)
+
+	assert.NoError(t, repo.Delete(ctx, 1, 0))
+	assert.NoError(t, repo.Delete(ctx, 2, 0))
+
+	n, err := repo.PurgeTrashed(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	assert.NoError(t, repo.Purge(ctx, 1))
+	n, err = repo.PurgeTrashed(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = repo.FindAll(ctx, &repository.FindAllFilter{Trashed: true})
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Cake_Memory_Repository_Concurrency(t *testing.T) {
+	var (
+		repo = &repository.CakeMemory{}
+		ctx  = context.Background()
+		wg   sync.WaitGroup
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rec := schema.Cake{Title: "Cake", CreatedAt: time.Now()}
+			assert.NoError(t, repo.Insert(ctx, &rec))
+
+			rec.Title = "Updated cake"
+			assert.NoError(t, repo.Update(ctx, &rec))
+
+			_, err := repo.FindAll(ctx, &repository.FindAllFilter{Limit: repository.MaxLimit})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	res, err := repo.FindAll(ctx, &repository.FindAllFilter{Title: "updated", Limit: repository.MaxLimit})
+	assert.NoError(t, err)
+	assert.Len(t, res, 50)
+}
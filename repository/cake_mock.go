@@ -23,6 +23,18 @@ func (m *CakeMock) FindAll(ctx context.Context, fil *FindAllFilter) ([]schema.Ca
 	return args.Get(0).([]schema.Cake), args.Error(1)
 }
 
+// Stream call fn on each cakes returned by the mock
+func (m *CakeMock) Stream(ctx context.Context, fil *FindAllFilter, fn func(rec *schema.Cake) error) error {
+	args := m.Called(ctx, fil)
+	for _, rec := range args.Get(0).([]schema.Cake) {
+		rec := rec
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *CakeMock) Insert(ctx context.Context, rec *schema.Cake) error {
 	rec.CreatedAt = time.Time{}
 	rec.UpdatedAt = time.Time{}
@@ -37,7 +49,27 @@ func (m *CakeMock) Update(ctx context.Context, rec *schema.Cake) error {
 	return args.Error(0)
 }
 
-func (m *CakeMock) Delete(ctx context.Context, id int) error {
+func (m *CakeMock) Delete(ctx context.Context, id int, version int) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *CakeMock) Restore(ctx context.Context, id int) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (m *CakeMock) Purge(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *CakeMock) PurgeTrashed(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *CakeMock) Truncate(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakePrice struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+type PriceFilter struct {
+	CakeID int
+
+	// Limit is a page size, Before is a price id from a previous page, latest price come first
+	Limit  int
+	Before int
+}
+
+func (f *PriceFilter) IsValidBefore() bool {
+	return f.Before > 0
+}
+
+// FindAll list a price history of a cake, it's still available after the cake is deleted
+func (s *CakePrice) FindAll(ctx context.Context, fil *PriceFilter) ([]schema.CakePrice, error) {
+	if fil == nil {
+		return nil, ErrFilterNill
+	}
+
+	q := util.NewQuery()
+	q.Where("cake_id = ?", fil.CakeID)
+
+	if fil.IsValidBefore() {
+		q.Where("id < ?", fil.Before)
+	}
+
+	where, args := q.Build()
+	query := "SELECT * FROM cake_prices " + where + " ORDER BY id DESC LIMIT ?"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), append(args, NormalizeLimit(fil.Limit))...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake prices, an error occurred")
+	}
+
+	res := []schema.CakePrice{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake prices, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+func (s *CakePrice) Insert(ctx context.Context, rec *schema.CakePrice) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	query := "INSERT INTO cake_prices (cake_id, price, currency, effective_from, tracker_id) VALUES (?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, conn(ctx, s.DB), query, rec.CakeID, rec.Price, rec.Currency, rec.EffectiveFrom, rec.TrackerID)
+	if err != nil {
+		return eris.Wrap(err, "insert cake price, an error occurred")
+	}
+	rec.ID = int(id)
+
+	return nil
+}
+
+func (s *CakePrice) retrieveRows(rows *sql.Rows, res *[]schema.CakePrice) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.CakePrice
+		if err := rows.Scan(
+			&o.ID,
+			&o.CakeID,
+			&o.Price,
+			&o.Currency,
+			&o.EffectiveFrom,
+			&o.TrackerID,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakePriceMock struct {
+	mock.Mock
+}
+
+func (m *CakePriceMock) FindAll(ctx context.Context, fil *PriceFilter) ([]schema.CakePrice, error) {
+	args := m.Called(ctx, fil)
+	res, _ := args.Get(0).([]schema.CakePrice)
+	return res, args.Error(1)
+}
+
+func (m *CakePriceMock) Insert(ctx context.Context, rec *schema.CakePrice) error {
+	rec.EffectiveFrom = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
@@ -0,0 +1,77 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var cakePriceColumns = []string{
+	"id",
+	"cake_id",
+	"price",
+	"currency",
+	"effective_from",
+	"tracker_id",
+}
+
+var cakePrice = schema.CakePrice{
+	ID:            2,
+	CakeID:        1,
+	Price:         175000,
+	Currency:      "IDR",
+	EffectiveFrom: time.Now(),
+	TrackerID:     "cdmqcoe8f8bf6b1jl4tg",
+}
+
+func Test_Cake_Price_Repository_Find_All(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT * FROM cake_prices WHERE cake_id = ? AND id < ? ORDER BY id DESC LIMIT ?").
+			WithArgs(cakePrice.CakeID, 5, repository.DefaultLimit).
+			WillReturnRows(sqlmock.NewRows(cakePriceColumns).
+				AddRow(cakePrice.ID, cakePrice.CakeID, cakePrice.Price, cakePrice.Currency, cakePrice.EffectiveFrom, cakePrice.TrackerID).
+				AddRow(1, cakePrice.CakeID, 150000, "IDR", cakePrice.EffectiveFrom.Add(-time.Hour), ""))
+
+		repo := &repository.CakePrice{DB: db}
+		res, err := repo.FindAll(context.Background(), &repository.PriceFilter{CakeID: cakePrice.CakeID, Before: 5})
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, cakePrice, res[0])
+	})
+
+	t.Run("Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT * FROM cake_prices WHERE cake_id = ? ORDER BY id DESC LIMIT ?").
+			WithArgs(9, repository.DefaultLimit).
+			WillReturnRows(sqlmock.NewRows(cakePriceColumns))
+
+		repo := &repository.CakePrice{DB: db}
+		_, err := repo.FindAll(context.Background(), &repository.PriceFilter{CakeID: 9})
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+	})
+}
+
+func Test_Cake_Price_Repository_Insert(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO cake_prices (cake_id, price, currency, effective_from, tracker_id) VALUES (?, ?, ?, ?, ?)").
+		WithArgs(cakePrice.CakeID, cakePrice.Price, cakePrice.Currency, cakePrice.EffectiveFrom, cakePrice.TrackerID).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+
+	repo := &repository.CakePrice{DB: db}
+	rec := cakePrice
+	assert.NoError(t, repo.Insert(context.Background(), &rec))
+	assert.Equal(t, 3, rec.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+// CakeRevision is an append only store, a revision is never updated or deleted except by Truncate
+type CakeRevision struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func (s *CakeRevision) Find(ctx context.Context, cakeID, id int) (*schema.CakeRevision, error) {
+	if cakeID <= 0 || id <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM cake_revisions WHERE cake_id = ? AND id = ? LIMIT 1"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), cakeID, id)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake revision by id, an error occurred")
+	}
+
+	res := []schema.CakeRevision{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake revision by id, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return &res[0], nil
+}
+
+// FindAll list a revisions of a cake from the oldest one
+func (s *CakeRevision) FindAll(ctx context.Context, cakeID int) ([]schema.CakeRevision, error) {
+	if cakeID <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM cake_revisions WHERE cake_id = ? ORDER BY id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake revisions, an error occurred")
+	}
+
+	res := []schema.CakeRevision{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake revisions, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+func (s *CakeRevision) Insert(ctx context.Context, rec *schema.CakeRevision) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	query := "INSERT INTO cake_revisions (cake_id, action, tracker_id, before_data, after_data, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, conn(ctx, s.DB), query,
+		rec.CakeID,
+		rec.Action,
+		rec.TrackerID,
+		nullJSON(rec.Before),
+		nullJSON(rec.After),
+		rec.CreatedAt,
+	)
+	if err != nil {
+		return eris.Wrap(err, "insert cake revision, an error occurred")
+	}
+	rec.ID = int(id)
+
+	return nil
+}
+
+// Truncate permanently delete every revisions, it's used along with a Truncate of the cakes so no revision
+// of a deleted cake is left behind
+func (s *CakeRevision) Truncate(ctx context.Context) error {
+	query := "DELETE FROM cake_revisions"
+	log.Print(query)
+
+	if _, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query)); err != nil {
+		return eris.Wrap(err, "truncate cake revisions, an error occurred")
+	}
+
+	return nil
+}
+
+// nullJSON store an empty json as a NULL
+func nullJSON(v json.RawMessage) any {
+	if len(v) == 0 {
+		return nil
+	}
+	return []byte(v)
+}
+
+func (s *CakeRevision) retrieveRows(rows *sql.Rows, res *[]schema.CakeRevision) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var (
+			o             schema.CakeRevision
+			before, after []byte
+		)
+		if err := rows.Scan(
+			&o.ID,
+			&o.CakeID,
+			&o.Action,
+			&o.TrackerID,
+			&before,
+			&after,
+			&o.CreatedAt,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+		o.Before, o.After = before, after
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakeRevisionMock struct {
+	mock.Mock
+}
+
+func (m *CakeRevisionMock) Find(ctx context.Context, cakeID, id int) (*schema.CakeRevision, error) {
+	args := m.Called(ctx, cakeID, id)
+	res, _ := args.Get(0).(*schema.CakeRevision)
+	return res, args.Error(1)
+}
+
+func (m *CakeRevisionMock) FindAll(ctx context.Context, cakeID int) ([]schema.CakeRevision, error) {
+	args := m.Called(ctx, cakeID)
+	res, _ := args.Get(0).([]schema.CakeRevision)
+	return res, args.Error(1)
+}
+
+func (m *CakeRevisionMock) Truncate(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *CakeRevisionMock) Insert(ctx context.Context, rec *schema.CakeRevision) error {
+	rec.CreatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var revision = schema.CakeRevision{
+	ID:        1,
+	CakeID:    1,
+	Action:    schema.RevisionActionUpdate,
+	TrackerID: "9bsv0s24le2002put6ig",
+	Before:    json.RawMessage(`{"id":1,"title":"Test Title"}`),
+	After:     json.RawMessage(`{"id":1,"title":"Test Title 2"}`),
+	CreatedAt: time.Now(),
+}
+
+var revisionColumns = []string{
+	"id",
+	"cake_id",
+	"action",
+	"tracker_id",
+	"before_data",
+	"after_data",
+	"created_at",
+}
+
+func Test_Cake_Revision_Repository_Find(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(revisionColumns).AddRow(
+		revision.ID,
+		revision.CakeID,
+		revision.Action,
+		revision.TrackerID,
+		[]byte(revision.Before),
+		[]byte(revision.After),
+		revision.CreatedAt,
+	)
+	mock.ExpectQuery("SELECT * FROM cake_revisions WHERE cake_id = ? AND id = ? LIMIT 1").
+		WithArgs(revision.CakeID, revision.ID).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT * FROM cake_revisions WHERE cake_id = ? AND id = ? LIMIT 1").
+		WithArgs(revision.CakeID, 2).WillReturnRows(sqlmock.NewRows(revisionColumns))
+
+	repo := &repository.CakeRevision{DB: db}
+	res, err := repo.Find(context.Background(), revision.CakeID, revision.ID)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(revision.After), string(res.After))
+
+	res, err = repo.Find(context.Background(), revision.CakeID, 2)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Cake_Revision_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(revisionColumns).
+		AddRow(1, revision.CakeID, schema.RevisionActionInsert, revision.TrackerID, nil, []byte(revision.Before), revision.CreatedAt).
+		AddRow(2, revision.CakeID, schema.RevisionActionDelete, revision.TrackerID, []byte(revision.Before), nil, revision.CreatedAt)
+	mock.ExpectQuery("SELECT * FROM cake_revisions WHERE cake_id = ? ORDER BY id ASC").
+		WithArgs(revision.CakeID).WillReturnRows(rows)
+
+	repo := &repository.CakeRevision{DB: db}
+	res, err := repo.FindAll(context.Background(), revision.CakeID)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.Nil(t, res[0].Before)
+	assert.Nil(t, res[1].After)
+}
+
+func Test_Cake_Revision_Repository_Insert(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rec := revision
+	rec.Before = nil
+
+	mock.ExpectExec("INSERT INTO cake_revisions (cake_id, action, tracker_id, before_data, after_data, created_at) VALUES (?, ?, ?, ?, ?, ?)").
+		WithArgs(rec.CakeID, rec.Action, rec.TrackerID, nil, []byte(rec.After), rec.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+
+	repo := &repository.CakeRevision{DB: db}
+	err := repo.Insert(context.Background(), &rec)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, rec.ID)
+}
+
+func Test_Cake_Revision_Repository_Truncate(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM cake_revisions").WillReturnResult(sqlmock.NewResult(0, 3))
+
+	repo := &repository.CakeRevision{DB: db}
+	assert.NoError(t, repo.Truncate(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
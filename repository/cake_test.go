@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"log"
 	"testing"
 	"time"
@@ -23,12 +24,28 @@ func NewMock() (*sql.DB, sqlmock.Sqlmock) {
 	return db, mock
 }
 
+var cakeColumns = []string{
+	"id",
+	"title",
+	"description",
+	"rating",
+	"image",
+	"created_at",
+	"updated_at",
+	"deleted_at",
+	"version",
+	"price",
+	"currency",
+}
+
 var cake = schema.Cake{
 	ID:          1,
 	Title:       "Test Title",
 	Description: "Test Description",
 	Rating:      7,
 	Image:       "https://cdn.lorem.space/images/movie/.cache/150x220/totoro-1988.jpeg",
+	Price:       150000,
+	Currency:    "IDR",
 	CreatedAt:   time.Now(),
 	UpdatedAt:   time.Now(),
 }
@@ -41,6 +58,8 @@ var cakes = []schema.Cake{
 		Description: "Test Description 2",
 		Rating:      8,
 		Image:       "https://cdn.lorem.space/images/movie/.cache/150x220/totoro-1988.jpeg",
+		Price:       85000,
+		Currency:    "IDR",
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	},
@@ -50,15 +69,7 @@ func Test_Cake_Repository_Find(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{
-		"id",
-		"title",
-		"description",
-		"rating",
-		"image",
-		"created_at",
-		"updated_at",
-	}).AddRow(
+	rows := sqlmock.NewRows(cakeColumns).AddRow(
 		cake.ID,
 		cake.Title,
 		cake.Description,
@@ -66,8 +77,12 @@ func Test_Cake_Repository_Find(t *testing.T) {
 		cake.Image,
 		cake.CreatedAt,
 		cake.UpdatedAt,
+		nil,
+		1,
+		cake.Price,
+		cake.Currency,
 	)
-	mock.ExpectQuery("SELECT * FROM cakes WHERE id = ? LIMIT 1").WithArgs(cake.ID).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT * FROM cakes WHERE id = ? AND deleted_at IS NULL LIMIT 1").WithArgs(cake.ID).WillReturnRows(rows)
 
 	repo := &repository.Cake{DB: db}
 	res, err := repo.Find(context.Background(), cake.ID)
@@ -79,6 +94,9 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
+	ratingMin, ratingMax := 5.0, 9.5
+	priceMin, priceMax := int64(50000), int64(200000)
+
 	tests := []struct {
 		Name   string
 		Query  string
@@ -87,12 +105,12 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 	}{
 		{
 			Name:   "No_Filter",
-			Query:  "SELECT * FROM cakes ORDER BY title ASC, rating ASC",
+			Query:  "SELECT * FROM cakes WHERE deleted_at IS NULL ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
 			Result: cakes,
 		},
 		{
 			Name:  "Title_Filter",
-			Query: "SELECT * FROM cakes WHERE title LIKE ? ORDER BY title ASC, rating ASC",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND title LIKE ? ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
 			Filter: repository.FindAllFilter{
 				Title: "Test Title",
 			},
@@ -100,7 +118,7 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 		},
 		{
 			Name:  "Description_Filter",
-			Query: "SELECT * FROM cakes WHERE description LIKE ? ORDER BY title ASC, rating ASC",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND description LIKE ? ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
 			Filter: repository.FindAllFilter{
 				Description: "Test Description",
 			},
@@ -108,30 +126,90 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 		},
 		{
 			Name:  "All_Filter",
-			Query: "SELECT * FROM cakes WHERE title LIKE ? AND description LIKE ? ORDER BY title ASC, rating ASC",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND title LIKE ? AND description LIKE ? ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
 			Filter: repository.FindAllFilter{
 				Title:       "Test Title",
 				Description: "Test Description",
 			},
 			Result: cakes,
 		},
+		{
+			Name:  "Trashed",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NOT NULL ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				Trashed: true,
+			},
+			Result: cakes,
+		},
+		{
+			Name:  "Category_Tag_Filter",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND id IN (SELECT cc.cake_id FROM cake_categories cc WHERE cc.category_id IN (WITH RECURSIVE tree AS (SELECT id FROM categories WHERE slug = ? UNION ALL SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id) SELECT id FROM tree)) AND id IN (SELECT ct.cake_id FROM cake_tags ct JOIN tags t ON t.id = ct.tag_id WHERE t.name = ?) ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				Category: "birthday",
+				Tag:      "vegan",
+			},
+			Result: cakes,
+		},
+		{
+			Name:  "In_Stock",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND id IN (SELECT cake_id FROM cake_stocks WHERE quantity > 0) ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				InStock: true,
+			},
+			Result: cakes,
+		},
+		{
+			Name:  "Exclude_Allergens",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND id NOT IN (SELECT cake_id FROM cake_allergens WHERE allergen IN (?, ?)) ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				ExcludeAllergens: []string{"nuts", "gluten"},
+			},
+			Result: cakes,
+		},
+		{
+			Name:  "Range_Filter",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND rating >= ? AND rating <= ? AND created_at > ? AND created_at < ? AND updated_at >= ? ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				RatingMin:     &ratingMin,
+				RatingMax:     &ratingMax,
+				CreatedAfter:  time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				UpdatedSince:  time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			},
+			Result: cakes,
+		},
+		{
+			Name:  "Price_Filter",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL AND currency = ? AND price >= ? AND price <= ? ORDER BY price ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				Currency: "IDR",
+				PriceMin: &priceMin,
+				PriceMax: &priceMax,
+				Sort:     []repository.SortField{{Key: "price"}},
+			},
+			Result: cakes,
+		},
+		{
+			Name:  "Sort",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL ORDER BY rating DESC, title ASC, created_at ASC, id ASC LIMIT ?",
+			Filter: repository.FindAllFilter{
+				Sort: []repository.SortField{
+					{Key: "rating", Desc: true},
+					{Key: "title"},
+					{Key: "created_at"},
+				},
+			},
+			Result: cakes,
+		},
 		{
 			Name:  "Record_Not_Found",
-			Query: "SELECT * FROM cakes ORDER BY title ASC, rating ASC",
+			Query: "SELECT * FROM cakes WHERE deleted_at IS NULL ORDER BY title ASC, rating ASC, id ASC LIMIT ?",
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			rows := sqlmock.NewRows([]string{
-				"id",
-				"title",
-				"description",
-				"rating",
-				"image",
-				"created_at",
-				"updated_at",
-			})
+			rows := sqlmock.NewRows(cakeColumns)
 
 			for _, c := range test.Result {
 				rows = rows.AddRow(
@@ -142,6 +220,10 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 					c.Image,
 					c.CreatedAt,
 					c.UpdatedAt,
+					nil,
+					1,
+					c.Price,
+					c.Currency,
 				)
 			}
 
@@ -152,6 +234,40 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 			if test.Filter.IsValidDescription() {
 				args = append(args, "%"+test.Filter.Description+"%")
 			}
+			if test.Filter.IsValidCategory() {
+				args = append(args, test.Filter.Category)
+			}
+			if test.Filter.IsValidTag() {
+				args = append(args, test.Filter.Tag)
+			}
+			for _, allergen := range test.Filter.ExcludeAllergens {
+				args = append(args, allergen)
+			}
+			if test.Filter.IsValidRatingMin() {
+				args = append(args, *test.Filter.RatingMin)
+			}
+			if test.Filter.IsValidRatingMax() {
+				args = append(args, *test.Filter.RatingMax)
+			}
+			if test.Filter.IsValidCurrency() {
+				args = append(args, test.Filter.Currency)
+			}
+			if test.Filter.IsValidPriceMin() {
+				args = append(args, *test.Filter.PriceMin)
+			}
+			if test.Filter.IsValidPriceMax() {
+				args = append(args, *test.Filter.PriceMax)
+			}
+			if test.Filter.IsValidCreatedAfter() {
+				args = append(args, test.Filter.CreatedAfter)
+			}
+			if test.Filter.IsValidCreatedBefore() {
+				args = append(args, test.Filter.CreatedBefore)
+			}
+			if test.Filter.IsValidUpdatedSince() {
+				args = append(args, test.Filter.UpdatedSince)
+			}
+			args = append(args, repository.DefaultLimit+1)
 
 			mock.ExpectQuery(test.Query).WithArgs(args...).WillReturnRows(rows)
 
@@ -170,6 +286,216 @@ func Test_Cake_Repository_Find_All(t *testing.T) {
 	}
 }
 
+func Test_Cake_Repository_Find_All_Pagination(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	repo := &repository.Cake{DB: db}
+
+	rows := sqlmock.NewRows(cakeColumns)
+	for _, c := range cakes {
+		rows = rows.AddRow(c.ID, c.Title, c.Description, c.Rating, c.Image, c.CreatedAt, c.UpdatedAt, nil, 1, c.Price, c.Currency)
+	}
+	mock.ExpectQuery("SELECT * FROM cakes WHERE deleted_at IS NULL ORDER BY title ASC, rating ASC, id ASC LIMIT ?").
+		WithArgs(2).WillReturnRows(rows)
+
+	fil := repository.FindAllFilter{Limit: 1}
+	res, err := repo.FindAll(context.Background(), &fil)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.True(t, fil.HasMore)
+	assert.NotEmpty(t, fil.NextCursor)
+
+	rows = sqlmock.NewRows(cakeColumns).AddRow(
+		cakes[1].ID, cakes[1].Title, cakes[1].Description, cakes[1].Rating, cakes[1].Image, cakes[1].CreatedAt, cakes[1].UpdatedAt, nil, 1, cakes[1].Price, cakes[1].Currency,
+	)
+	mock.ExpectQuery("SELECT * FROM cakes WHERE deleted_at IS NULL AND (title > ? OR (title = ? AND rating > ?) OR (title = ? AND rating = ? AND id > ?)) ORDER BY title ASC, rating ASC, id ASC LIMIT ?").
+		WithArgs(cake.Title, cake.Title, cake.Rating, cake.Title, cake.Rating, cake.ID, 2).WillReturnRows(rows)
+
+	fil = repository.FindAllFilter{Limit: 1, Cursor: fil.NextCursor}
+	res, err = repo.FindAll(context.Background(), &fil)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.False(t, fil.HasMore)
+	assert.Empty(t, fil.NextCursor)
+
+	fil = repository.FindAllFilter{Cursor: "not-a-cursor"}
+	_, err = repo.FindAll(context.Background(), &fil)
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Repository_Stream(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	repo := &repository.Cake{DB: db}
+
+	rows := sqlmock.NewRows(cakeColumns)
+	for _, c := range cakes {
+		rows = rows.AddRow(c.ID, c.Title, c.Description, c.Rating, c.Image, c.CreatedAt, c.UpdatedAt, nil, 1, c.Price, c.Currency)
+	}
+	mock.ExpectQuery("SELECT * FROM cakes WHERE deleted_at IS NULL AND currency = ? ORDER BY title ASC, rating ASC, id ASC").
+		WithArgs("IDR").WillReturnRows(rows)
+
+	// a limit is ignored, every cakes is streamed
+	ids := []int{}
+	err := repo.Stream(context.Background(), &repository.FindAllFilter{Currency: "IDR", Limit: 1}, func(rec *schema.Cake) error {
+		ids = append(ids, rec.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{cakes[0].ID, cakes[1].ID}, ids)
+
+	rows = sqlmock.NewRows(cakeColumns)
+	for _, c := range cakes {
+		rows = rows.AddRow(c.ID, c.Title, c.Description, c.Rating, c.Image, c.CreatedAt, c.UpdatedAt, nil, 1, c.Price, c.Currency)
+	}
+	mock.ExpectQuery("SELECT * FROM cakes WHERE deleted_at IS NULL ORDER BY title ASC, rating ASC, id ASC").
+		WillReturnRows(rows)
+
+	// an error of fn stop the stream
+	stopped := errors.New("stopped")
+	calls := 0
+	err = repo.Stream(context.Background(), &repository.FindAllFilter{}, func(rec *schema.Cake) error {
+		calls++
+		return stopped
+	})
+	assert.ErrorIs(t, err, stopped)
+	assert.Equal(t, 1, calls)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Repository_Find_All_Search(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(append(cakeColumns, "score"))
+	for i, c := range cakes {
+		rows = rows.AddRow(c.ID, c.Title, c.Description, c.Rating, c.Image, c.CreatedAt, c.UpdatedAt, nil, 1, c.Price, c.Currency, 2.5-float64(i))
+	}
+
+	match := "MATCH (title, description) AGAINST (? IN BOOLEAN MODE)"
+	mock.ExpectQuery("SELECT *, "+match+" AS score FROM cakes WHERE deleted_at IS NULL AND "+match+" AND title LIKE ? ORDER BY score DESC, id ASC LIMIT ?").
+		WithArgs("+lemon -nuts", "+lemon -nuts", "%Test%", 2).WillReturnRows(rows)
+
+	repo := &repository.Cake{DB: db}
+	fil := repository.FindAllFilter{
+		Title:      "Test",
+		Search:     "+lemon -nuts",
+		SearchMode: repository.SearchModeBoolean,
+		Limit:      1,
+	}
+	res, err := repo.FindAll(context.Background(), &fil)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, 2.5, *res[0].Score)
+	assert.True(t, fil.HasMore)
+
+	rows = sqlmock.NewRows(append(cakeColumns, "score")).AddRow(cakes[1].ID, cakes[1].Title, cakes[1].Description, cakes[1].Rating, cakes[1].Image, cakes[1].CreatedAt, cakes[1].UpdatedAt, nil, 1, cakes[1].Price, cakes[1].Currency, 1.5)
+
+	mock.ExpectQuery("SELECT *, "+match+" AS score FROM cakes WHERE deleted_at IS NULL AND "+match+" AND title LIKE ? AND ("+match+" < ? OR ("+match+" = ? AND id > ?)) ORDER BY score DESC, id ASC LIMIT ?").
+		WithArgs("+lemon -nuts", "+lemon -nuts", "%Test%", "+lemon -nuts", 2.5, "+lemon -nuts", 2.5, cake.ID, 2).WillReturnRows(rows)
+
+	fil.Cursor = fil.NextCursor
+	res, err = repo.FindAll(context.Background(), &fil)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.False(t, fil.HasMore)
+
+	_, err = repo.FindAll(context.Background(), &repository.FindAllFilter{
+		Sort: []repository.SortField{{Key: "relevance"}},
+	})
+	assert.ErrorIs(t, err, repository.ErrInvalidSort)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Repository_Find_All_Boolean_Search_Malformed(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Search   string
+		Expected string
+	}{
+		{Name: "Lone_Plus", Search: "lemon +", Expected: "lemon"},
+		{Name: "At_Sign", Search: "@lemon -nuts", Expected: "lemon -nuts"},
+		{Name: "Unbalanced_Quote", Search: `"lemon cake`, Expected: "lemon cake"},
+		{Name: "Prefix", Search: "+choco* (cake)", Expected: "+choco* cake"},
+	}
+
+	match := "MATCH (title, description) AGAINST (? IN BOOLEAN MODE)"
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			db, mock := NewMock()
+			defer db.Close()
+
+			// the search is sanitized, so MySQL does not fail with a syntax error
+			mock.ExpectQuery("SELECT *, "+match+" AS score FROM cakes WHERE deleted_at IS NULL AND "+match+" ORDER BY score DESC, id ASC LIMIT ?").
+				WithArgs(test.Expected, test.Expected, 11).
+				WillReturnRows(sqlmock.NewRows(append(cakeColumns, "score")).AddRow(cake.ID, cake.Title, cake.Description, cake.Rating, cake.Image, cake.CreatedAt, cake.UpdatedAt, nil, 1, cake.Price, cake.Currency, 1.5))
+
+			repo := &repository.Cake{DB: db}
+			res, err := repo.FindAll(context.Background(), &repository.FindAllFilter{
+				Search:     test.Search,
+				SearchMode: repository.SearchModeBoolean,
+				Limit:      10,
+			})
+			assert.NoError(t, err)
+			assert.Len(t, res, 1)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_Cake_Repository_Parse_Sort(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Value    string
+		Expected []repository.SortField
+		Error    error
+	}{
+		{
+			Name:     "Empty",
+			Value:    "",
+			Expected: nil,
+		},
+		{
+			Name:  "Multiple_Keys",
+			Value: "-rating,title,+created_at",
+			Expected: []repository.SortField{
+				{Key: "rating", Desc: true},
+				{Key: "title"},
+				{Key: "created_at"},
+			},
+		},
+		{
+			Name:  "Unknown_Key",
+			Value: "title,weight",
+			Error: repository.ErrInvalidSort,
+		},
+		{
+			Name:  "Duplicate_Key",
+			Value: "title,-title",
+			Error: repository.ErrInvalidSort,
+		},
+		{
+			Name:  "Injection",
+			Value: "title; DROP TABLE cakes",
+			Error: repository.ErrInvalidSort,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			res, err := repository.ParseSort(test.Value)
+			assert.ErrorIs(t, err, test.Error)
+			assert.Equal(t, test.Expected, res)
+		})
+	}
+}
+
 func Test_Cake_Repository_Insert(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
@@ -177,12 +503,14 @@ func Test_Cake_Repository_Insert(t *testing.T) {
 	expec := 1
 	result := sqlmock.NewResult(int64(expec), 1)
 
-	mock.ExpectExec("INSERT INTO cakes (title, description, rating, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)").
+	mock.ExpectExec("INSERT INTO cakes (title, description, rating, image, price, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)").
 		WithArgs(
 			cake.Title,
 			cake.Description,
 			cake.Rating,
 			cake.Image,
+			cake.Price,
+			cake.Currency,
 			cake.CreatedAt,
 			cake.UpdatedAt,
 		).WillReturnResult(result)
@@ -193,34 +521,173 @@ func Test_Cake_Repository_Insert(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Cake_Repository_Insert_Postgres(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	expec := 1
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(expec)
+
+	// Postgres return the inserted id by the statement itself
+	mock.ExpectQuery("INSERT INTO cakes (title, description, rating, image, price, currency, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id").
+		WithArgs(
+			cake.Title,
+			cake.Description,
+			cake.Rating,
+			cake.Image,
+			cake.Price,
+			cake.Currency,
+			cake.CreatedAt,
+			cake.UpdatedAt,
+		).WillReturnRows(rows)
+	repo := &repository.Cake{DB: db, Dialect: repository.DialectPostgres}
+	err := repo.Insert(context.Background(), &cake)
+
+	assert.Equal(t, expec, cake.ID)
+	assert.NoError(t, err)
+}
+
+func Test_Cake_Repository_Find_All_Postgres(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(cakeColumns).AddRow(
+		cake.ID,
+		cake.Title,
+		cake.Description,
+		cake.Rating,
+		cake.Image,
+		cake.CreatedAt,
+		cake.UpdatedAt,
+		nil,
+		1,
+		cake.Price,
+		cake.Currency,
+	)
+	mock.ExpectQuery("SELECT * FROM cakes WHERE deleted_at IS NULL AND title LIKE $1 AND currency = $2 ORDER BY title ASC, rating ASC, id ASC LIMIT $3").
+		WithArgs("%Lemon%", "IDR", 21).
+		WillReturnRows(rows)
+
+	repo := &repository.Cake{DB: db, Dialect: repository.DialectPostgres}
+	res, err := repo.FindAll(context.Background(), &repository.FindAllFilter{Title: "Lemon", Currency: "IDR"})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func Test_Cake_Repository_Update(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
 	result := sqlmock.NewResult(0, 1)
-	mock.ExpectExec("UPDATE cakes SET title=?, description=?, rating=?, image=?, updated_at=? WHERE id = ?").
+	mock.ExpectExec("UPDATE cakes SET title=?, description=?, image=?, price=?, currency=?, updated_at=?, version=version+1 WHERE id = ? AND deleted_at IS NULL").
 		WithArgs(
 			cake.Title,
 			cake.Description,
-			cake.Rating,
 			cake.Image,
+			cake.Price,
+			cake.Currency,
 			cake.UpdatedAt,
 			cake.ID,
 		).WillReturnResult(result)
 
+	// an unversioned update does not check the record version
+	rec := cake
+	rec.Version = 0
+
 	repo := &repository.Cake{DB: db}
-	err := repo.Update(context.Background(), &cake)
+	err := repo.Update(context.Background(), &rec)
 	assert.NoError(t, err)
 }
 
+func Test_Cake_Repository_Update_Versioned(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	query := "UPDATE cakes SET title=?, description=?, image=?, price=?, currency=?, updated_at=?, version=version+1 WHERE id = ? AND deleted_at IS NULL AND version = ?"
+	rec := cake
+	rec.Version = 3
+
+	mock.ExpectExec(query).
+		WithArgs(rec.Title, rec.Description, rec.Image, rec.Price, rec.Currency, rec.UpdatedAt, rec.ID, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).
+		WithArgs(rec.Title, rec.Description, rec.Image, rec.Price, rec.Currency, rec.UpdatedAt, rec.ID, 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &repository.Cake{DB: db}
+	assert.NoError(t, repo.Update(context.Background(), &rec))
+	assert.Equal(t, 4, rec.Version)
+
+	assert.ErrorIs(t, repo.Update(context.Background(), &rec), repository.ErrVersionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func Test_Cake_Repository_Delete(t *testing.T) {
 	db, mock := NewMock()
 	defer db.Close()
 
 	result := sqlmock.NewResult(0, 1)
-	mock.ExpectExec("DELETE FROM cakes WHERE id = ?").WithArgs(cake.ID).WillReturnResult(result)
+	mock.ExpectExec("UPDATE cakes SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), cake.ID).WillReturnResult(result)
+	mock.ExpectExec("UPDATE cakes SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND version = ?").
+		WithArgs(sqlmock.AnyArg(), cake.ID, 2).WillReturnResult(sqlmock.NewResult(0, 0))
 
 	repo := &repository.Cake{DB: db}
-	err := repo.Delete(context.Background(), cake.ID)
+	assert.NoError(t, repo.Delete(context.Background(), cake.ID, 0))
+	assert.ErrorIs(t, repo.Delete(context.Background(), cake.ID, 2), repository.ErrVersionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Repository_Restore(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	query := "UPDATE cakes SET deleted_at = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NOT NULL"
+	mock.ExpectExec(query).WithArgs(sqlmock.AnyArg(), cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(sqlmock.AnyArg(), cake.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &repository.Cake{DB: db}
+	assert.NoError(t, repo.Restore(context.Background(), cake.ID))
+	assert.ErrorIs(t, repo.Restore(context.Background(), cake.ID), repository.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Repository_Purge(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	query := "DELETE FROM cakes WHERE id = ? AND deleted_at IS NOT NULL"
+	mock.ExpectExec(query).WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &repository.Cake{DB: db}
+	assert.NoError(t, repo.Purge(context.Background(), cake.ID))
+	assert.ErrorIs(t, repo.Purge(context.Background(), cake.ID), repository.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Cake_Repository_Purge_Trashed(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	before := time.Now()
+	mock.ExpectExec("DELETE FROM cakes WHERE deleted_at IS NOT NULL AND deleted_at < ?").
+		WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	repo := &repository.Cake{DB: db}
+	n, err := repo.PurgeTrashed(context.Background(), before)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func Test_Cake_Repository_Truncate(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM cakes").WillReturnResult(sqlmock.NewResult(0, 4))
+
+	repo := &repository.Cake{DB: db}
+	assert.NoError(t, repo.Truncate(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
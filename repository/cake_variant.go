@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakeVariant struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func (s *CakeVariant) Find(ctx context.Context, cakeID, id int) (*schema.CakeVariant, error) {
+	if id <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM cake_variants WHERE id = ? AND cake_id = ? LIMIT 1"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), id, cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake variant by id, an error occurred")
+	}
+
+	res := []schema.CakeVariant{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake variant by id, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return &res[0], nil
+}
+
+func (s *CakeVariant) FindAll(ctx context.Context, cakeID int) ([]schema.CakeVariant, error) {
+	query := "SELECT * FROM cake_variants WHERE cake_id = ? ORDER BY id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "find cake variants, an error occurred")
+	}
+
+	res := []schema.CakeVariant{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find cake variants, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+// Insert insert a variant, ErrDuplicateRecord is returned when the sku is already used
+func (s *CakeVariant) Insert(ctx context.Context, rec *schema.CakeVariant) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	options, err := json.Marshal(rec.Options)
+	if err != nil {
+		return eris.Wrap(err, "insert cake variant, an error occurred")
+	}
+
+	query := "INSERT INTO cake_variants (cake_id, sku, name, options, price, stock, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, conn(ctx, s.DB), query, rec.CakeID, rec.SKU, rec.Name, options, rec.Price, rec.Stock, rec.CreatedAt, rec.UpdatedAt)
+	if err != nil {
+		if isDuplicate(err) {
+			return ErrDuplicateRecord
+		}
+		return eris.Wrap(err, "insert cake variant, an error occurred")
+	}
+	rec.ID = int(id)
+
+	return nil
+}
+
+// Update update a variant, ErrDuplicateRecord is returned when the sku is already used by another variant
+func (s *CakeVariant) Update(ctx context.Context, rec *schema.CakeVariant) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+	if rec.ID <= 0 {
+		return ErrRecordNotFound
+	}
+
+	options, err := json.Marshal(rec.Options)
+	if err != nil {
+		return eris.Wrap(err, "update cake variant, an error occurred")
+	}
+
+	query := "UPDATE cake_variants SET sku=?, name=?, options=?, price=?, stock=?, updated_at=? WHERE id = ? AND cake_id = ?"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), rec.SKU, rec.Name, options, rec.Price, rec.Stock, rec.UpdatedAt, rec.ID, rec.CakeID)
+	if err != nil {
+		if isDuplicate(err) {
+			return ErrDuplicateRecord
+		}
+		return eris.Wrap(err, "update cake variant, an error occurred")
+	}
+
+	return affected(res)
+}
+
+func (s *CakeVariant) Delete(ctx context.Context, cakeID, id int) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM cake_variants WHERE id = ? AND cake_id = ?"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), id, cakeID)
+	if err != nil {
+		return eris.Wrap(err, "delete cake variant, an error occurred")
+	}
+
+	return affected(res)
+}
+
+func (s *CakeVariant) retrieveRows(rows *sql.Rows, res *[]schema.CakeVariant) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var (
+			o       schema.CakeVariant
+			options []byte
+		)
+		if err := rows.Scan(
+			&o.ID,
+			&o.CakeID,
+			&o.SKU,
+			&o.Name,
+			&options,
+			&o.Price,
+			&o.Stock,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		if len(options) > 0 {
+			if err := json.Unmarshal(options, &o.Options); err != nil {
+				util.ResetSlice(res)
+				return err
+			}
+		}
+		if o.Options == nil {
+			o.Options = map[string]string{}
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
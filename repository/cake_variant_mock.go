@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakeVariantMock struct {
+	mock.Mock
+}
+
+func (m *CakeVariantMock) Find(ctx context.Context, cakeID, id int) (*schema.CakeVariant, error) {
+	args := m.Called(ctx, cakeID, id)
+	res, _ := args.Get(0).(*schema.CakeVariant)
+	return res, args.Error(1)
+}
+
+func (m *CakeVariantMock) FindAll(ctx context.Context, cakeID int) ([]schema.CakeVariant, error) {
+	args := m.Called(ctx, cakeID)
+	res, _ := args.Get(0).([]schema.CakeVariant)
+	return res, args.Error(1)
+}
+
+func (m *CakeVariantMock) Insert(ctx context.Context, rec *schema.CakeVariant) error {
+	rec.CreatedAt = time.Time{}
+	rec.UpdatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *CakeVariantMock) Update(ctx context.Context, rec *schema.CakeVariant) error {
+	rec.UpdatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *CakeVariantMock) Delete(ctx context.Context, cakeID, id int) error {
+	args := m.Called(ctx, cakeID, id)
+	return args.Error(0)
+}
@@ -0,0 +1,172 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var cakeVariantColumns = []string{
+	"id",
+	"cake_id",
+	"sku",
+	"name",
+	"options",
+	"price",
+	"stock",
+	"created_at",
+	"updated_at",
+}
+
+var cakeVariant = schema.CakeVariant{
+	ID:        1,
+	CakeID:    1,
+	SKU:       "CHEESE-8IN",
+	Name:      "8 inch",
+	Options:   map[string]string{"size": `8"`},
+	Price:     250000,
+	Stock:     4,
+	CreatedAt: time.Now(),
+	UpdatedAt: time.Now(),
+}
+
+func cakeVariantRow(rows *sqlmock.Rows, rec schema.CakeVariant, options any) *sqlmock.Rows {
+	return rows.AddRow(
+		rec.ID,
+		rec.CakeID,
+		rec.SKU,
+		rec.Name,
+		options,
+		rec.Price,
+		rec.Stock,
+		rec.CreatedAt,
+		rec.UpdatedAt,
+	)
+}
+
+func Test_Cake_Variant_Repository_Find(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT * FROM cake_variants WHERE id = ? AND cake_id = ? LIMIT 1").
+			WithArgs(cakeVariant.ID, cakeVariant.CakeID).
+			WillReturnRows(cakeVariantRow(sqlmock.NewRows(cakeVariantColumns), cakeVariant, `{"size":"8\""}`))
+
+		repo := &repository.CakeVariant{DB: db}
+		res, err := repo.Find(context.Background(), cakeVariant.CakeID, cakeVariant.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, &cakeVariant, res)
+	})
+
+	t.Run("Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT * FROM cake_variants WHERE id = ? AND cake_id = ? LIMIT 1").
+			WithArgs(9, cakeVariant.CakeID).
+			WillReturnRows(sqlmock.NewRows(cakeVariantColumns))
+
+		repo := &repository.CakeVariant{DB: db}
+		_, err := repo.Find(context.Background(), cakeVariant.CakeID, 9)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+	})
+}
+
+func Test_Cake_Variant_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	second := cakeVariant
+	second.ID, second.SKU, second.Options = 2, "CHEESE-10IN", map[string]string{}
+
+	rows := sqlmock.NewRows(cakeVariantColumns)
+	cakeVariantRow(rows, cakeVariant, `{"size":"8\""}`)
+	cakeVariantRow(rows, second, nil)
+
+	mock.ExpectQuery("SELECT * FROM cake_variants WHERE cake_id = ? ORDER BY id ASC").
+		WithArgs(cakeVariant.CakeID).
+		WillReturnRows(rows)
+
+	repo := &repository.CakeVariant{DB: db}
+	res, err := repo.FindAll(context.Background(), cakeVariant.CakeID)
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.CakeVariant{cakeVariant, second}, res)
+}
+
+func Test_Cake_Variant_Repository_Insert(t *testing.T) {
+	insertQuery := "INSERT INTO cake_variants (cake_id, sku, name, options, price, stock, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	t.Run("Inserted", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectExec(insertQuery).
+			WithArgs(cakeVariant.CakeID, cakeVariant.SKU, cakeVariant.Name, []byte(`{"size":"8\""}`), cakeVariant.Price, cakeVariant.Stock, cakeVariant.CreatedAt, cakeVariant.UpdatedAt).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+
+		repo := &repository.CakeVariant{DB: db}
+		rec := cakeVariant
+		rec.ID = 0
+		assert.NoError(t, repo.Insert(context.Background(), &rec))
+		assert.Equal(t, 7, rec.ID)
+	})
+
+	t.Run("Duplicate_SKU", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectExec(insertQuery).
+			WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+		repo := &repository.CakeVariant{DB: db}
+		rec := cakeVariant
+		assert.ErrorIs(t, repo.Insert(context.Background(), &rec), repository.ErrDuplicateRecord)
+	})
+}
+
+func Test_Cake_Variant_Repository_Update(t *testing.T) {
+	updateQuery := "UPDATE cake_variants SET sku=?, name=?, options=?, price=?, stock=?, updated_at=? WHERE id = ? AND cake_id = ?"
+
+	t.Run("Updated", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectExec(updateQuery).
+			WithArgs(cakeVariant.SKU, cakeVariant.Name, []byte(`{"size":"8\""}`), cakeVariant.Price, cakeVariant.Stock, cakeVariant.UpdatedAt, cakeVariant.ID, cakeVariant.CakeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		repo := &repository.CakeVariant{DB: db}
+		rec := cakeVariant
+		assert.NoError(t, repo.Update(context.Background(), &rec))
+	})
+
+	t.Run("Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectExec(updateQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		repo := &repository.CakeVariant{DB: db}
+		rec := cakeVariant
+		assert.ErrorIs(t, repo.Update(context.Background(), &rec), repository.ErrRecordNotFound)
+	})
+}
+
+func Test_Cake_Variant_Repository_Delete(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM cake_variants WHERE id = ? AND cake_id = ?").
+		WithArgs(cakeVariant.ID, cakeVariant.CakeID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &repository.CakeVariant{DB: db}
+	assert.NoError(t, repo.Delete(context.Background(), cakeVariant.CakeID, cakeVariant.ID))
+}
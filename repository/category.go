@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type Category struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func (s *Category) Find(ctx context.Context, id int) (*schema.Category, error) {
+	if id <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM categories WHERE id = ? LIMIT 1"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), id)
+	if err != nil {
+		return nil, eris.Wrap(err, "find category by id, an error occurred")
+	}
+
+	res := []schema.Category{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find category by id, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return &res[0], nil
+}
+
+func (s *Category) FindAll(ctx context.Context) ([]schema.Category, error) {
+	query := "SELECT * FROM categories ORDER BY name ASC, id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query))
+	if err != nil {
+		return nil, eris.Wrap(err, "find categories, an error occurred")
+	}
+
+	res := []schema.Category{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find categories, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+// FindByCakeIDs list a categories of each cakes, keyed by cake id
+func (s *Category) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.Category, error) {
+	res := map[int][]schema.Category{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := "SELECT cc.cake_id, c.* FROM cake_categories cc JOIN categories c ON c.id = cc.category_id WHERE cc.cake_id IN (" + placeholders(len(ids)) + ") ORDER BY c.name ASC, c.id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), intArgs(ids)...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find categories by cake ids, an error occurred")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cakeID int
+			o      schema.Category
+		)
+		if err := rows.Scan(append([]any{&cakeID}, categoryFields(&o)...)...); err != nil {
+			return nil, eris.Wrap(err, "find categories by cake ids, an error occurred")
+		}
+		res[cakeID] = append(res[cakeID], o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, eris.Wrap(err, "find categories by cake ids, an error occurred")
+	}
+
+	return res, nil
+}
+
+func (s *Category) Insert(ctx context.Context, rec *schema.Category) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	query := "INSERT INTO categories (parent_id, name, slug, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, conn(ctx, s.DB), query, rec.ParentID, rec.Name, rec.Slug, rec.CreatedAt, rec.UpdatedAt)
+	if err != nil {
+		if isDuplicate(err) {
+			return ErrDuplicateRecord
+		}
+		return eris.Wrap(err, "insert category, an error occurred")
+	}
+	rec.ID = int(id)
+
+	return nil
+}
+
+func (s *Category) Update(ctx context.Context, rec *schema.Category) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+	if rec.ID <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "UPDATE categories SET parent_id=?, name=?, slug=?, updated_at=? WHERE id = ?"
+	log.Print(query)
+
+	if _, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), rec.ParentID, rec.Name, rec.Slug, rec.UpdatedAt, rec.ID); err != nil {
+		if isDuplicate(err) {
+			return ErrDuplicateRecord
+		}
+		return eris.Wrap(err, "update category, an error occurred")
+	}
+
+	return nil
+}
+
+// Delete delete a category, it's children become a root categories
+func (s *Category) Delete(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM categories WHERE id = ?"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), id)
+	if err != nil {
+		return eris.Wrap(err, "delete category, an error occurred")
+	}
+
+	return affected(res)
+}
+
+// SetCakeCategories replace a categories of a cake
+func (s *Category) SetCakeCategories(ctx context.Context, cakeID int, ids []int) error {
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "set cake categories, an error occurred")
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM cake_categories WHERE cake_id = ?"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), cakeID); err != nil {
+		return eris.Wrap(err, "set cake categories, an error occurred")
+	}
+
+	if len(ids) > 0 {
+		var (
+			values = make([]string, len(ids))
+			args   = make([]any, 0, len(ids)*2)
+		)
+		for i, id := range ids {
+			values[i] = "(?, ?)"
+			args = append(args, cakeID, id)
+		}
+
+		query = "INSERT INTO cake_categories (cake_id, category_id) VALUES " + strings.Join(values, ", ")
+		log.Print(query)
+
+		if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), args...); err != nil {
+			return eris.Wrap(err, "set cake categories, an error occurred")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "set cake categories, an error occurred")
+	}
+
+	return nil
+}
+
+// categoryFields return a scan destinations of categories columns in a table order
+func categoryFields(c *schema.Category) []any {
+	return []any{
+		&c.ID,
+		&c.ParentID,
+		&c.Name,
+		&c.Slug,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	}
+}
+
+func (s *Category) retrieveRows(rows *sql.Rows, res *[]schema.Category) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.Category
+		if err := rows.Scan(categoryFields(&o)...); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CategoryMock struct {
+	mock.Mock
+}
+
+func (m *CategoryMock) Find(ctx context.Context, id int) (*schema.Category, error) {
+	args := m.Called(ctx, id)
+	res, _ := args.Get(0).(*schema.Category)
+	return res, args.Error(1)
+}
+
+func (m *CategoryMock) FindAll(ctx context.Context) ([]schema.Category, error) {
+	args := m.Called(ctx)
+	res, _ := args.Get(0).([]schema.Category)
+	return res, args.Error(1)
+}
+
+func (m *CategoryMock) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.Category, error) {
+	args := m.Called(ctx, ids)
+	res, _ := args.Get(0).(map[int][]schema.Category)
+	return res, args.Error(1)
+}
+
+func (m *CategoryMock) Insert(ctx context.Context, rec *schema.Category) error {
+	rec.CreatedAt = time.Time{}
+	rec.UpdatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *CategoryMock) Update(ctx context.Context, rec *schema.Category) error {
+	rec.CreatedAt = time.Time{}
+	rec.UpdatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *CategoryMock) Delete(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *CategoryMock) SetCakeCategories(ctx context.Context, cakeID int, ids []int) error {
+	args := m.Called(ctx, cakeID, ids)
+	return args.Error(0)
+}
@@ -0,0 +1,151 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var categoryColumns = []string{
+	"id",
+	"parent_id",
+	"name",
+	"slug",
+	"created_at",
+	"updated_at",
+}
+
+var parentID = 1
+
+var category = schema.Category{
+	ID:        2,
+	ParentID:  &parentID,
+	Name:      "Kids",
+	Slug:      "kids",
+	CreatedAt: time.Now(),
+	UpdatedAt: time.Now(),
+}
+
+func Test_Category_Repository_Find(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(categoryColumns).AddRow(
+		category.ID,
+		category.ParentID,
+		category.Name,
+		category.Slug,
+		category.CreatedAt,
+		category.UpdatedAt,
+	)
+	mock.ExpectQuery("SELECT * FROM categories WHERE id = ? LIMIT 1").WithArgs(category.ID).WillReturnRows(rows)
+
+	repo := &repository.Category{DB: db}
+	res, err := repo.Find(context.Background(), category.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, parentID, *res.ParentID)
+}
+
+func Test_Category_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(categoryColumns).
+		AddRow(1, nil, "Birthday", "birthday", category.CreatedAt, category.UpdatedAt).
+		AddRow(category.ID, category.ParentID, category.Name, category.Slug, category.CreatedAt, category.UpdatedAt)
+	mock.ExpectQuery("SELECT * FROM categories ORDER BY name ASC, id ASC").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT * FROM categories ORDER BY name ASC, id ASC").WillReturnRows(sqlmock.NewRows(categoryColumns))
+
+	repo := &repository.Category{DB: db}
+	res, err := repo.FindAll(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.Nil(t, res[0].ParentID)
+
+	res, err = repo.FindAll(context.Background())
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Category_Repository_Find_By_Cake_IDs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(append([]string{"cake_id"}, categoryColumns...)).
+		AddRow(1, category.ID, category.ParentID, category.Name, category.Slug, category.CreatedAt, category.UpdatedAt).
+		AddRow(2, category.ID, category.ParentID, category.Name, category.Slug, category.CreatedAt, category.UpdatedAt)
+	mock.ExpectQuery("SELECT cc.cake_id, c.* FROM cake_categories cc JOIN categories c ON c.id = cc.category_id WHERE cc.cake_id IN (?, ?) ORDER BY c.name ASC, c.id ASC").
+		WithArgs(1, 2).WillReturnRows(rows)
+
+	repo := &repository.Category{DB: db}
+	res, err := repo.FindByCakeIDs(context.Background(), []int{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, res[1], 1)
+	assert.Len(t, res[2], 1)
+}
+
+func Test_Category_Repository_Insert(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	query := "INSERT INTO categories (parent_id, name, slug, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
+	mock.ExpectExec(query).
+		WithArgs(category.ParentID, category.Name, category.Slug, category.CreatedAt, category.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec(query).
+		WithArgs(category.ParentID, category.Name, category.Slug, category.CreatedAt, category.UpdatedAt).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+	repo := &repository.Category{DB: db}
+	rec := category
+	assert.NoError(t, repo.Insert(context.Background(), &rec))
+	assert.Equal(t, 3, rec.ID)
+
+	assert.ErrorIs(t, repo.Insert(context.Background(), &rec), repository.ErrDuplicateRecord)
+}
+
+func Test_Category_Repository_Update(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE categories SET parent_id=?, name=?, slug=?, updated_at=? WHERE id = ?").
+		WithArgs(category.ParentID, category.Name, category.Slug, category.UpdatedAt, category.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &repository.Category{DB: db}
+	err := repo.Update(context.Background(), &category)
+	assert.NoError(t, err)
+}
+
+func Test_Category_Repository_Delete(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM categories WHERE id = ?").WithArgs(category.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM categories WHERE id = ?").WithArgs(category.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &repository.Category{DB: db}
+	assert.NoError(t, repo.Delete(context.Background(), category.ID))
+	assert.ErrorIs(t, repo.Delete(context.Background(), category.ID), repository.ErrRecordNotFound)
+}
+
+func Test_Category_Repository_Set_Cake_Categories(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO cake_categories (cake_id, category_id) VALUES (?, ?), (?, ?)").
+		WithArgs(1, 1, 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := &repository.Category{DB: db}
+	assert.NoError(t, repo.SetCakeCategories(context.Background(), 1, []int{1, 2}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/zufzuf/cake-store/libs/util"
+)
+
+// Dialect is a SQL flavour of a database, a repository use it on a statement which is
+// written differently between a databases. the zero value is DialectMySQL
+type Dialect string
+
+const (
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+func (d Dialect) isMySQL() bool {
+	return d == "" || d == DialectMySQL
+}
+
+// rebind convert a ? placeholders of a query into the dialect placeholders
+func (d Dialect) rebind(query string) string {
+	if d == DialectPostgres {
+		return util.Rebind(query)
+	}
+	return query
+}
+
+// execer is a database or a transaction to run a statement on
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insert run an insert statement and return an id of the inserted row. Postgres does not
+// support LastInsertId, so the id is returned by the statement itself
+func (d Dialect) insert(ctx context.Context, db execer, query string, args ...any) (int64, error) {
+	var id int64
+	if d == DialectPostgres {
+		err := db.QueryRowContext(ctx, d.rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// flag convert a boolean argument of a TINYINT(1) column, Postgres does not convert a boolean into a number
+func (d Dialect) flag(v bool) any {
+	if d != DialectPostgres {
+		return v
+	}
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// forUpdate return a row lock clause of a select statement. SQLite does not have a row lock,
+// a transaction on it hold the database write lock since it begin (see db.OpenSQLite)
+func (d Dialect) forUpdate() string {
+	if d == DialectSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// upsert return a conflict clause of an insert statement on a unique key, the columns are
+// replaced by the inserted values, or the existing row is kept when there is no column
+func (d Dialect) upsert(key string, cols ...string) string {
+	if d.isMySQL() {
+		if len(cols) == 0 {
+			return "ON DUPLICATE KEY UPDATE " + key + " = " + key
+		}
+
+		sets := make([]string, len(cols))
+		for i, col := range cols {
+			sets[i] = col + " = VALUES(" + col + ")"
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	}
+
+	if len(cols) == 0 {
+		return "ON CONFLICT (" + key + ") DO NOTHING"
+	}
+
+	sets := make([]string, len(cols))
+	for i, col := range cols {
+		sets[i] = col + " = excluded." + col
+	}
+	return "ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// searchTerm is a word of a full-text search, op is a boolean operator of the word:
+// '+' for a required word, '-' for an excluded word or zero
+type searchTerm struct {
+	op     byte
+	word   string
+	prefix bool
+}
+
+// searchTerms split a search into a lower case words, a boolean search support a required (+word),
+// an excluded (-word) and a prefix (word*) operators like MySQL
+func searchTerms(search, mode string) []searchTerm {
+	var res []searchTerm
+	for _, field := range strings.Fields(strings.ToLower(search)) {
+		op := byte(0)
+		if mode == SearchModeBoolean && (field[0] == '+' || field[0] == '-') {
+			op, field = field[0], field[1:]
+		}
+
+		prefix := mode == SearchModeBoolean && strings.HasSuffix(field, "*")
+		for _, word := range words(field) {
+			res = append(res, searchTerm{op: op, word: word, prefix: prefix})
+		}
+	}
+	return res
+}
+
+// booleanSearch rebuild a boolean search from it's searchTerms, an operator which is not supported
+// by searchTerms (e.g. a lone '+', '@' or an unbalanced '"') is dropped, so MySQL does not fail
+// with a syntax error on a malformed search
+func booleanSearch(search string) string {
+	var b strings.Builder
+	for i, t := range searchTerms(search, SearchModeBoolean) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if t.op != 0 {
+			b.WriteByte(t.op)
+		}
+		b.WriteString(t.word)
+		if t.prefix {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
+
+// substringMatch return a relevance expression of a database without a full-text index on cakes,
+// the score is a sum of the search words occurrences in the title and description, zero mean
+// the cake does not match. a word is matched as a substring, so a word is always a prefix search
+func substringMatch(search, mode string) (string, []any) {
+	const doc = "LOWER(title || ' ' || COALESCE(description, ''))"
+
+	var (
+		scores, conds       []string
+		scoreArgs, condArgs []any
+	)
+	for _, t := range searchTerms(search, mode) {
+		n := "(LENGTH(" + doc + ") - LENGTH(REPLACE(" + doc + ", ?, ''))) / LENGTH(?)"
+
+		switch t.op {
+		case '+':
+			conds, condArgs = append(conds, n+" > 0"), append(condArgs, t.word, t.word)
+		case '-':
+			conds, condArgs = append(conds, n+" = 0"), append(condArgs, t.word, t.word)
+			continue
+		}
+		scores, scoreArgs = append(scores, n), append(scoreArgs, t.word, t.word)
+	}
+
+	if len(scores) == 0 {
+		return "0", nil
+	}
+
+	score := "(" + strings.Join(scores, " + ") + ")"
+	if len(conds) == 0 {
+		return score, scoreArgs
+	}
+
+	return "(CASE WHEN " + strings.Join(conds, " AND ") + " THEN " + score + " ELSE 0 END)", append(condArgs, scoreArgs...)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/rotisserie/eris"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var (
+	ErrDuplicateRecord = eris.New("duplicate record")
+)
+
+// UnsupportedFilterError is returned by a repository which can not apply a filter, e.g. a filter on
+// a relation of CakeMemory, Key is a query parameter of the filter
+type UnsupportedFilterError struct {
+	Key string
+}
+
+func (e *UnsupportedFilterError) Error() string {
+	return e.Key + " filter is not supported by the storage"
+}
+
+// isDuplicate report a unique constraint violation
+func isDuplicate(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062
+	}
+
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	return false
+}
+
+// isDeadlock report a transaction which is aborted by a deadlock or a serialization failure,
+// it succeed when the whole transaction is retried
+func isDeadlock(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1213
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40P01" || pqErr.Code == "40001"
+	}
+
+	return false
+}
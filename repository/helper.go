@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// placeholders return n comma separated placeholders for an IN clause
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func intArgs(vals []int) []any {
+	args := make([]any, len(vals))
+	for i, v := range vals {
+		args[i] = v
+	}
+	return args
+}
+
+func stringArgs(vals []string) []any {
+	args := make([]any, len(vals))
+	for i, v := range vals {
+		args[i] = v
+	}
+	return args
+}
+
+// lockCake lock an active cake row in a transaction, so a concurrent writes which derive
+// a cake column from it's children (e.g. rating, image) are applied one by one
+func lockCake(ctx context.Context, tx execer, d Dialect, cakeID int) error {
+	query := "SELECT id FROM cakes WHERE id = ? AND deleted_at IS NULL" + d.forUpdate()
+	log.Print(query)
+
+	var id int
+	if err := tx.QueryRowContext(ctx, d.rebind(query), cakeID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return eris.Wrap(err, "lock cake, an error occurred")
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var (
+	ErrStatusConflict = eris.New("order status conflict")
+)
+
+type Order struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func (s *Order) Find(ctx context.Context, id int) (*schema.Order, error) {
+	if id <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM orders WHERE id = ? LIMIT 1"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), id)
+	if err != nil {
+		return nil, eris.Wrap(err, "find order by id, an error occurred")
+	}
+
+	res := []schema.Order{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find order by id, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	if err := s.loadLines(ctx, res); err != nil {
+		return nil, err
+	}
+
+	return &res[0], nil
+}
+
+type OrderFilter struct {
+	Status string
+	CakeID int
+
+	// CreatedAfter and CreatedBefore is an exclusive bounds, zero time mean unbounded
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// Limit is a page size, Before is an order id from a previous page, newest order come first
+	Limit  int
+	Before int
+}
+
+func (f *OrderFilter) IsValidStatus() bool {
+	return len(f.Status) > 0
+}
+
+func (f *OrderFilter) IsValidCakeID() bool {
+	return f.CakeID > 0
+}
+
+func (f *OrderFilter) IsValidCreatedAfter() bool {
+	return !f.CreatedAfter.IsZero()
+}
+
+func (f *OrderFilter) IsValidCreatedBefore() bool {
+	return !f.CreatedBefore.IsZero()
+}
+
+func (f *OrderFilter) IsValidBefore() bool {
+	return f.Before > 0
+}
+
+func (s *Order) FindAll(ctx context.Context, fil *OrderFilter) ([]schema.Order, error) {
+	if fil == nil {
+		return nil, ErrFilterNill
+	}
+
+	q := util.NewQuery()
+
+	if fil.IsValidStatus() {
+		q.Where("status = ?", fil.Status)
+	}
+
+	if fil.IsValidCakeID() {
+		q.Where("id IN (SELECT order_id FROM order_lines WHERE cake_id = ?)", fil.CakeID)
+	}
+
+	if fil.IsValidCreatedAfter() {
+		q.Where("created_at > ?", fil.CreatedAfter)
+	}
+
+	if fil.IsValidCreatedBefore() {
+		q.Where("created_at < ?", fil.CreatedBefore)
+	}
+
+	if fil.IsValidBefore() {
+		q.Where("id < ?", fil.Before)
+	}
+
+	where, args := q.Build()
+	query := "SELECT * FROM orders " + where + " ORDER BY id DESC LIMIT ?"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), append(args, NormalizeLimit(fil.Limit))...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find orders, an error occurred")
+	}
+
+	res := []schema.Order{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find orders, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	if err := s.loadLines(ctx, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// loadLines load a lines of each orders
+func (s *Order) loadLines(ctx context.Context, orders []schema.Order) error {
+	ids := make([]int, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+	}
+
+	query := "SELECT * FROM order_lines WHERE order_id IN (" + placeholders(len(ids)) + ") ORDER BY id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), intArgs(ids)...)
+	if err != nil {
+		return eris.Wrap(err, "find order lines, an error occurred")
+	}
+
+	lines := []schema.OrderLine{}
+	if err := s.retrieveLineRows(rows, &lines); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return eris.Wrap(err, "find order lines, an error occurred")
+	}
+
+	byOrder := map[int][]schema.OrderLine{}
+	for _, line := range lines {
+		byOrder[line.OrderID] = append(byOrder[line.OrderID], line)
+	}
+	for i := range orders {
+		orders[i].Lines = byOrder[orders[i].ID]
+	}
+
+	return nil
+}
+
+// Insert insert an order with it's lines in a transaction, ID of the order and each line is filled on success
+func (s *Order) Insert(ctx context.Context, rec *schema.Order) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "insert order, an error occurred")
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO orders (customer_name, customer_phone, note, status, total, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, tx, query, rec.CustomerName, rec.CustomerPhone, rec.Note, rec.Status, rec.Total, rec.Currency, rec.CreatedAt, rec.UpdatedAt)
+	if err != nil {
+		return eris.Wrap(err, "insert order, an error occurred")
+	}
+
+	query = "INSERT INTO order_lines (order_id, cake_id, cake_title, quantity, unit_price) VALUES (?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	lineIDs := make([]int, len(rec.Lines))
+	for i, line := range rec.Lines {
+		lineID, err := s.Dialect.insert(ctx, tx, query, id, line.CakeID, line.CakeTitle, line.Quantity, line.UnitPrice)
+		if err != nil {
+			return eris.Wrap(err, "insert order, an error occurred")
+		}
+		lineIDs[i] = int(lineID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "insert order, an error occurred")
+	}
+
+	rec.ID = int(id)
+	for i := range rec.Lines {
+		rec.Lines[i].ID, rec.Lines[i].OrderID = lineIDs[i], rec.ID
+	}
+
+	return nil
+}
+
+// UpdateStatus move an order from a status into another one, the update is guarded by the current status
+// so a concurrent transitions can not both succeed, ErrStatusConflict is returned when the status has changed
+func (s *Order) UpdateStatus(ctx context.Context, id int, from, to string, updatedAt time.Time) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "UPDATE orders SET status = ?, updated_at = ? WHERE id = ? AND status = ?"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), to, updatedAt, id, from)
+	if err != nil {
+		return eris.Wrap(err, "update order status, an error occurred")
+	}
+
+	if err := affected(res); err != nil {
+		if eris.Is(err, ErrRecordNotFound) {
+			return ErrStatusConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Order) retrieveRows(rows *sql.Rows, res *[]schema.Order) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.Order
+		if err := rows.Scan(
+			&o.ID,
+			&o.CustomerName,
+			&o.CustomerPhone,
+			&o.Note,
+			&o.Status,
+			&o.Total,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+			&o.Currency,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Order) retrieveLineRows(rows *sql.Rows, res *[]schema.OrderLine) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.OrderLine
+		if err := rows.Scan(
+			&o.ID,
+			&o.OrderID,
+			&o.CakeID,
+			&o.CakeTitle,
+			&o.Quantity,
+			&o.UnitPrice,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type OrderMock struct {
+	mock.Mock
+}
+
+func (m *OrderMock) Find(ctx context.Context, id int) (*schema.Order, error) {
+	args := m.Called(ctx, id)
+	res, _ := args.Get(0).(*schema.Order)
+	return res, args.Error(1)
+}
+
+func (m *OrderMock) FindAll(ctx context.Context, fil *OrderFilter) ([]schema.Order, error) {
+	args := m.Called(ctx, fil)
+	res, _ := args.Get(0).([]schema.Order)
+	return res, args.Error(1)
+}
+
+func (m *OrderMock) Insert(ctx context.Context, rec *schema.Order) error {
+	rec.CreatedAt = time.Time{}
+	rec.UpdatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *OrderMock) UpdateStatus(ctx context.Context, id int, from, to string, updatedAt time.Time) error {
+	args := m.Called(ctx, id, from, to)
+	return args.Error(0)
+}
@@ -0,0 +1,149 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var orderColumns = []string{
+	"id",
+	"customer_name",
+	"customer_phone",
+	"note",
+	"status",
+	"total",
+	"created_at",
+	"updated_at",
+	"currency",
+}
+
+var orderLineColumns = []string{
+	"id",
+	"order_id",
+	"cake_id",
+	"cake_title",
+	"quantity",
+	"unit_price",
+}
+
+var order = schema.Order{
+	ID:            1,
+	CustomerName:  "Budi",
+	CustomerPhone: "08123456789",
+	Status:        schema.OrderStatusPending,
+	Total:         300000,
+	Currency:      "IDR",
+	Lines: []schema.OrderLine{
+		{ID: 1, OrderID: 1, CakeID: 1, CakeTitle: "Lemon cheesecake", Quantity: 2, UnitPrice: 150000},
+	},
+	CreatedAt: time.Now(),
+	UpdatedAt: time.Now(),
+}
+
+func orderRows() *sqlmock.Rows {
+	return sqlmock.NewRows(orderColumns).AddRow(
+		order.ID,
+		order.CustomerName,
+		order.CustomerPhone,
+		order.Note,
+		order.Status,
+		order.Total,
+		order.CreatedAt,
+		order.UpdatedAt,
+		order.Currency,
+	)
+}
+
+func orderLineRows() *sqlmock.Rows {
+	rows := sqlmock.NewRows(orderLineColumns)
+	for _, l := range order.Lines {
+		rows.AddRow(l.ID, l.OrderID, l.CakeID, l.CakeTitle, l.Quantity, l.UnitPrice)
+	}
+	return rows
+}
+
+func Test_Order_Repository_Find(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT * FROM orders WHERE id = ? LIMIT 1").WithArgs(order.ID).WillReturnRows(orderRows())
+	mock.ExpectQuery("SELECT * FROM order_lines WHERE order_id IN (?) ORDER BY id ASC").WithArgs(order.ID).WillReturnRows(orderLineRows())
+	mock.ExpectQuery("SELECT * FROM orders WHERE id = ? LIMIT 1").WithArgs(2).WillReturnRows(sqlmock.NewRows(orderColumns))
+
+	repo := &repository.Order{DB: db}
+	res, err := repo.Find(context.Background(), order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &order, res)
+
+	res, err = repo.Find(context.Background(), 2)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Order_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	after := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT * FROM orders WHERE status = ? AND id IN (SELECT order_id FROM order_lines WHERE cake_id = ?) AND created_at > ? AND id < ? ORDER BY id DESC LIMIT ?").
+		WithArgs(schema.OrderStatusPending, 1, after, 10, 5).
+		WillReturnRows(orderRows())
+	mock.ExpectQuery("SELECT * FROM order_lines WHERE order_id IN (?) ORDER BY id ASC").WithArgs(order.ID).WillReturnRows(orderLineRows())
+
+	repo := &repository.Order{DB: db}
+	res, err := repo.FindAll(context.Background(), &repository.OrderFilter{
+		Status:       schema.OrderStatusPending,
+		CakeID:       1,
+		CreatedAfter: after,
+		Limit:        5,
+		Before:       10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.Order{order}, res)
+}
+
+func Test_Order_Repository_Insert(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rec := order
+	rec.ID, rec.Lines = 0, []schema.OrderLine{{CakeID: 1, CakeTitle: "Lemon cheesecake", Quantity: 2, UnitPrice: 150000}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO orders (customer_name, customer_phone, note, status, total, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)").
+		WithArgs(rec.CustomerName, rec.CustomerPhone, rec.Note, rec.Status, rec.Total, rec.Currency, rec.CreatedAt, rec.UpdatedAt).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("INSERT INTO order_lines (order_id, cake_id, cake_title, quantity, unit_price) VALUES (?, ?, ?, ?, ?)").
+		WithArgs(5, 1, "Lemon cheesecake", 2, int64(150000)).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectCommit()
+
+	repo := &repository.Order{DB: db}
+	assert.NoError(t, repo.Insert(context.Background(), &rec))
+	assert.Equal(t, 5, rec.ID)
+	assert.Equal(t, schema.OrderLine{ID: 8, OrderID: 5, CakeID: 1, CakeTitle: "Lemon cheesecake", Quantity: 2, UnitPrice: 150000}, rec.Lines[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Order_Repository_Update_Status(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	now := time.Now()
+	query := "UPDATE orders SET status = ?, updated_at = ? WHERE id = ? AND status = ?"
+	mock.ExpectExec(query).WithArgs(schema.OrderStatusConfirmed, now, order.ID, schema.OrderStatusPending).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(schema.OrderStatusConfirmed, now, order.ID, schema.OrderStatusPending).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := &repository.Order{DB: db}
+	err := repo.UpdateStatus(context.Background(), order.ID, schema.OrderStatusPending, schema.OrderStatusConfirmed, now)
+	assert.NoError(t, err)
+
+	err = repo.UpdateStatus(context.Background(), order.ID, schema.OrderStatusPending, schema.OrderStatusConfirmed, now)
+	assert.ErrorIs(t, err, repository.ErrStatusConflict)
+}
@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = eris.New("invalid cursor")
+	ErrInvalidSort   = eris.New("invalid sort")
+)
+
+// NormalizeLimit clamp a requested page size into 1..MaxLimit,
+// zero or negative value fallback to DefaultLimit
+func NormalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
+
+// sortColumn is a column which take a part on keyset pagination,
+// value is used to pick a cursor value from the last record on a page
+// and decode is used to convert a cursor value back into a query argument.
+// Column can be an expression with placeholders, Args is bound on each occurrence of it
+// and Alias is used on ORDER BY instead of the expression
+type sortColumn struct {
+	Column string
+	Args   []any
+	Alias  string
+	Desc   bool
+	value  func(c *schema.Cake) any
+	decode func(raw json.RawMessage) (any, error)
+}
+
+func (c sortColumn) order() string {
+	col := c.Column
+	if len(c.Alias) > 0 {
+		col = c.Alias
+	}
+
+	if c.Desc {
+		return col + " DESC"
+	}
+	return col + " ASC"
+}
+
+func (c sortColumn) operator() string {
+	if c.Desc {
+		return "<"
+	}
+	return ">"
+}
+
+func decodeString(raw json.RawMessage) (any, error) {
+	var v string
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+func decodeFloat(raw json.RawMessage) (any, error) {
+	var v float64
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+func decodeInt(raw json.RawMessage) (any, error) {
+	var v int
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+func decodeInt64(raw json.RawMessage) (any, error) {
+	var v int64
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+func decodeTime(raw json.RawMessage) (any, error) {
+	var v time.Time
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+var (
+	cakeSortTitle = sortColumn{
+		Column: "title",
+		value:  func(c *schema.Cake) any { return c.Title },
+		decode: decodeString,
+	}
+	cakeSortRating = sortColumn{
+		Column: "rating",
+		value:  func(c *schema.Cake) any { return c.Rating },
+		decode: decodeFloat,
+	}
+	cakeSortPrice = sortColumn{
+		Column: "price",
+		value:  func(c *schema.Cake) any { return c.Price },
+		decode: decodeInt64,
+	}
+	// cakeSortID is always appended as the last sort column, as a unique tie breaker
+	// the keyset stays stable when a new rows are inserted between page fetches
+	cakeSortID = sortColumn{
+		Column: "id",
+		value:  func(c *schema.Cake) any { return c.ID },
+		decode: decodeInt,
+	}
+
+	cakeSortCreatedAt = sortColumn{
+		Column: "created_at",
+		value:  func(c *schema.Cake) any { return c.CreatedAt },
+		decode: decodeTime,
+	}
+	cakeSortUpdatedAt = sortColumn{
+		Column: "updated_at",
+		value:  func(c *schema.Cake) any { return c.UpdatedAt },
+		decode: decodeTime,
+	}
+
+	// cakeSortColumns is a whitelist of sort key which client can use,
+	// a key is mapped into a fixed column so user input never reach the query
+	cakeSortColumns = map[string]sortColumn{
+		"id":         cakeSortID,
+		"title":      cakeSortTitle,
+		"rating":     cakeSortRating,
+		"price":      cakeSortPrice,
+		"created_at": cakeSortCreatedAt,
+		"updated_at": cakeSortUpdatedAt,
+		"relevance":  cakeSortRelevance,
+	}
+
+	// cakeSortRelevance is only available on a full-text search, the column is resolved by cakeSort
+	cakeSortRelevance = sortColumn{
+		Alias: "score",
+		value: func(c *schema.Cake) any {
+			if c.Score == nil {
+				return 0
+			}
+			return *c.Score
+		},
+		decode: decodeFloat,
+	}
+
+	CakeSortKeys = []string{"id", "title", "rating", "price", "created_at", "updated_at", "relevance"}
+
+	CakeDefaultSort = []SortField{{Key: "title"}, {Key: "rating"}}
+
+	// CakeSearchDefaultSort is used on a full-text search, most relevant first
+	CakeSearchDefaultSort = []SortField{{Key: "relevance", Desc: true}}
+)
+
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+func (f SortField) String() string {
+	if f.Desc {
+		return "-" + f.Key
+	}
+	return f.Key
+}
+
+// ParseSort parse a comma separated sort keys, prefix a key with `-` for descending order,
+// e.g. `-rating,title,created_at`
+func ParseSort(val string) ([]SortField, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return nil, nil
+	}
+
+	var (
+		keys   = strings.Split(val, ",")
+		fields = make([]SortField, 0, len(keys))
+		seen   = map[string]bool{}
+	)
+
+	for _, key := range keys {
+		field := SortField{Key: strings.TrimSpace(key)}
+		if strings.HasPrefix(field.Key, "-") {
+			field.Key, field.Desc = field.Key[1:], true
+		} else {
+			field.Key = strings.TrimPrefix(field.Key, "+")
+		}
+
+		if _, ok := cakeSortColumns[field.Key]; !ok || seen[field.Key] {
+			return nil, ErrInvalidSort
+		}
+		seen[field.Key] = true
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func joinSort(fields []SortField) string {
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = f.String()
+	}
+	return strings.Join(keys, ",")
+}
+
+// cakeSort resolve a sort fields into a sort columns, id is appended as a tie breaker
+// when the fields does not contain it yet. relevance is resolved into a match expression
+// and rejected when it's not a full-text search
+func cakeSort(fields []SortField, match string, matchArgs ...any) ([]sortColumn, error) {
+	var (
+		cols  = make([]sortColumn, 0, len(fields)+1)
+		hasID = false
+	)
+
+	for _, f := range fields {
+		col, ok := cakeSortColumns[f.Key]
+		if !ok {
+			return nil, ErrInvalidSort
+		}
+		if f.Key == "relevance" {
+			if len(match) == 0 {
+				return nil, ErrInvalidSort
+			}
+			col.Column, col.Args = match, matchArgs
+		}
+		col.Desc = f.Desc
+		cols = append(cols, col)
+
+		hasID = hasID || f.Key == "id"
+	}
+
+	if !hasID {
+		cols = append(cols, cakeSortID)
+	}
+
+	return cols, nil
+}
+
+type cursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+func encodeCursor(sort string, cols []sortColumn, c *schema.Cake) (string, error) {
+	cur := cursor{Sort: sort, Values: make([]json.RawMessage, len(cols))}
+	for i, col := range cols {
+		raw, err := json.Marshal(col.value(c))
+		if err != nil {
+			return "", eris.Wrap(err, "encode cursor, an error occurred")
+		}
+		cur.Values[i] = raw
+	}
+
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return "", eris.Wrap(err, "encode cursor, an error occurred")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor decode a cursor values, a cursor which taken from a different sort is rejected
+func decodeCursor(sort string, cols []sortColumn, val string) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cur := cursor{}
+	if err := json.Unmarshal(b, &cur); err != nil || cur.Sort != sort || len(cur.Values) != len(cols) {
+		return nil, ErrInvalidCursor
+	}
+
+	vals := make([]any, len(cols))
+	for i, col := range cols {
+		v, err := col.decode(cur.Values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		vals[i] = v
+	}
+
+	return vals, nil
+}
+
+// keysetQuery build a predicate to seek after the cursor values, e.g. for (a ASC, b DESC) :
+// (a > ? OR (a = ? AND b < ?))
+func keysetQuery(cols []sortColumn, vals []any) (string, []any) {
+	var (
+		ors  = make([]string, len(cols))
+		args = []any{}
+	)
+
+	for i, col := range cols {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, cols[j].Column+" = ?")
+			args = append(append(args, cols[j].Args...), vals[j])
+		}
+		ands = append(ands, col.Column+" "+col.operator()+" ?")
+		args = append(append(args, col.Args...), vals[i])
+
+		ors[i] = strings.Join(ands, " AND ")
+		if i > 0 {
+			ors[i] = "(" + ors[i] + ")"
+		}
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func orderBy(cols []sortColumn) string {
+	orders := make([]string, len(cols))
+	for i, col := range cols {
+		orders[i] = col.order()
+	}
+	return "ORDER BY " + strings.Join(orders, ", ")
+}
@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+const (
+	// RatingPriorMean and RatingPriorWeight is a prior of the bayesian average rating,
+	// a cake with a few reviews is pulled toward the prior mean as if it had RatingPriorWeight reviews of it
+	RatingPriorMean   = 3.0
+	RatingPriorWeight = 5
+)
+
+type Review struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+type ReviewFilter struct {
+	CakeID int
+	Score  int
+
+	// Limit is a page size, Before is a review id from a previous page, newest review come first
+	Limit  int
+	Before int
+}
+
+func (f *ReviewFilter) IsValidScore() bool {
+	return f.Score > 0
+}
+
+func (f *ReviewFilter) IsValidBefore() bool {
+	return f.Before > 0
+}
+
+func (s *Review) FindAll(ctx context.Context, fil *ReviewFilter) ([]schema.Review, error) {
+	if fil == nil {
+		return nil, ErrFilterNill
+	}
+
+	q := util.NewQuery()
+	q.Where("cake_id = ?", fil.CakeID)
+
+	if fil.IsValidScore() {
+		q.Where("score = ?", fil.Score)
+	}
+
+	if fil.IsValidBefore() {
+		q.Where("id < ?", fil.Before)
+	}
+
+	where, args := q.Build()
+	query := "SELECT * FROM reviews " + where + " ORDER BY id DESC LIMIT ?"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), append(args, NormalizeLimit(fil.Limit))...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find reviews, an error occurred")
+	}
+
+	res := []schema.Review{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find reviews, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+// Stars count a reviews of a cake by it's score
+func (s *Review) Stars(ctx context.Context, cakeID int) (map[int]int, error) {
+	query := "SELECT score, COUNT(*) FROM reviews WHERE cake_id = ? GROUP BY score"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "count review stars, an error occurred")
+	}
+	defer rows.Close()
+
+	res := map[int]int{}
+	for rows.Next() {
+		var score, count int
+		if err := rows.Scan(&score, &count); err != nil {
+			return nil, eris.Wrap(err, "count review stars, an error occurred")
+		}
+		res[score] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, eris.Wrap(err, "count review stars, an error occurred")
+	}
+
+	return res, nil
+}
+
+// Insert insert a review of an active cake and recompute the cake rating in a transaction
+func (s *Review) Insert(ctx context.Context, rec *schema.Review) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "insert review, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := lockCake(ctx, tx, s.Dialect, rec.CakeID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO reviews (cake_id, author, score, body, created_at) VALUES (?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, tx, query, rec.CakeID, rec.Author, rec.Score, rec.Body, rec.CreatedAt)
+	if err != nil {
+		return eris.Wrap(err, "insert review, an error occurred")
+	}
+
+	if err := s.rate(ctx, tx, rec.CakeID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "insert review, an error occurred")
+	}
+	rec.ID = int(id)
+
+	return nil
+}
+
+// Delete delete a review of an active cake and recompute the cake rating in a transaction
+func (s *Review) Delete(ctx context.Context, cakeID, id int) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "delete review, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := lockCake(ctx, tx, s.Dialect, cakeID); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM reviews WHERE id = ? AND cake_id = ?"
+	log.Print(query)
+
+	res, err := tx.ExecContext(ctx, s.Dialect.rebind(query), id, cakeID)
+	if err != nil {
+		return eris.Wrap(err, "delete review, an error occurred")
+	}
+
+	if err := affected(res); err != nil {
+		return err
+	}
+
+	if err := s.rate(ctx, tx, cakeID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "delete review, an error occurred")
+	}
+
+	return nil
+}
+
+// rate recompute a bayesian average rating of a cake from it's reviews, a cake without any review has zero rating.
+// a rating is a part of a cake, so it's version and updated_at is bumped and it's ETag change
+func (s *Review) rate(ctx context.Context, tx execer, cakeID int) error {
+	prior := "?"
+	if s.Dialect == DialectPostgres {
+		// Postgres can not infer a type of a multiplied placeholders, and an integer division truncate the rating
+		prior = "CAST(? AS NUMERIC)"
+	}
+
+	query := "UPDATE cakes SET rating = (" +
+		"SELECT CASE WHEN COUNT(*) = 0 THEN 0 ELSE (" + prior + " * ? + SUM(score)) / (? + COUNT(*)) END " +
+		"FROM reviews WHERE cake_id = ?" +
+		"), updated_at = ?, version = version + 1 WHERE id = ?"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), RatingPriorWeight, RatingPriorMean, RatingPriorWeight, cakeID, time.Now(), cakeID); err != nil {
+		return eris.Wrap(err, "rate cake, an error occurred")
+	}
+
+	return nil
+}
+
+func (s *Review) retrieveRows(rows *sql.Rows, res *[]schema.Review) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var (
+			o    schema.Review
+			body sql.NullString
+		)
+		if err := rows.Scan(
+			&o.ID,
+			&o.CakeID,
+			&o.Author,
+			&o.Score,
+			&body,
+			&o.CreatedAt,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+		o.Body = body.String
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
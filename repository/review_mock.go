@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type ReviewMock struct {
+	mock.Mock
+}
+
+func (m *ReviewMock) FindAll(ctx context.Context, fil *ReviewFilter) ([]schema.Review, error) {
+	args := m.Called(ctx, fil)
+	res, _ := args.Get(0).([]schema.Review)
+	return res, args.Error(1)
+}
+
+func (m *ReviewMock) Stars(ctx context.Context, cakeID int) (map[int]int, error) {
+	args := m.Called(ctx, cakeID)
+	res, _ := args.Get(0).(map[int]int)
+	return res, args.Error(1)
+}
+
+func (m *ReviewMock) Insert(ctx context.Context, rec *schema.Review) error {
+	rec.CreatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *ReviewMock) Delete(ctx context.Context, cakeID, id int) error {
+	args := m.Called(ctx, cakeID, id)
+	return args.Error(0)
+}
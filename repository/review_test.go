@@ -0,0 +1,120 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var reviewColumns = []string{
+	"id",
+	"cake_id",
+	"author",
+	"score",
+	"body",
+	"created_at",
+}
+
+var review = schema.Review{
+	ID:        1,
+	CakeID:    1,
+	Author:    "Siti",
+	Score:     5,
+	Body:      "Soft and not too sweet",
+	CreatedAt: time.Now(),
+}
+
+const (
+	lockCakeQuery = "SELECT id FROM cakes WHERE id = ? AND deleted_at IS NULL FOR UPDATE"
+	rateCakeQuery = "UPDATE cakes SET rating = (SELECT CASE WHEN COUNT(*) = 0 THEN 0 ELSE (? * ? + SUM(score)) / (? + COUNT(*)) END FROM reviews WHERE cake_id = ?), updated_at = ?, version = version + 1 WHERE id = ?"
+)
+
+func Test_Review_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT * FROM reviews WHERE cake_id = ? AND score = ? AND id < ? ORDER BY id DESC LIMIT ?").
+		WithArgs(review.CakeID, 5, 10, repository.DefaultLimit).
+		WillReturnRows(sqlmock.NewRows(reviewColumns).
+			AddRow(review.ID, review.CakeID, review.Author, review.Score, review.Body, review.CreatedAt).
+			AddRow(2, review.CakeID, "Andi", 5, nil, review.CreatedAt))
+
+	repo := &repository.Review{DB: db}
+	res, err := repo.FindAll(context.Background(), &repository.ReviewFilter{CakeID: review.CakeID, Score: 5, Before: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, review, res[0])
+	assert.Equal(t, "", res[1].Body)
+}
+
+func Test_Review_Repository_Stars(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT score, COUNT(*) FROM reviews WHERE cake_id = ? GROUP BY score").
+		WithArgs(review.CakeID).
+		WillReturnRows(sqlmock.NewRows([]string{"score", "count"}).AddRow(5, 3).AddRow(2, 1))
+
+	repo := &repository.Review{DB: db}
+	res, err := repo.Stars(context.Background(), review.CakeID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{5: 3, 2: 1}, res)
+}
+
+func Test_Review_Repository_Insert(t *testing.T) {
+	t.Run("Rated", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(review.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(review.CakeID))
+		mock.ExpectExec("INSERT INTO reviews (cake_id, author, score, body, created_at) VALUES (?, ?, ?, ?, ?)").
+			WithArgs(review.CakeID, review.Author, review.Score, review.Body, review.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec(rateCakeQuery).
+			WithArgs(repository.RatingPriorWeight, repository.RatingPriorMean, repository.RatingPriorWeight, review.CakeID, sqlmock.AnyArg(), review.CakeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := &repository.Review{DB: db}
+		rec := review
+		assert.NoError(t, repo.Insert(context.Background(), &rec))
+		assert.Equal(t, 3, rec.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Cake_Not_Found", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(review.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		repo := &repository.Review{DB: db}
+		rec := review
+		assert.ErrorIs(t, repo.Insert(context.Background(), &rec), repository.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Review_Repository_Delete(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCakeQuery).WithArgs(review.CakeID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(review.CakeID))
+	mock.ExpectExec("DELETE FROM reviews WHERE id = ? AND cake_id = ?").WithArgs(review.ID, review.CakeID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(rateCakeQuery).
+		WithArgs(repository.RatingPriorWeight, repository.RatingPriorMean, repository.RatingPriorWeight, review.CakeID, sqlmock.AnyArg(), review.CakeID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := &repository.Review{DB: db}
+	assert.NoError(t, repo.Delete(context.Background(), review.CakeID, review.ID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,740 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/db"
+	"github.com/zufzuf/cake-store/fixtures"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/migrations"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/server"
+	"github.com/zufzuf/cake-store/service"
+)
+
+// sqlDatabase is a real SQL engine a repository is tested against instead of an expected queries,
+// Open return a new migrated database of a test
+type sqlDatabase struct {
+	Name       string
+	Dialect    repository.Dialect
+	Migrations migrations.Dialect
+	Open       func(t *testing.T) *sql.DB
+}
+
+// sqlDatabases list a databases to test on, SQLite is always tested and PostgreSQL is tested
+// when POSTGRES_TEST_DSN is set
+func sqlDatabases() []sqlDatabase {
+	res := []sqlDatabase{
+		{Name: "SQLite", Dialect: repository.DialectSQLite, Migrations: migrations.SQLite, Open: NewSQLite},
+	}
+	if len(os.Getenv("POSTGRES_TEST_DSN")) > 0 {
+		res = append(res, sqlDatabase{Name: "Postgres", Dialect: repository.DialectPostgres, Migrations: migrations.Postgres, Open: NewPostgres})
+	}
+	return res
+}
+
+// forEachDatabase run a test on a new database of each sqlDatabases
+func forEachDatabase(t *testing.T, test func(t *testing.T, conn *sql.DB, d repository.Dialect)) {
+	for _, database := range sqlDatabases() {
+		database := database
+		t.Run(database.Name, func(t *testing.T) {
+			test(t, database.Open(t), database.Dialect)
+		})
+	}
+}
+
+// NewSQLite open a migrated SQLite database in a temporary directory
+func NewSQLite(t *testing.T) *sql.DB {
+	conn, err := db.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "cake-store.db"))
+	if err != nil {
+		t.Fatalf("an error '%+v' was not expected when opening a sqlite database", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := migrations.Up(context.Background(), conn, migrations.SQLite); err != nil {
+		t.Fatalf("an error '%+v' was not expected when migrating a sqlite database", err)
+	}
+
+	return conn
+}
+
+// NewPostgres open a migrated PostgreSQL database on POSTGRES_TEST_DSN, each test get it's own schema
+// which is dropped when the test is done
+func NewPostgres(t *testing.T) *sql.DB {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("an error '%+v' was not expected when opening a postgres database", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	// the extension is shared by every schema, so it's created on the public schema before migrating
+	name := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	for _, stmt := range []string{"CREATE EXTENSION IF NOT EXISTS citext SCHEMA public", "CREATE SCHEMA " + name} {
+		if _, err := admin.Exec(stmt); err != nil {
+			t.Fatalf("an error '%+v' was not expected when preparing a postgres schema", err)
+		}
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + name + " CASCADE") })
+
+	conn, err := db.OpenPostgres(context.Background(), searchPath(dsn, name+",public"))
+	if err != nil {
+		t.Fatalf("an error '%+v' was not expected when opening a postgres database", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := migrations.Up(context.Background(), conn, migrations.Postgres); err != nil {
+		t.Fatalf("an error '%+v' was not expected when migrating a postgres database", err)
+	}
+
+	return conn
+}
+
+// searchPath set a search_path of a connection string, a parameter which is not a connection
+// setting is sent to the server as a run-time parameter
+func searchPath(dsn, path string) string {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + path
+	}
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "search_path=" + url.QueryEscape(path)
+}
+
+func newSQLCakes(t *testing.T, conn *sql.DB, d repository.Dialect) *repository.Cake {
+	repo := &repository.Cake{DB: conn, Dialect: d}
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, c := range []schema.Cake{
+		{Title: "Lemon cheesecake", Description: "A cheesecake made of lemon", Price: 150000, Currency: "IDR"},
+		{Title: "chocolate fudge", Description: "A rich chocolate cake", Price: 120000, Currency: "IDR"},
+		{Title: "Lemon tart", Description: "A tart with lemon curd and lemon zest", Price: 5, Currency: "USD"},
+		{Title: "Carrot cake", Description: "A spiced cake", Price: 90000, Currency: "IDR"},
+	} {
+		c.CreatedAt = base.AddDate(0, i, 0)
+		c.UpdatedAt = c.CreatedAt
+		assert.NoError(t, repo.Insert(context.Background(), &c))
+	}
+
+	return repo
+}
+
+func Test_SQL_Migrations(t *testing.T) {
+	for _, database := range sqlDatabases() {
+		database := database
+		t.Run(database.Name, func(t *testing.T) {
+			var (
+				conn = database.Open(t)
+				ctx  = context.Background()
+			)
+
+			// an up to date database is not migrated again
+			assert.NoError(t, migrations.Up(ctx, conn, database.Migrations))
+			assert.NoError(t, migrations.Check(ctx, conn))
+
+			version, dirty, err := migrations.Version(ctx, conn)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(14), version)
+			assert.False(t, dirty)
+
+			assert.NoError(t, migrations.Steps(ctx, conn, database.Migrations, -2))
+			version, _, _ = migrations.Version(ctx, conn)
+			assert.Equal(t, uint64(12), version)
+			assert.ErrorIs(t, migrations.Check(ctx, conn), migrations.ErrPending)
+
+			assert.NoError(t, migrations.Steps(ctx, conn, database.Migrations, 1))
+			version, _, _ = migrations.Version(ctx, conn)
+			assert.Equal(t, uint64(13), version)
+
+			// every down migration is reversible
+			assert.NoError(t, migrations.Goto(ctx, conn, database.Migrations, 0))
+			version, _, _ = migrations.Version(ctx, conn)
+			assert.Equal(t, uint64(0), version)
+			assert.ErrorIs(t, migrations.Goto(ctx, conn, database.Migrations, 99), migrations.ErrUnknownVersion)
+
+			assert.NoError(t, migrations.Goto(ctx, conn, database.Migrations, 14))
+			assert.NoError(t, migrations.Check(ctx, conn))
+		})
+	}
+}
+
+func Test_SQL_Migrations_Dirty(t *testing.T) {
+	for _, database := range sqlDatabases() {
+		database := database
+		t.Run(database.Name, func(t *testing.T) {
+			var (
+				conn = database.Open(t)
+				ctx  = context.Background()
+			)
+
+			// a failed migration leave it's version dirty, so it's not applied again until it's forced
+			assert.NoError(t, migrations.Goto(ctx, conn, database.Migrations, 13))
+			_, err := conn.Exec("CREATE TABLE cake_allergens (id INT)")
+			assert.NoError(t, err)
+
+			assert.Error(t, migrations.Up(ctx, conn, database.Migrations))
+			assert.ErrorIs(t, migrations.Check(ctx, conn), migrations.ErrDirty)
+			assert.ErrorIs(t, migrations.Up(ctx, conn, database.Migrations), migrations.ErrDirty)
+
+			_, err = conn.Exec("DROP TABLE cake_allergens")
+			assert.NoError(t, err)
+			assert.NoError(t, migrations.Force(ctx, conn, 13))
+			assert.NoError(t, migrations.Up(ctx, conn, database.Migrations))
+			assert.NoError(t, migrations.Check(ctx, conn))
+		})
+	}
+}
+
+func Test_SQL_Cake_Repository_Find_All(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo     = newSQLCakes(t, conn, d)
+			ctx      = context.Background()
+			priceMax = int64(130000)
+		)
+
+		assert.NoError(t, (&repository.Tag{DB: conn, Dialect: d}).SetCakeTags(ctx, 2, []string{"vegan"}))
+		assert.NoError(t, (&repository.Allergen{DB: conn, Dialect: d}).SetCakeAllergens(ctx, 4, []string{"nuts"}))
+		assert.NoError(t, (&repository.Stock{DB: conn, Dialect: d}).Move(ctx, &schema.StockMovement{
+			CakeID: 3, Kind: schema.StockMovementBaked, Quantity: 2, CreatedAt: time.Now(),
+		}))
+
+		tests := []struct {
+			Name   string
+			Filter repository.FindAllFilter
+			Result []string
+			Error  error
+		}{
+			{
+				Name:   "Default_Sort_Case_Insensitive",
+				Result: []string{"Carrot cake", "chocolate fudge", "Lemon cheesecake", "Lemon tart"},
+			},
+			{
+				Name:   "Title_Filter",
+				Filter: repository.FindAllFilter{Title: "LEMON"},
+				Result: []string{"Lemon cheesecake", "Lemon tart"},
+			},
+			{
+				Name:   "Range_Filter",
+				Filter: repository.FindAllFilter{Currency: "IDR", PriceMax: &priceMax},
+				Result: []string{"Carrot cake", "chocolate fudge"},
+			},
+			{
+				Name:   "Created_After",
+				Filter: repository.FindAllFilter{CreatedAfter: time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)},
+				Result: []string{"Carrot cake", "Lemon tart"},
+			},
+			{
+				Name:   "Search",
+				Filter: repository.FindAllFilter{Search: "lemon"},
+				Result: []string{"Lemon tart", "Lemon cheesecake"},
+			},
+			{
+				Name:   "Search_Boolean",
+				Filter: repository.FindAllFilter{Search: "+lemon -tart", SearchMode: repository.SearchModeBoolean},
+				Result: []string{"Lemon cheesecake"},
+			},
+			{
+				Name:   "Search_Boolean_Prefix",
+				Filter: repository.FindAllFilter{Search: "choc*", SearchMode: repository.SearchModeBoolean},
+				Result: []string{"chocolate fudge"},
+			},
+			{
+				Name:   "Tag_Filter",
+				Filter: repository.FindAllFilter{Tag: "Vegan"},
+				Result: []string{"chocolate fudge"},
+			},
+			{
+				Name:   "In_Stock_Filter",
+				Filter: repository.FindAllFilter{InStock: true},
+				Result: []string{"Lemon tart"},
+			},
+			{
+				Name:   "Exclude_Allergens",
+				Filter: repository.FindAllFilter{Title: "cake", ExcludeAllergens: []string{"nuts"}},
+				Result: []string{"Lemon cheesecake"},
+			},
+			{
+				Name:   "Not_Found",
+				Filter: repository.FindAllFilter{Search: "opera"},
+				Error:  repository.ErrRecordNotFound,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.Name, func(t *testing.T) {
+				res, err := repo.FindAll(ctx, &test.Filter)
+				if test.Error != nil {
+					assert.ErrorIs(t, err, test.Error)
+					assert.Nil(t, res)
+					return
+				}
+
+				assert.NoError(t, err)
+				assert.Equal(t, test.Result, titles(res))
+			})
+		}
+	})
+}
+
+func Test_SQL_Cake_Repository_Find_All_Pagination(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo = newSQLCakes(t, conn, d)
+			ctx  = context.Background()
+			fil  = repository.FindAllFilter{Search: "cake", Limit: 2}
+		)
+
+		res, err := repo.FindAll(ctx, &fil)
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.True(t, fil.HasMore)
+
+		seen := titles(res)
+		for fil.HasMore {
+			fil.Cursor = fil.NextCursor
+			res, err = repo.FindAll(ctx, &fil)
+			assert.NoError(t, err)
+			seen = append(seen, titles(res)...)
+		}
+		assert.ElementsMatch(t, []string{"Lemon cheesecake", "chocolate fudge", "Carrot cake"}, seen)
+	})
+}
+
+func Test_SQL_Cake_Repository_Lifecycle(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo = newSQLCakes(t, conn, d)
+			ctx  = context.Background()
+		)
+
+		res, err := repo.Find(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Lemon cheesecake", res.Title)
+		assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), res.CreatedAt.UTC())
+		assert.Equal(t, 1, res.Version)
+
+		rec := schema.Cake{ID: 1, Title: "Orange cheesecake", Currency: "IDR", Version: 1, UpdatedAt: time.Now()}
+		assert.NoError(t, repo.Update(ctx, &rec))
+		assert.Equal(t, 2, rec.Version)
+		assert.ErrorIs(t, repo.Update(ctx, &schema.Cake{ID: 1, Version: 1}), repository.ErrVersionConflict)
+
+		assert.NoError(t, repo.Delete(ctx, 1, 2))
+		_, err = repo.Find(ctx, 1)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+		assert.NoError(t, repo.Restore(ctx, 1))
+		assert.NoError(t, repo.Delete(ctx, 1, 0))
+		assert.NoError(t, repo.Purge(ctx, 1))
+		assert.ErrorIs(t, repo.Purge(ctx, 1), repository.ErrRecordNotFound)
+
+		assert.NoError(t, repo.Delete(ctx, 2, 0))
+		n, err := repo.PurgeTrashed(ctx, time.Now().Add(time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+}
+
+func Test_SQL_Relation_Repository(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			ctx = context.Background()
+			now = time.Now()
+		)
+		newSQLCakes(t, conn, d)
+
+		categories := &repository.Category{DB: conn, Dialect: d}
+		parent := schema.Category{Name: "Cakes", Slug: "cakes", CreatedAt: now, UpdatedAt: now}
+		assert.NoError(t, categories.Insert(ctx, &parent))
+		child := schema.Category{ParentID: &parent.ID, Name: "Cheesecakes", Slug: "cheesecakes", CreatedAt: now, UpdatedAt: now}
+		assert.NoError(t, categories.Insert(ctx, &child))
+		assert.ErrorIs(t, categories.Insert(ctx, &schema.Category{Name: "Copy", Slug: "CAKES"}), repository.ErrDuplicateRecord)
+		assert.NoError(t, categories.SetCakeCategories(ctx, 1, []int{child.ID}))
+
+		cakes, err := (&repository.Cake{DB: conn, Dialect: d}).FindAll(ctx, &repository.FindAllFilter{Category: "cakes"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Lemon cheesecake"}, titles(cakes))
+
+		variants := &repository.CakeVariant{DB: conn, Dialect: d}
+		variant := schema.CakeVariant{CakeID: 1, SKU: "LC-S", Name: "Small", Options: map[string]string{"size": "small"}, CreatedAt: now, UpdatedAt: now}
+		assert.NoError(t, variants.Insert(ctx, &variant))
+		assert.ErrorIs(t, variants.Insert(ctx, &schema.CakeVariant{CakeID: 2, SKU: "lc-s", Name: "Copy"}), repository.ErrDuplicateRecord)
+
+		found, err := variants.Find(ctx, 1, variant.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"size": "small"}, found.Options)
+
+		nutrition := &repository.Nutrition{DB: conn, Dialect: d}
+		assert.NoError(t, nutrition.SetCakeNutrition(ctx, 1, &schema.Nutrition{ServingSize: 100, Energy: 320.5}))
+		assert.NoError(t, nutrition.SetCakeNutrition(ctx, 1, &schema.Nutrition{ServingSize: 80, Energy: 256.4}))
+
+		facts, err := nutrition.FindByCakeIDs(ctx, []int{1, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, map[int]*schema.Nutrition{1: {ServingSize: 80, Energy: 256.4}}, facts)
+
+		// a purged cake cascade into it's relations
+		assert.NoError(t, (&repository.Cake{DB: conn, Dialect: d}).Delete(ctx, 1, 0))
+		assert.NoError(t, (&repository.Cake{DB: conn, Dialect: d}).Purge(ctx, 1))
+
+		facts, err = nutrition.FindByCakeIDs(ctx, []int{1})
+		assert.NoError(t, err)
+		assert.Empty(t, facts)
+
+		_, err = variants.FindAll(ctx, 1)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+	})
+}
+
+func Test_SQL_Stock_Repository(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo = &repository.Stock{DB: conn, Dialect: d}
+			ctx  = context.Background()
+			wg   sync.WaitGroup
+		)
+		newSQLCakes(t, conn, d)
+
+		// a concurrent movements are applied one by one by the SQLite write lock or the Postgres row lock
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.Move(ctx, &schema.StockMovement{CakeID: 1, Kind: schema.StockMovementBaked, Quantity: 3, CreatedAt: time.Now()}))
+			}()
+		}
+		wg.Wait()
+
+		mov := schema.StockMovement{CakeID: 1, Kind: schema.StockMovementSold, Quantity: -31, CreatedAt: time.Now()}
+		assert.ErrorIs(t, repo.Move(ctx, &mov), repository.ErrInsufficientStock)
+
+		mov.Quantity = -30
+		assert.NoError(t, repo.Move(ctx, &mov))
+		assert.Equal(t, 0, mov.Balance)
+
+		res, err := repo.Find(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, res.Quantity)
+
+		movements, err := repo.FindMovements(ctx, &repository.MovementFilter{CakeID: 1, Kind: schema.StockMovementBaked})
+		assert.NoError(t, err)
+		assert.Len(t, movements, 10)
+
+		// a stock of a trashed cake is not moved
+		assert.NoError(t, (&repository.Cake{DB: conn, Dialect: d}).Delete(ctx, 2, 0))
+		mov = schema.StockMovement{CakeID: 2, Kind: schema.StockMovementBaked, Quantity: 1, CreatedAt: time.Now()}
+		assert.ErrorIs(t, repo.Move(ctx, &mov), repository.ErrRecordNotFound)
+	})
+}
+
+func Test_SQL_Review_Repository(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo = &repository.Review{DB: conn, Dialect: d}
+			ctx  = context.Background()
+		)
+		cakes := newSQLCakes(t, conn, d)
+
+		for _, score := range []int{5, 5, 4} {
+			assert.NoError(t, repo.Insert(ctx, &schema.Review{CakeID: 1, Author: "Ana", Score: score, CreatedAt: time.Now()}))
+		}
+		assert.ErrorIs(t, repo.Insert(ctx, &schema.Review{CakeID: 9, Author: "Ana", Score: 5}), repository.ErrRecordNotFound)
+
+		cake, err := cakes.Find(ctx, 1)
+		assert.NoError(t, err)
+		assert.InDelta(t, (repository.RatingPriorWeight*repository.RatingPriorMean+14)/(repository.RatingPriorWeight+3), cake.Rating, 0.01)
+
+		// every rating change bump the version, so the ETag of the cake change
+		assert.Equal(t, 4, cake.Version)
+
+		stars, err := repo.Stars(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, stars[5])
+
+		assert.NoError(t, repo.Delete(ctx, 1, 1))
+		assert.ErrorIs(t, repo.Delete(ctx, 1, 1), repository.ErrRecordNotFound)
+
+		cake, err = cakes.Find(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, cake.Version)
+	})
+}
+
+func Test_SQL_Cake_Image_Repository(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo = &repository.CakeImage{DB: conn, Dialect: d}
+			ctx  = context.Background()
+		)
+		cakes := newSQLCakes(t, conn, d)
+
+		first := schema.CakeImage{CakeID: 1, Image: schema.Image{URL: "/files/first.png"}, CreatedAt: time.Now()}
+		second := schema.CakeImage{CakeID: 1, Image: schema.Image{URL: "/files/second.png"}, CreatedAt: time.Now()}
+		assert.NoError(t, repo.Insert(ctx, &first))
+		assert.NoError(t, repo.Insert(ctx, &second))
+		assert.True(t, first.Primary)
+		assert.False(t, second.Primary)
+
+		cake, err := cakes.Find(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "/files/first.png", cake.Image)
+		assert.Equal(t, 2, cake.Version)
+
+		// the next image is promoted when the primary image is removed
+		_, err = repo.Delete(ctx, 1, first.ID)
+		assert.NoError(t, err)
+
+		images, err := repo.FindAll(ctx, 1)
+		assert.NoError(t, err)
+		assert.Len(t, images, 1)
+		assert.True(t, images[0].Primary)
+
+		cake, _ = cakes.Find(ctx, 1)
+		assert.Equal(t, "/files/second.png", cake.Image)
+
+		_, err = repo.Delete(ctx, 1, second.ID)
+		assert.NoError(t, err)
+
+		cake, _ = cakes.Find(ctx, 1)
+		assert.Empty(t, cake.Image)
+		assert.Equal(t, 4, cake.Version)
+	})
+}
+
+func Test_SQL_Order_Repository(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			repo = &repository.Order{DB: conn, Dialect: d}
+			ctx  = context.Background()
+			now  = time.Now()
+		)
+		newSQLCakes(t, conn, d)
+
+		rec := schema.Order{
+			CustomerName: "Ana",
+			Status:       schema.OrderStatusPending,
+			Total:        240000,
+			Currency:     "IDR",
+			Lines:        []schema.OrderLine{{CakeID: 2, CakeTitle: "chocolate fudge", Quantity: 2, UnitPrice: 120000}},
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		assert.NoError(t, repo.Insert(ctx, &rec))
+
+		res, err := repo.Find(ctx, rec.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, rec.Lines, res.Lines)
+
+		assert.NoError(t, repo.UpdateStatus(ctx, rec.ID, schema.OrderStatusPending, schema.OrderStatusConfirmed, now))
+		assert.ErrorIs(t, repo.UpdateStatus(ctx, rec.ID, schema.OrderStatusPending, schema.OrderStatusCancelled, now), repository.ErrStatusConflict)
+
+		orders, err := repo.FindAll(ctx, &repository.OrderFilter{CakeID: 2, Status: schema.OrderStatusConfirmed})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 1)
+	})
+}
+
+func Test_SQL_Transactor(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			cakes  = newSQLCakes(t, conn, d)
+			tags   = &repository.Tag{DB: conn, Dialect: d}
+			tx     = &repository.Transactor{DB: conn}
+			ctx    = context.Background()
+			failed = errors.New("failed")
+		)
+
+		// every write of a failed unit of work is rolled back
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := cakes.Delete(ctx, 1, 0); err != nil {
+				return err
+			}
+			if err := tags.SetCakeTags(ctx, 1, []string{"seasonal"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		assert.ErrorIs(t, err, failed)
+
+		_, err = cakes.Find(ctx, 1)
+		assert.NoError(t, err)
+		_, err = tags.FindAll(ctx)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+		err = tx.WithinTx(ctx, func(ctx context.Context) error {
+			if _, err := cakes.Find(ctx, 1); err != nil {
+				return err
+			}
+			return tags.SetCakeTags(ctx, 1, []string{"seasonal"})
+		})
+		assert.NoError(t, err)
+
+		res, err := tags.FindByCakeIDs(ctx, []int{1})
+		assert.NoError(t, err)
+		assert.Len(t, res[1], 1)
+	})
+}
+
+func Test_SQL_Seed(t *testing.T) {
+	util.NewValidator()
+
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			cakes = newSQLCakes(t, conn, d)
+			srv   = server.CakeService(conn, d)
+			ctx   = context.Background()
+		)
+
+		reqs, err := fixtures.Default()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, reqs)
+
+		// "Lemon Cheesecake" and "Carrot Cake" exist already with another case
+		res, err := srv.Seed(ctx, reqs, false)
+		assert.NoError(t, err)
+		assert.Equal(t, &service.SeedResult{Inserted: len(reqs) - 2, Skipped: 2}, res)
+
+		res, err = srv.Seed(ctx, reqs, false)
+		assert.NoError(t, err)
+		assert.Equal(t, &service.SeedResult{Inserted: 0, Skipped: len(reqs)}, res)
+
+		revisions := &repository.CakeRevision{DB: conn, Dialect: d}
+		assert.NoError(t, revisions.Insert(ctx, &schema.CakeRevision{CakeID: 1, Action: schema.RevisionActionUpdate, CreatedAt: time.Now()}))
+
+		res, err = srv.Seed(ctx, reqs, true)
+		assert.NoError(t, err)
+		assert.Equal(t, &service.SeedResult{Inserted: len(reqs)}, res)
+
+		_, err = cakes.Find(ctx, 1)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+		// a revision of a deleted cake is deleted along with it
+		_, err = revisions.FindAll(ctx, 1)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+		all, err := srv.FindAll(ctx, &service.FindAllRequest{Title: "Lemon Cheesecake"})
+		assert.NoError(t, err)
+		if assert.Len(t, all, 1) {
+			assert.Equal(t, int64(250000), all[0].Price)
+			assert.Len(t, all[0].Tags, 2)
+			assert.Equal(t, []string{"dairy", "eggs", "gluten"}, all[0].Allergens)
+			assert.NotNil(t, all[0].Nutrition)
+		}
+	})
+}
+
+// sqlImportRows read a rows of an import from a slice
+type sqlImportRows []service.ImportRow
+
+func (r *sqlImportRows) Read() (*service.ImportRow, error) {
+	if len(*r) == 0 {
+		return nil, io.EOF
+	}
+
+	row := (*r)[0]
+	*r = (*r)[1:]
+	return &row, nil
+}
+
+func Test_SQL_Import(t *testing.T) {
+	util.NewValidator()
+
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			srv = server.CakeService(conn, d)
+			ctx = context.Background()
+		)
+
+		reqs, err := fixtures.Default()
+		assert.NoError(t, err)
+
+		rows := func(categoryID int) *sqlImportRows {
+			res := sqlImportRows{}
+			for i, req := range reqs {
+				res = append(res, service.ImportRow{Line: i + 2, Request: req})
+			}
+			res[len(res)-1].Request.CategoryIDs = []int{categoryID}
+			return &res
+		}
+
+		// the last row has an unknown category, so every rows is rolled back
+		res, err := srv.Import(ctx, rows(99), service.ImportModeAtomic)
+		assert.ErrorIs(t, err, service.ErrImportFailed)
+		assert.Equal(t, 1, res.Failed)
+
+		_, err = srv.FindAll(ctx, &service.FindAllRequest{})
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+		res, err = srv.Import(ctx, rows(99), service.ImportModeBestEffort)
+		assert.NoError(t, err)
+		assert.Equal(t, len(reqs)-1, res.Inserted)
+
+		all, err := srv.FindAll(ctx, &service.FindAllRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, all, len(reqs)-1)
+	})
+}
+
+func Test_SQL_Batch(t *testing.T) {
+	util.NewValidator()
+
+	forEachDatabase(t, func(t *testing.T, conn *sql.DB, d repository.Dialect) {
+		var (
+			srv = server.CakeService(conn, d)
+			ctx = context.Background()
+		)
+
+		reqs, err := fixtures.Default()
+		assert.NoError(t, err)
+
+		lemon := reqs[0]
+		assert.NoError(t, srv.Insert(ctx, &lemon))
+
+		ops := func(atomic bool) *service.BatchRequest {
+			carrot, unknown := reqs[2], reqs[3]
+			unknown.CategoryIDs = []int{99}
+			return &service.BatchRequest{Atomic: &atomic, Operations: []service.BatchOperation{
+				{Op: service.BatchOpDelete, ID: lemon.ID},
+				{Op: service.BatchOpCreate, Cake: &carrot},
+				{Op: service.BatchOpCreate, Cake: &unknown},
+			}}
+		}
+
+		// the last operation has an unknown category, so the delete and the create are rolled back
+		res, err := srv.Batch(ctx, ops(true))
+		assert.ErrorIs(t, err, service.ErrBatchFailed)
+		assert.Equal(t, 1, res.Failed)
+
+		all, err := srv.FindAll(ctx, &service.FindAllRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+		assert.Equal(t, lemon.ID, all[0].ID)
+
+		// only the failed operation is rolled back
+		res, err = srv.Batch(ctx, ops(false))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Equal(t, service.BatchStatusFailed, res.Results[2].Status)
+
+		all, err = srv.FindAll(ctx, &service.FindAllRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+		assert.Equal(t, reqs[2].Title, all[0].Title)
+	})
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var (
+	ErrInsufficientStock = eris.New("insufficient stock")
+)
+
+type Stock struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// Find find a stock of a cake, a cake which never had a movement has no stock record
+func (s *Stock) Find(ctx context.Context, cakeID int) (*schema.Stock, error) {
+	if cakeID <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM cake_stocks WHERE cake_id = ? LIMIT 1"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), cakeID)
+	if err != nil {
+		return nil, eris.Wrap(err, "find stock by cake id, an error occurred")
+	}
+
+	res := []schema.Stock{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find stock by cake id, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return &res[0], nil
+}
+
+type StockFilter struct {
+	// Below list a stocks which quantity is less than it, nil mean unbounded
+	Below *int
+}
+
+func (f *StockFilter) IsValidBelow() bool {
+	return f.Below != nil
+}
+
+// FindAll list a stock levels of an active cakes, the lowest first
+func (s *Stock) FindAll(ctx context.Context, fil *StockFilter) ([]schema.Stock, error) {
+	if fil == nil {
+		return nil, ErrFilterNill
+	}
+
+	q := util.NewQuery()
+	q.Where("cake_id IN (SELECT id FROM cakes WHERE deleted_at IS NULL)")
+
+	if fil.IsValidBelow() {
+		q.Where("quantity < ?", *fil.Below)
+	}
+
+	where, args := q.Build()
+	query := "SELECT * FROM cake_stocks " + where + " ORDER BY quantity ASC, cake_id ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), args...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find stocks, an error occurred")
+	}
+
+	res := []schema.Stock{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find stocks, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+type MovementFilter struct {
+	CakeID int
+	Kind   string
+
+	// Limit is a page size, Before is a movement id from a previous page, newest movement come first
+	Limit  int
+	Before int
+}
+
+func (f *MovementFilter) IsValidKind() bool {
+	return len(f.Kind) > 0
+}
+
+func (f *MovementFilter) IsValidBefore() bool {
+	return f.Before > 0
+}
+
+func (s *Stock) FindMovements(ctx context.Context, fil *MovementFilter) ([]schema.StockMovement, error) {
+	if fil == nil {
+		return nil, ErrFilterNill
+	}
+
+	q := util.NewQuery()
+	q.Where("cake_id = ?", fil.CakeID)
+
+	if fil.IsValidKind() {
+		q.Where("kind = ?", fil.Kind)
+	}
+
+	if fil.IsValidBefore() {
+		q.Where("id < ?", fil.Before)
+	}
+
+	where, args := q.Build()
+	query := "SELECT * FROM stock_movements " + where + " ORDER BY id DESC LIMIT ?"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), append(args, NormalizeLimit(fil.Limit))...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find stock movements, an error occurred")
+	}
+
+	res := []schema.StockMovement{}
+	if err := s.retrieveMovementRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find stock movements, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+// Move apply a movement to the stock of a cake and append it to the ledger in a transaction,
+// the stock row is locked so a concurrent movements are applied one by one and a movement
+// which drive the quantity below zero is rejected with ErrInsufficientStock.
+// Balance and ID of the movement is filled on success
+func (s *Stock) Move(ctx context.Context, mov *schema.StockMovement) error {
+	if mov == nil {
+		return ErrRecordNill
+	}
+
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "move stock, an error occurred")
+	}
+	defer tx.Rollback()
+
+	// the cake is locked, so it's not deleted until the movement is committed
+	if err := lockCake(ctx, tx, s.Dialect, mov.CakeID); err != nil {
+		return err
+	}
+
+	// make sure the stock row is exist, so there is always a row to lock
+	query := "INSERT INTO cake_stocks (cake_id, quantity, updated_at) VALUES (?, 0, ?) " + s.Dialect.upsert("cake_id")
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), mov.CakeID, mov.CreatedAt); err != nil {
+		return eris.Wrap(err, "move stock, an error occurred")
+	}
+
+	query = "SELECT quantity FROM cake_stocks WHERE cake_id = ?" + s.Dialect.forUpdate()
+	log.Print(query)
+
+	var quantity int
+	if err := tx.QueryRowContext(ctx, s.Dialect.rebind(query), mov.CakeID).Scan(&quantity); err != nil {
+		return eris.Wrap(err, "move stock, an error occurred")
+	}
+
+	balance := quantity + mov.Quantity
+	if balance < 0 {
+		return ErrInsufficientStock
+	}
+
+	query = "UPDATE cake_stocks SET quantity = ?, updated_at = ? WHERE cake_id = ?"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), balance, mov.CreatedAt, mov.CakeID); err != nil {
+		return eris.Wrap(err, "move stock, an error occurred")
+	}
+
+	query = "INSERT INTO stock_movements (cake_id, kind, quantity, balance, note, tracker_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, tx, query, mov.CakeID, mov.Kind, mov.Quantity, balance, mov.Note, mov.TrackerID, mov.CreatedAt)
+	if err != nil {
+		return eris.Wrap(err, "move stock, an error occurred")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "move stock, an error occurred")
+	}
+	mov.ID, mov.Balance = int(id), balance
+
+	return nil
+}
+
+func (s *Stock) retrieveRows(rows *sql.Rows, res *[]schema.Stock) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.Stock
+		if err := rows.Scan(&o.CakeID, &o.Quantity, &o.UpdatedAt); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Stock) retrieveMovementRows(rows *sql.Rows, res *[]schema.StockMovement) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.StockMovement
+		if err := rows.Scan(
+			&o.ID,
+			&o.CakeID,
+			&o.Kind,
+			&o.Quantity,
+			&o.Balance,
+			&o.Note,
+			&o.TrackerID,
+			&o.CreatedAt,
+		); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
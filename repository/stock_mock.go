@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type StockMock struct {
+	mock.Mock
+}
+
+func (m *StockMock) Find(ctx context.Context, cakeID int) (*schema.Stock, error) {
+	args := m.Called(ctx, cakeID)
+	res, _ := args.Get(0).(*schema.Stock)
+	return res, args.Error(1)
+}
+
+func (m *StockMock) FindAll(ctx context.Context, fil *StockFilter) ([]schema.Stock, error) {
+	args := m.Called(ctx, fil)
+	res, _ := args.Get(0).([]schema.Stock)
+	return res, args.Error(1)
+}
+
+func (m *StockMock) FindMovements(ctx context.Context, fil *MovementFilter) ([]schema.StockMovement, error) {
+	args := m.Called(ctx, fil)
+	res, _ := args.Get(0).([]schema.StockMovement)
+	return res, args.Error(1)
+}
+
+func (m *StockMock) Move(ctx context.Context, mov *schema.StockMovement) error {
+	mov.CreatedAt = time.Time{}
+	args := m.Called(ctx, mov)
+	return args.Error(0)
+}
@@ -0,0 +1,142 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var stockColumns = []string{
+	"cake_id",
+	"quantity",
+	"updated_at",
+}
+
+var movementColumns = []string{
+	"id",
+	"cake_id",
+	"kind",
+	"quantity",
+	"balance",
+	"note",
+	"tracker_id",
+	"created_at",
+}
+
+func Test_Stock_Repository_Find(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT * FROM cake_stocks WHERE cake_id = ? LIMIT 1").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(stockColumns).AddRow(1, 12, now))
+	mock.ExpectQuery("SELECT * FROM cake_stocks WHERE cake_id = ? LIMIT 1").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(stockColumns))
+
+	repo := &repository.Stock{DB: db}
+	res, err := repo.Find(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, &schema.Stock{CakeID: 1, Quantity: 12, UpdatedAt: now}, res)
+
+	res, err = repo.Find(context.Background(), 2)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Stock_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	below := 5
+	mock.ExpectQuery("SELECT * FROM cake_stocks WHERE cake_id IN (SELECT id FROM cakes WHERE deleted_at IS NULL) AND quantity < ? ORDER BY quantity ASC, cake_id ASC").
+		WithArgs(below).
+		WillReturnRows(sqlmock.NewRows(stockColumns).AddRow(2, 0, time.Now()).AddRow(1, 3, time.Now()))
+
+	repo := &repository.Stock{DB: db}
+	res, err := repo.FindAll(context.Background(), &repository.StockFilter{Below: &below})
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+}
+
+func Test_Stock_Repository_Find_Movements(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT * FROM stock_movements WHERE cake_id = ? AND kind = ? AND id < ? ORDER BY id DESC LIMIT ?").
+		WithArgs(1, schema.StockMovementSold, 10, repository.DefaultLimit).
+		WillReturnRows(sqlmock.NewRows(movementColumns).AddRow(9, 1, schema.StockMovementSold, -2, 10, "", "", time.Now()))
+
+	repo := &repository.Stock{DB: db}
+	res, err := repo.FindMovements(context.Background(), &repository.MovementFilter{
+		CakeID: 1,
+		Kind:   schema.StockMovementSold,
+		Before: 10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, -2, res[0].Quantity)
+}
+
+func Test_Stock_Repository_Move(t *testing.T) {
+	now := time.Now()
+
+	expectLock := func(mock sqlmock.Sqlmock, quantity int) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec("INSERT INTO cake_stocks (cake_id, quantity, updated_at) VALUES (?, 0, ?) ON DUPLICATE KEY UPDATE cake_id = cake_id").
+			WithArgs(1, now).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT quantity FROM cake_stocks WHERE cake_id = ? FOR UPDATE").
+			WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(quantity))
+	}
+
+	t.Run("Applied", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		expectLock(mock, 5)
+		mock.ExpectExec("UPDATE cake_stocks SET quantity = ?, updated_at = ? WHERE cake_id = ?").
+			WithArgs(3, now, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO stock_movements (cake_id, kind, quantity, balance, note, tracker_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)").
+			WithArgs(1, schema.StockMovementSold, -2, 3, "", "", now).WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectCommit()
+
+		repo := &repository.Stock{DB: db}
+		mov := &schema.StockMovement{CakeID: 1, Kind: schema.StockMovementSold, Quantity: -2, CreatedAt: now}
+		assert.NoError(t, repo.Move(context.Background(), mov))
+		assert.Equal(t, 4, mov.ID)
+		assert.Equal(t, 3, mov.Balance)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Cake_Deleted", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		// a trashed cake is not locked, so nothing is moved
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockCakeQuery).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		repo := &repository.Stock{DB: db}
+		mov := &schema.StockMovement{CakeID: 1, Kind: schema.StockMovementBaked, Quantity: 2, CreatedAt: now}
+		assert.ErrorIs(t, repo.Move(context.Background(), mov), repository.ErrRecordNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Insufficient", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		expectLock(mock, 1)
+		mock.ExpectRollback()
+
+		repo := &repository.Stock{DB: db}
+		mov := &schema.StockMovement{CakeID: 1, Kind: schema.StockMovementSold, Quantity: -2, CreatedAt: now}
+		assert.ErrorIs(t, repo.Move(context.Background(), mov), repository.ErrInsufficientStock)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type Tag struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+func (s *Tag) Find(ctx context.Context, id int) (*schema.Tag, error) {
+	if id <= 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := "SELECT * FROM tags WHERE id = ? LIMIT 1"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), id)
+	if err != nil {
+		return nil, eris.Wrap(err, "find tag by id, an error occurred")
+	}
+
+	res := []schema.Tag{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find tag by id, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return &res[0], nil
+}
+
+func (s *Tag) FindAll(ctx context.Context) ([]schema.Tag, error) {
+	query := "SELECT * FROM tags ORDER BY name ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query))
+	if err != nil {
+		return nil, eris.Wrap(err, "find tags, an error occurred")
+	}
+
+	res := []schema.Tag{}
+	if err := s.retrieveRows(rows, &res); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, eris.Wrap(err, "find tags, an error occurred")
+	}
+
+	if len(res) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return res, nil
+}
+
+// FindByCakeIDs list a tags of each cakes, keyed by cake id
+func (s *Tag) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.Tag, error) {
+	res := map[int][]schema.Tag{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	query := "SELECT ct.cake_id, t.* FROM cake_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.cake_id IN (" + placeholders(len(ids)) + ") ORDER BY t.name ASC"
+	log.Print(query)
+
+	rows, err := conn(ctx, s.DB).QueryContext(ctx, s.Dialect.rebind(query), intArgs(ids)...)
+	if err != nil {
+		return nil, eris.Wrap(err, "find tags by cake ids, an error occurred")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cakeID int
+			o      schema.Tag
+		)
+		if err := rows.Scan(&cakeID, &o.ID, &o.Name, &o.CreatedAt); err != nil {
+			return nil, eris.Wrap(err, "find tags by cake ids, an error occurred")
+		}
+		res[cakeID] = append(res[cakeID], o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, eris.Wrap(err, "find tags by cake ids, an error occurred")
+	}
+
+	return res, nil
+}
+
+func (s *Tag) Insert(ctx context.Context, rec *schema.Tag) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+
+	query := "INSERT INTO tags (name, created_at) VALUES (?, ?)"
+	log.Print(query)
+
+	id, err := s.Dialect.insert(ctx, conn(ctx, s.DB), query, rec.Name, rec.CreatedAt)
+	if err != nil {
+		if isDuplicate(err) {
+			return ErrDuplicateRecord
+		}
+		return eris.Wrap(err, "insert tag, an error occurred")
+	}
+	rec.ID = int(id)
+
+	return nil
+}
+
+func (s *Tag) Update(ctx context.Context, rec *schema.Tag) error {
+	if rec == nil {
+		return ErrRecordNill
+	}
+	if rec.ID <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "UPDATE tags SET name=? WHERE id = ?"
+	log.Print(query)
+
+	if _, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), rec.Name, rec.ID); err != nil {
+		if isDuplicate(err) {
+			return ErrDuplicateRecord
+		}
+		return eris.Wrap(err, "update tag, an error occurred")
+	}
+
+	return nil
+}
+
+func (s *Tag) Delete(ctx context.Context, id int) error {
+	if id <= 0 {
+		return ErrRecordNotFound
+	}
+
+	query := "DELETE FROM tags WHERE id = ?"
+	log.Print(query)
+
+	res, err := conn(ctx, s.DB).ExecContext(ctx, s.Dialect.rebind(query), id)
+	if err != nil {
+		return eris.Wrap(err, "delete tag, an error occurred")
+	}
+
+	return affected(res)
+}
+
+// SetCakeTags replace a tags of a cake by it's names, an unknown tag is created
+func (s *Tag) SetCakeTags(ctx context.Context, cakeID int, names []string) error {
+	tx, err := begin(ctx, s.DB)
+	if err != nil {
+		return eris.Wrap(err, "set cake tags, an error occurred")
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		id, err := s.ensure(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	query := "DELETE FROM cake_tags WHERE cake_id = ?"
+	log.Print(query)
+
+	if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), cakeID); err != nil {
+		return eris.Wrap(err, "set cake tags, an error occurred")
+	}
+
+	if len(ids) > 0 {
+		var (
+			values = make([]string, len(ids))
+			args   = make([]any, 0, len(ids)*2)
+		)
+		for i, id := range ids {
+			values[i] = "(?, ?)"
+			args = append(args, cakeID, id)
+		}
+
+		query = "INSERT INTO cake_tags (cake_id, tag_id) VALUES " + strings.Join(values, ", ")
+		log.Print(query)
+
+		if _, err := tx.ExecContext(ctx, s.Dialect.rebind(query), args...); err != nil {
+			return eris.Wrap(err, "set cake tags, an error occurred")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "set cake tags, an error occurred")
+	}
+
+	return nil
+}
+
+// ensure find a tag id by it's name, the tag is created when it does not exist yet
+func (s *Tag) ensure(ctx context.Context, tx execer, name string) (int, error) {
+	query := "SELECT id FROM tags WHERE name = ? LIMIT 1"
+	log.Print(query)
+
+	var id int
+	err := tx.QueryRowContext(ctx, s.Dialect.rebind(query), name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, eris.Wrap(err, "find tag by name, an error occurred")
+	}
+
+	query = "INSERT INTO tags (name, created_at) VALUES (?, ?)"
+	log.Print(query)
+
+	lastID, err := s.Dialect.insert(ctx, tx, query, name, time.Now())
+	if err != nil {
+		return 0, eris.Wrap(err, "insert tag, an error occurred")
+	}
+
+	return int(lastID), nil
+}
+
+func (s *Tag) retrieveRows(rows *sql.Rows, res *[]schema.Tag) error {
+	if rows == nil {
+		return ErrSQLRowsNill
+	}
+	if res == nil {
+		return ErrResultNill
+	}
+
+	for rows.Next() {
+		var o schema.Tag
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedAt); err != nil {
+			util.ResetSlice(res)
+			return err
+		}
+
+		*res = append(*res, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		util.ResetSlice(res)
+		return err
+	}
+
+	return nil
+}
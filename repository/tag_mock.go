@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type TagMock struct {
+	mock.Mock
+}
+
+func (m *TagMock) Find(ctx context.Context, id int) (*schema.Tag, error) {
+	args := m.Called(ctx, id)
+	res, _ := args.Get(0).(*schema.Tag)
+	return res, args.Error(1)
+}
+
+func (m *TagMock) FindAll(ctx context.Context) ([]schema.Tag, error) {
+	args := m.Called(ctx)
+	res, _ := args.Get(0).([]schema.Tag)
+	return res, args.Error(1)
+}
+
+func (m *TagMock) FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.Tag, error) {
+	args := m.Called(ctx, ids)
+	res, _ := args.Get(0).(map[int][]schema.Tag)
+	return res, args.Error(1)
+}
+
+func (m *TagMock) Insert(ctx context.Context, rec *schema.Tag) error {
+	rec.CreatedAt = time.Time{}
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *TagMock) Update(ctx context.Context, rec *schema.Tag) error {
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *TagMock) Delete(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *TagMock) SetCakeTags(ctx context.Context, cakeID int, names []string) error {
+	args := m.Called(ctx, cakeID, names)
+	return args.Error(0)
+}
@@ -0,0 +1,119 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var tagColumns = []string{
+	"id",
+	"name",
+	"created_at",
+}
+
+var tag = schema.Tag{
+	ID:        1,
+	Name:      "vegan",
+	CreatedAt: time.Now(),
+}
+
+func Test_Tag_Repository_Find(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(tagColumns).AddRow(tag.ID, tag.Name, tag.CreatedAt)
+	mock.ExpectQuery("SELECT * FROM tags WHERE id = ? LIMIT 1").WithArgs(tag.ID).WillReturnRows(rows)
+
+	repo := &repository.Tag{DB: db}
+	res, err := repo.Find(context.Background(), tag.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tag.Name, res.Name)
+}
+
+func Test_Tag_Repository_Find_All(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(tagColumns).AddRow(tag.ID, tag.Name, tag.CreatedAt).AddRow(2, "gluten-free", tag.CreatedAt)
+	mock.ExpectQuery("SELECT * FROM tags ORDER BY name ASC").WillReturnRows(rows)
+
+	repo := &repository.Tag{DB: db}
+	res, err := repo.FindAll(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+}
+
+func Test_Tag_Repository_Find_By_Cake_IDs(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	rows := sqlmock.NewRows(append([]string{"cake_id"}, tagColumns...)).AddRow(1, tag.ID, tag.Name, tag.CreatedAt)
+	mock.ExpectQuery("SELECT ct.cake_id, t.* FROM cake_tags ct JOIN tags t ON t.id = ct.tag_id WHERE ct.cake_id IN (?) ORDER BY t.name ASC").
+		WithArgs(1).WillReturnRows(rows)
+
+	repo := &repository.Tag{DB: db}
+	res, err := repo.FindByCakeIDs(context.Background(), []int{1})
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.Tag{tag}, res[1])
+}
+
+func Test_Tag_Repository_Insert(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO tags (name, created_at) VALUES (?, ?)").
+		WithArgs(tag.Name, tag.CreatedAt).WillReturnResult(sqlmock.NewResult(4, 1))
+
+	repo := &repository.Tag{DB: db}
+	rec := tag
+	assert.NoError(t, repo.Insert(context.Background(), &rec))
+	assert.Equal(t, 4, rec.ID)
+}
+
+func Test_Tag_Repository_Update(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE tags SET name=? WHERE id = ?").WithArgs(tag.Name, tag.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &repository.Tag{DB: db}
+	assert.NoError(t, repo.Update(context.Background(), &tag))
+}
+
+func Test_Tag_Repository_Delete(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM tags WHERE id = ?").WithArgs(tag.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := &repository.Tag{DB: db}
+	assert.NoError(t, repo.Delete(context.Background(), tag.ID))
+}
+
+func Test_Tag_Repository_Set_Cake_Tags(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM tags WHERE name = ? LIMIT 1").WithArgs("vegan").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(tag.ID))
+	mock.ExpectQuery("SELECT id FROM tags WHERE name = ? LIMIT 1").WithArgs("seasonal").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO tags (name, created_at) VALUES (?, ?)").WithArgs("seasonal", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("DELETE FROM cake_tags WHERE cake_id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO cake_tags (cake_id, tag_id) VALUES (?, ?), (?, ?)").
+		WithArgs(1, tag.ID, 1, 7).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := &repository.Tag{DB: db}
+	assert.NoError(t, repo.SetCakeTags(context.Background(), 1, []string{"vegan", "seasonal"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
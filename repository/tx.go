@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// DefaultTxAttempts is a number of attempts of a transaction which is aborted by a deadlock
+const DefaultTxAttempts = 3
+
+type txKey struct{}
+
+// savepointKey is a depth of the savepoints of a context, a nested savepoint has it's own name
+type savepointKey struct{}
+
+// Transactor run a unit of work in a transaction, the transaction is passed through the context
+// so every repository called with the context run on it. the transaction is committed when the
+// unit of work succeed and rolled back otherwise, a deadlock retry the whole unit of work.
+type Transactor struct {
+	DB *sql.DB
+
+	// Attempts is a max attempts of a transaction, DefaultTxAttempts is used when it's zero
+	Attempts int
+}
+
+// WithinTx run fn in a transaction, fn join the transaction of ctx when there is one already.
+// fn may be called more than once, so it must not keep a side effect out of the transaction
+func (s *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	attempts := s.Attempts
+	if attempts <= 0 {
+		attempts = DefaultTxAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = s.run(ctx, fn); err == nil || !isDeadlock(err) {
+			return err
+		}
+
+		// back off a little so a conflicting transaction can finish
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+
+	return err
+}
+
+func (s *Transactor) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return eris.Wrap(err, "begin transaction, an error occurred")
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return eris.Wrap(err, "commit transaction, an error occurred")
+	}
+
+	return nil
+}
+
+// WithinSavepoint run fn in a savepoint of the transaction of ctx, only the changes of fn are rolled back
+// when it fails so the transaction can continue. fn run in it's own transaction when ctx has none.
+// a deadlock is returned as is since it abort the whole transaction, which is retried by WithinTx
+func (s *Transactor) WithinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx := txFrom(ctx)
+	if tx == nil {
+		return s.WithinTx(ctx, fn)
+	}
+
+	depth, _ := ctx.Value(savepointKey{}).(int)
+	depth++
+	name := "sp_" + strconv.Itoa(depth)
+
+	query := "SAVEPOINT " + name
+	log.Print(query)
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return eris.Wrap(err, "create savepoint, an error occurred")
+	}
+
+	if err := fn(context.WithValue(ctx, savepointKey{}, depth)); err != nil {
+		if isDeadlock(err) {
+			return err
+		}
+
+		query = "ROLLBACK TO SAVEPOINT " + name
+		log.Print(query)
+		if _, rbErr := tx.ExecContext(ctx, query); rbErr != nil {
+			return eris.Wrap(rbErr, "rollback savepoint, an error occurred")
+		}
+		return err
+	}
+
+	query = "RELEASE SAVEPOINT " + name
+	log.Print(query)
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return eris.Wrap(err, "release savepoint, an error occurred")
+	}
+
+	return nil
+}
+
+// txFrom return a transaction of a context, it's nil outside of Transactor.WithinTx
+func txFrom(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// conn return a transaction of a context or the database when there is no transaction
+func conn(ctx context.Context, db *sql.DB) execer {
+	if tx := txFrom(ctx); tx != nil {
+		return tx
+	}
+	return db
+}
+
+// localTx is a transaction of a repository method, it's the transaction of a context when there
+// is one, so it's committed or rolled back by it's owner instead
+type localTx struct {
+	*sql.Tx
+	owned bool
+}
+
+// begin begin a transaction of a repository method, it join the transaction of a context when there is one
+func begin(ctx context.Context, db *sql.DB) (*localTx, error) {
+	if tx := txFrom(ctx); tx != nil {
+		return &localTx{Tx: tx}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &localTx{Tx: tx, owned: true}, nil
+}
+
+func (t *localTx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *localTx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// TransactorMock run a unit of work without a transaction, an error returned by the mock
+// is returned without running the unit of work like a failed begin
+type TransactorMock struct {
+	mock.Mock
+}
+
+func (m *TransactorMock) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+func (m *TransactorMock) WithinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
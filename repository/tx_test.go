@@ -0,0 +1,162 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/zufzuf/cake-store/repository"
+)
+
+func Test_Transactor_Commit(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	// a repository transaction join the ambient transaction instead of beginning another one
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT * FROM cakes WHERE id = ? AND deleted_at IS NULL LIMIT 1 FOR UPDATE").
+		WithArgs(cake.ID).WillReturnRows(sqlmock.NewRows(cakeColumns).AddRow(
+		cake.ID, cake.Title, cake.Description, cake.Rating, cake.Image, cake.CreatedAt, cake.UpdatedAt, nil, 1, cake.Price, cake.Currency,
+	))
+	mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var (
+		tx         = &repository.Transactor{DB: db}
+		cakes      = &repository.Cake{DB: db}
+		categories = &repository.Category{DB: db}
+	)
+	err := tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		if _, err := cakes.Find(ctx, cake.ID); err != nil {
+			return err
+		}
+		return categories.SetCakeCategories(ctx, cake.ID, nil)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Transactor_Rollback(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	errFailed := errors.New("failed")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	var (
+		tx         = &repository.Transactor{DB: db}
+		categories = &repository.Category{DB: db}
+	)
+	err := tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := categories.SetCakeCategories(ctx, cake.ID, nil); err != nil {
+			return err
+		}
+
+		// a nested unit of work run on the same transaction
+		return tx.WithinTx(ctx, func(ctx context.Context) error {
+			return errFailed
+		})
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_Transactor_Deadlock_Retry(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+	t.Run("Retried", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnError(deadlock)
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var (
+			tx         = &repository.Transactor{DB: db}
+			categories = &repository.Category{DB: db}
+			calls      int
+		)
+		err := tx.WithinTx(context.Background(), func(ctx context.Context) error {
+			calls++
+			return categories.SetCakeCategories(ctx, cake.ID, nil)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Attempts_Exhausted", func(t *testing.T) {
+		db, mock := NewMock()
+		defer db.Close()
+
+		for i := 0; i < 2; i++ {
+			mock.ExpectBegin()
+			mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnError(deadlock)
+			mock.ExpectRollback()
+		}
+
+		var (
+			tx         = &repository.Transactor{DB: db, Attempts: 2}
+			categories = &repository.Category{DB: db}
+		)
+		err := tx.WithinTx(context.Background(), func(ctx context.Context) error {
+			return categories.SetCakeCategories(ctx, cake.ID, nil)
+		})
+
+		var myErr *mysql.MySQLError
+		assert.ErrorAs(t, err, &myErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Transactor_Savepoint(t *testing.T) {
+	db, mock := NewMock()
+	defer db.Close()
+
+	errFailed := errors.New("failed")
+
+	// only the changes of a failed savepoint are rolled back, the transaction is still committed
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM cake_categories WHERE cake_id = ?").WithArgs(cake.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var (
+		tx         = &repository.Transactor{DB: db}
+		categories = &repository.Category{DB: db}
+	)
+	err := tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		err := tx.WithinSavepoint(ctx, func(ctx context.Context) error {
+			if err := categories.SetCakeCategories(ctx, cake.ID, nil); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+
+		// a nested savepoint has it's own name
+		return tx.WithinSavepoint(ctx, func(ctx context.Context) error {
+			return tx.WithinSavepoint(ctx, func(ctx context.Context) error {
+				return categories.SetCakeCategories(ctx, cake.ID, nil)
+			})
+		})
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,33 @@
+package schema
+
+// Allergens is a fixed vocabulary of an allergen which a cake can declare,
+// it follows the 14 major allergens of the EU food information regulation
+var Allergens = []string{
+	"celery",
+	"crustaceans",
+	"dairy",
+	"eggs",
+	"fish",
+	"gluten",
+	"lupin",
+	"molluscs",
+	"mustard",
+	"nuts",
+	"peanuts",
+	"sesame",
+	"soy",
+	"sulphites",
+}
+
+// Nutrition is a nutrition facts of a cake per serving, ServingSize and every nutrient is in gram
+// except Energy which is in kcal
+type Nutrition struct {
+	ServingSize  float64 `json:"serving_size" db:"serving_size"`
+	Energy       float64 `json:"energy" db:"energy"`
+	Fat          float64 `json:"fat" db:"fat"`
+	SaturatedFat float64 `json:"saturated_fat" db:"saturated_fat"`
+	Carbohydrate float64 `json:"carbohydrate" db:"carbohydrate"`
+	Sugars       float64 `json:"sugars" db:"sugars"`
+	Protein      float64 `json:"protein" db:"protein"`
+	Salt         float64 `json:"salt" db:"salt"`
+}
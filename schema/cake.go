@@ -2,12 +2,29 @@ package schema
 
 import "time"
 
+// Cake is a cake on the store, Price is in a minor unit of the ISO 4217 Currency
 type Cake struct {
-	ID          int       `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	Rating      float64   `json:"rating" db:"rating"`
-	Image       string    `json:"image" db:"image"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int        `json:"id" db:"id"`
+	Title       string     `json:"title" db:"title"`
+	Description string     `json:"description" db:"description"`
+	Rating      float64    `json:"rating" db:"rating"`
+	Image       string     `json:"image" db:"image"`
+	Price       int64      `json:"price" db:"price"`
+	Currency    string     `json:"currency" db:"currency"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	Version     int        `json:"version" db:"version"`
+
+	// Categories, Tags, Images, Allergens and Nutrition is loaded from a relation tables,
+	// Image is kept as the url of the primary image. Variants is only loaded when it's embedded
+	Categories []Category    `json:"categories,omitempty" db:"-"`
+	Tags       []Tag         `json:"tags,omitempty" db:"-"`
+	Images     []CakeImage   `json:"images,omitempty" db:"-"`
+	Allergens  []string      `json:"allergens,omitempty" db:"-"`
+	Nutrition  *Nutrition    `json:"nutrition,omitempty" db:"-"`
+	Variants   []CakeVariant `json:"variants,omitempty" db:"-"`
+
+	// Score is a full-text search relevance, it's only set on a search result
+	Score *float64 `json:"score,omitempty" db:"score"`
 }
@@ -0,0 +1,33 @@
+package schema
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RevisionActionInsert  = "insert"
+	RevisionActionUpdate  = "update"
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+	RevisionActionPurge   = "purge"
+)
+
+// CakeRevision is an immutable snapshot of a cake before and after a change,
+// Before is null on insert and restore, After is null on delete and both are null on purge
+type CakeRevision struct {
+	ID        int             `json:"id" db:"id"`
+	CakeID    int             `json:"cake_id" db:"cake_id"`
+	Action    string          `json:"action" db:"action"`
+	TrackerID string          `json:"tracker_id" db:"tracker_id"`
+	Before    json.RawMessage `json:"before" db:"before_data"`
+	After     json.RawMessage `json:"after" db:"after_data"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// FieldChange is a field level difference between two revisions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
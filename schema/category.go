@@ -0,0 +1,13 @@
+package schema
+
+import "time"
+
+// Category is a hierarchical classification of cakes, e.g. Birthday > Kids
+type Category struct {
+	ID        int       `json:"id" db:"id"`
+	ParentID  *int      `json:"parent_id" db:"parent_id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package schema
+
+import "time"
+
+// Image is an uploaded image, Key is it's storage key and URL is a public url of it
+type Image struct {
+	Key         string      `json:"key"`
+	URL         string      `json:"url"`
+	ContentType string      `json:"content_type"`
+	Size        int64       `json:"size"`
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Thumbnails  []Thumbnail `json:"thumbnails"`
+}
+
+// Thumbnail is a resized copy of an image
+type Thumbnail struct {
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// CakeImage is an image on a cake gallery, the primary image is used as the cake image.
+// Key and Thumbnails is empty for an image which is hosted elsewhere
+type CakeImage struct {
+	ID     int `json:"id" db:"id"`
+	CakeID int `json:"cake_id" db:"cake_id"`
+	Image
+	Alt       string    `json:"alt" db:"alt"`
+	Position  int       `json:"position" db:"position"`
+	Primary   bool      `json:"primary" db:"is_primary"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
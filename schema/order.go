@@ -0,0 +1,37 @@
+package schema
+
+import "time"
+
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusConfirmed = "confirmed"
+	OrderStatusBaking    = "baking"
+	OrderStatusReady     = "ready"
+	OrderStatusPickedUp  = "picked_up"
+	OrderStatusCancelled = "cancelled"
+)
+
+// Order is a customer order, Total is a sum of line subtotals in a minor unit of the Currency,
+// every ordered cake is priced in the same currency
+type Order struct {
+	ID            int         `json:"id" db:"id"`
+	CustomerName  string      `json:"customer_name" db:"customer_name"`
+	CustomerPhone string      `json:"customer_phone" db:"customer_phone"`
+	Note          string      `json:"note" db:"note"`
+	Status        string      `json:"status" db:"status"`
+	Total         int64       `json:"total" db:"total"`
+	Currency      string      `json:"currency" db:"currency"`
+	Lines         []OrderLine `json:"lines,omitempty" db:"-"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// OrderLine is an ordered cake, CakeTitle and UnitPrice is a snapshot when the order is made
+type OrderLine struct {
+	ID        int    `json:"id" db:"id"`
+	OrderID   int    `json:"order_id" db:"order_id"`
+	CakeID    int    `json:"cake_id" db:"cake_id"`
+	CakeTitle string `json:"cake_title" db:"cake_title"`
+	Quantity  int    `json:"quantity" db:"quantity"`
+	UnitPrice int64  `json:"unit_price" db:"unit_price"`
+}
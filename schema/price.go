@@ -0,0 +1,14 @@
+package schema
+
+import "time"
+
+// CakePrice is a price of a cake since EffectiveFrom until a next price of the cake,
+// Price is in a minor unit of the currency
+type CakePrice struct {
+	ID            int       `json:"id" db:"id"`
+	CakeID        int       `json:"cake_id" db:"cake_id"`
+	Price         int64     `json:"price" db:"price"`
+	Currency      string    `json:"currency" db:"currency"`
+	EffectiveFrom time.Time `json:"effective_from" db:"effective_from"`
+	TrackerID     string    `json:"tracker_id" db:"tracker_id"`
+}
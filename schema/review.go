@@ -0,0 +1,23 @@
+package schema
+
+import "time"
+
+// Review is a customer review of a cake, Score is a 1 to 5 star
+type Review struct {
+	ID        int       `json:"id" db:"id"`
+	CakeID    int       `json:"cake_id" db:"cake_id"`
+	Author    string    `json:"author" db:"author"`
+	Score     int       `json:"score" db:"score"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// RatingSummary is a rating breakdown of a cake, Stars is a number of reviews keyed by it's score,
+// Rating is the computed cake rating and Average is a plain mean of the scores
+type RatingSummary struct {
+	CakeID  int         `json:"cake_id"`
+	Rating  float64     `json:"rating"`
+	Average float64     `json:"average"`
+	Count   int         `json:"count"`
+	Stars   map[int]int `json:"stars"`
+}
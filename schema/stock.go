@@ -0,0 +1,30 @@
+package schema
+
+import "time"
+
+const (
+	StockMovementBaked    = "baked"
+	StockMovementSold     = "sold"
+	StockMovementWasted   = "wasted"
+	StockMovementAdjusted = "adjusted"
+)
+
+// Stock is a current on-hand quantity of a cake
+type Stock struct {
+	CakeID    int       `json:"cake_id" db:"cake_id"`
+	Quantity  int       `json:"quantity" db:"quantity"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// StockMovement is an append-only ledger entry of a stock change,
+// Quantity is a signed delta and Balance is the on-hand quantity after the movement
+type StockMovement struct {
+	ID        int       `json:"id" db:"id"`
+	CakeID    int       `json:"cake_id" db:"cake_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Quantity  int       `json:"quantity" db:"quantity"`
+	Balance   int       `json:"balance" db:"balance"`
+	Note      string    `json:"note" db:"note"`
+	TrackerID string    `json:"tracker_id" db:"tracker_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,10 @@
+package schema
+
+import "time"
+
+// Tag is a free-form label of cakes
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,18 @@
+package schema
+
+import "time"
+
+// CakeVariant is a sellable size or flavour of a cake, Options describe it (e.g. size: 8", flavour: chocolate).
+// Price is in a minor unit of the cake currency. Stock is a display only quantity of the variant, it's not
+// tracked by the stock movements of the cake (see StockMovement) and it's not reserved by an order
+type CakeVariant struct {
+	ID        int               `json:"id" db:"id"`
+	CakeID    int               `json:"cake_id" db:"cake_id"`
+	SKU       string            `json:"sku" db:"sku"`
+	Name      string            `json:"name" db:"name"`
+	Options   map[string]string `json:"options" db:"options"`
+	Price     int64             `json:"price" db:"price"`
+	Stock     int               `json:"stock" db:"stock"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
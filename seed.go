@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rotisserie/eris"
+
+	"github.com/zufzuf/cake-store/fixtures"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/migrations"
+	"github.com/zufzuf/cake-store/server"
+	"github.com/zufzuf/cake-store/service"
+)
+
+// seed load the cakes of a fixture files into a database of STORAGE_DRIVER, the embedded
+// fixtures are loaded when there is no file
+func seed(ctx context.Context, args []string) error {
+	var (
+		reset bool
+		paths []string
+	)
+	for _, arg := range args {
+		switch {
+		case arg == "--reset":
+			reset = true
+		case strings.HasPrefix(arg, "-"):
+			return eris.Wrapf(ErrInvalidArgs, "unknown seed flag %q\n%s", arg, usage)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+
+	var (
+		reqs []service.CakeRequest
+		err  error
+	)
+	if len(paths) == 0 {
+		reqs, err = fixtures.Default()
+	} else {
+		reqs, err = fixtures.Read(paths...)
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, _, dialect, err := database()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := migrations.Check(ctx, conn); err != nil {
+		return eris.Wrap(err, "run `cake-store migrate up` first")
+	}
+
+	util.NewValidator()
+	res, err := server.CakeService(conn, dialect).Seed(ctx, reqs, reset)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("inserted %d cakes, skipped %d existing cakes\n", res.Inserted, res.Skipped)
+
+	return nil
+}
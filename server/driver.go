@@ -0,0 +1,27 @@
+package server
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	StorageDriverMySQL    = "mysql"
+	StorageDriverSQLite   = "sqlite"
+	StorageDriverPostgres = "postgres"
+	StorageDriverMemory   = "memory"
+)
+
+// StorageDriver read a repository backend from STORAGE_DRIVER, it fallback to StorageDriverMySQL
+func StorageDriver() string {
+	driver, ok := os.LookupEnv("STORAGE_DRIVER")
+	if !ok || len(driver) == 0 {
+		return StorageDriverMySQL
+	}
+	return driver
+}
+
+func strictIfMatch() bool {
+	strict, _ := strconv.ParseBool(os.Getenv("STRICT_IF_MATCH"))
+	return strict
+}
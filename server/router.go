@@ -4,8 +4,14 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/zufzuf/cake-store/handler"
 )
 
+// notServed answer a route which need a database, so a client of the memory driver can tell it from a typo
+func notServed(rw http.ResponseWriter, r *http.Request) {
+	handler.NotSupported(rw, r.Method+" "+r.URL.Path)
+}
+
 func (hs *HTTPServer) routes() {
 	hs.Router.Get("/", func(rw http.ResponseWriter, r *http.Request) {
 		rw.Write([]byte("🍰 cake api 🔥"))
@@ -16,5 +22,73 @@ func (hs *HTTPServer) routes() {
 		r.Get("/{id:[0-9]+}", hs.CakeHandler.FindCake)
 		r.Patch("/{id:[0-9]+}", hs.CakeHandler.UpdateCake)
 		r.Delete("/{id:[0-9]+}", hs.CakeHandler.DeleteCake)
+		r.Post("/{id:[0-9]+}/restore", hs.CakeHandler.RestoreCake)
+		r.Get("/export", hs.CakeHandler.ExportCake)
+		r.Get("/trash", hs.CakeHandler.FindAllTrashedCake)
+		r.Delete("/trash/{id:[0-9]+}", hs.CakeHandler.PurgeCake)
+
+		// a cake relations are only served with a database
+		if hs.DB == nil {
+			r.Post("/import", notServed)
+			r.Post("/batch", notServed)
+			r.HandleFunc("/{id:[0-9]+}/*", notServed)
+			return
+		}
+
+		r.Post("/import", hs.CakeHandler.ImportCake)
+		r.Post("/batch", hs.CakeHandler.BatchCake)
+		r.Get("/{id:[0-9]+}/history", hs.CakeHandler.FindCakeHistory)
+		r.Get("/{id:[0-9]+}/history/diff", hs.CakeHandler.DiffCakeHistory)
+		r.Get("/{id:[0-9]+}/prices", hs.CakeHandler.FindCakePrices)
+		r.Get("/{id:[0-9]+}/stock", hs.StockHandler.FindStock)
+		r.Get("/{id:[0-9]+}/stock/movements", hs.StockHandler.FindStockMovements)
+		r.Post("/{id:[0-9]+}/stock/movements", hs.StockHandler.AddStockMovement)
+		r.Get("/{id:[0-9]+}/images", hs.ImageHandler.FindAllCakeImage)
+		r.Post("/{id:[0-9]+}/images", hs.ImageHandler.UploadCakeImage)
+		r.Put("/{id:[0-9]+}/images/order", hs.ImageHandler.ReorderCakeImage)
+		r.Patch("/{id:[0-9]+}/images/{imageID:[0-9]+}", hs.ImageHandler.UpdateCakeImage)
+		r.Delete("/{id:[0-9]+}/images/{imageID:[0-9]+}", hs.ImageHandler.DeleteCakeImage)
+		r.Get("/{id:[0-9]+}/variants", hs.VariantHandler.FindAllCakeVariant)
+		r.Post("/{id:[0-9]+}/variants", hs.VariantHandler.AddCakeVariant)
+		r.Get("/{id:[0-9]+}/variants/{variantID:[0-9]+}", hs.VariantHandler.FindCakeVariant)
+		r.Patch("/{id:[0-9]+}/variants/{variantID:[0-9]+}", hs.VariantHandler.UpdateCakeVariant)
+		r.Delete("/{id:[0-9]+}/variants/{variantID:[0-9]+}", hs.VariantHandler.DeleteCakeVariant)
+		r.Get("/{id:[0-9]+}/reviews", hs.ReviewHandler.FindAllReview)
+		r.Post("/{id:[0-9]+}/reviews", hs.ReviewHandler.AddReview)
+		r.Get("/{id:[0-9]+}/reviews/summary", hs.ReviewHandler.FindReviewSummary)
+		r.Delete("/{id:[0-9]+}/reviews/{reviewID:[0-9]+}", hs.ReviewHandler.DeleteReview)
+	})
+
+	// the other resources are only served with a database
+	if hs.DB == nil {
+		for _, pattern := range []string{"/categories", "/tags", "/stocks", "/orders"} {
+			hs.Router.HandleFunc(pattern, notServed)
+			hs.Router.HandleFunc(pattern+"/*", notServed)
+		}
+		hs.Router.HandleFunc("/files/*", notServed)
+		return
+	}
+
+	hs.Router.Route("/categories", func(r chi.Router) {
+		r.Get("/", hs.CategoryHandler.FindAllCategory)
+		r.Post("/", hs.CategoryHandler.AddCategory)
+		r.Get("/{id:[0-9]+}", hs.CategoryHandler.FindCategory)
+		r.Patch("/{id:[0-9]+}", hs.CategoryHandler.UpdateCategory)
+		r.Delete("/{id:[0-9]+}", hs.CategoryHandler.DeleteCategory)
+	})
+	hs.Router.Route("/tags", func(r chi.Router) {
+		r.Get("/", hs.TagHandler.FindAllTag)
+		r.Post("/", hs.TagHandler.AddTag)
+		r.Get("/{id:[0-9]+}", hs.TagHandler.FindTag)
+		r.Patch("/{id:[0-9]+}", hs.TagHandler.UpdateTag)
+		r.Delete("/{id:[0-9]+}", hs.TagHandler.DeleteTag)
+	})
+	hs.Router.Get("/stocks", hs.StockHandler.FindAllStock)
+	hs.Router.Get("/files/*", hs.FileHandler.ServeFile)
+	hs.Router.Route("/orders", func(r chi.Router) {
+		r.Get("/", hs.OrderHandler.FindAllOrder)
+		r.Post("/", hs.OrderHandler.AddOrder)
+		r.Get("/{id:[0-9]+}", hs.OrderHandler.FindOrder)
+		r.Post("/{id:[0-9]+}/status", hs.OrderHandler.TransitionOrder)
 	})
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/zufzuf/cake-store/handler"
 	"github.com/zufzuf/cake-store/libs/logger"
 	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/migrations"
 	"github.com/zufzuf/cake-store/repository"
 	AppMiddleware "github.com/zufzuf/cake-store/server/middleware"
 	"github.com/zufzuf/cake-store/service"
@@ -29,11 +30,79 @@ type CakeHandler interface {
 	AddCake(rw http.ResponseWriter, r *http.Request)
 	UpdateCake(rw http.ResponseWriter, r *http.Request)
 	DeleteCake(rw http.ResponseWriter, r *http.Request)
+	FindAllTrashedCake(rw http.ResponseWriter, r *http.Request)
+	RestoreCake(rw http.ResponseWriter, r *http.Request)
+	PurgeCake(rw http.ResponseWriter, r *http.Request)
+	FindCakeHistory(rw http.ResponseWriter, r *http.Request)
+	DiffCakeHistory(rw http.ResponseWriter, r *http.Request)
+	FindCakePrices(rw http.ResponseWriter, r *http.Request)
+	ImportCake(rw http.ResponseWriter, r *http.Request)
+	ExportCake(rw http.ResponseWriter, r *http.Request)
+	BatchCake(rw http.ResponseWriter, r *http.Request)
 }
 
-func NewHTTPServer() *HTTPServer {
-	db := db.Init()
+type CategoryHandler interface {
+	FindCategory(rw http.ResponseWriter, r *http.Request)
+	FindAllCategory(rw http.ResponseWriter, r *http.Request)
+	AddCategory(rw http.ResponseWriter, r *http.Request)
+	UpdateCategory(rw http.ResponseWriter, r *http.Request)
+	DeleteCategory(rw http.ResponseWriter, r *http.Request)
+}
+
+type TagHandler interface {
+	FindTag(rw http.ResponseWriter, r *http.Request)
+	FindAllTag(rw http.ResponseWriter, r *http.Request)
+	AddTag(rw http.ResponseWriter, r *http.Request)
+	UpdateTag(rw http.ResponseWriter, r *http.Request)
+	DeleteTag(rw http.ResponseWriter, r *http.Request)
+}
+
+type StockHandler interface {
+	FindStock(rw http.ResponseWriter, r *http.Request)
+	FindAllStock(rw http.ResponseWriter, r *http.Request)
+	FindStockMovements(rw http.ResponseWriter, r *http.Request)
+	AddStockMovement(rw http.ResponseWriter, r *http.Request)
+}
+
+type OrderHandler interface {
+	FindOrder(rw http.ResponseWriter, r *http.Request)
+	FindAllOrder(rw http.ResponseWriter, r *http.Request)
+	AddOrder(rw http.ResponseWriter, r *http.Request)
+	TransitionOrder(rw http.ResponseWriter, r *http.Request)
+}
+
+type ReviewHandler interface {
+	FindAllReview(rw http.ResponseWriter, r *http.Request)
+	FindReviewSummary(rw http.ResponseWriter, r *http.Request)
+	AddReview(rw http.ResponseWriter, r *http.Request)
+	DeleteReview(rw http.ResponseWriter, r *http.Request)
+}
+
+type ImageHandler interface {
+	FindAllCakeImage(rw http.ResponseWriter, r *http.Request)
+	UploadCakeImage(rw http.ResponseWriter, r *http.Request)
+	UpdateCakeImage(rw http.ResponseWriter, r *http.Request)
+	ReorderCakeImage(rw http.ResponseWriter, r *http.Request)
+	DeleteCakeImage(rw http.ResponseWriter, r *http.Request)
+}
+
+type VariantHandler interface {
+	FindAllCakeVariant(rw http.ResponseWriter, r *http.Request)
+	FindCakeVariant(rw http.ResponseWriter, r *http.Request)
+	AddCakeVariant(rw http.ResponseWriter, r *http.Request)
+	UpdateCakeVariant(rw http.ResponseWriter, r *http.Request)
+	DeleteCakeVariant(rw http.ResponseWriter, r *http.Request)
+}
+
+type FileHandler interface {
+	ServeFile(rw http.ResponseWriter, r *http.Request)
+}
 
+type TrashPurger interface {
+	PurgeTrashed(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+func NewHTTPServer() *HTTPServer {
 	logger.StartLogger()
 	util.NewValidator()
 
@@ -53,23 +122,141 @@ func NewHTTPServer() *HTTPServer {
 	}))
 	r.Use(AppMiddleware.Tracker)
 
+	server := &HTTPServer{Router: r}
+
+	switch driver := StorageDriver(); driver {
+	case StorageDriverMySQL:
+		server.database(db.Init(), repository.DialectMySQL)
+	case StorageDriverSQLite:
+		server.database(db.InitSQLite(), repository.DialectSQLite)
+	case StorageDriverPostgres:
+		server.database(db.InitPostgres(), repository.DialectPostgres)
+	case StorageDriverMemory:
+		server.memory()
+	default:
+		log.Fatalf("unknown STORAGE_DRIVER %q, it must be one of [%s %s %s %s]",
+			driver, StorageDriverMySQL, StorageDriverSQLite, StorageDriverPostgres, StorageDriverMemory)
+	}
+
+	server.routes()
+
+	return server
+}
+
+// database wire every handlers with a SQL repositories of a database dialect
+func (hs *HTTPServer) database(db *sql.DB, dialect repository.Dialect) {
+	// a schema is migrated by the migrate command, a database is only served on the latest schema
+	if err := migrations.Check(context.Background(), db); err != nil {
+		log.Fatalf("refusing to serve, run `cake-store migrate status` and `cake-store migrate up` first: \n%+v\n", err)
+	}
+
+	srv := CakeService(db, dialect)
+
+	repoStock := &repository.Stock{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	repoOrder := &repository.Order{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	repoReview := &repository.Review{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	store := newStorage()
+	maxImageSize := int64Env("UPLOAD_MAX_SIZE", service.DefaultMaxImageSize)
+
+	hs.DB = db
+	hs.CakeHandler = &handler.Cake{Service: srv, StrictIfMatch: strictIfMatch()}
+	hs.TrashPurger = srv
+
+	hs.CategoryHandler = &handler.Category{Service: &service.Category{Repo: srv.Categories}}
+	hs.TagHandler = &handler.Tag{Service: &service.Tag{Repo: srv.Tags}}
+	hs.StockHandler = &handler.Stock{Service: &service.Stock{Repo: repoStock, Cakes: srv.Repo}}
+	hs.OrderHandler = &handler.Order{Service: &service.Order{Repo: repoOrder, Cakes: srv.Repo, Tx: srv.Tx}}
+	hs.ReviewHandler = &handler.Review{Service: &service.Review{Repo: repoReview, Cakes: srv.Repo}}
+	hs.ImageHandler = &handler.Image{
+		Service: &service.Image{Repo: srv.Images, Storage: store, Cakes: srv.Repo, MaxSize: maxImageSize},
+		MaxSize: maxImageSize,
+	}
+	hs.VariantHandler = &handler.Variant{Service: &service.Variant{Repo: srv.Variants, Cakes: srv.Repo}}
+	hs.FileHandler = &handler.File{Storage: store}
+}
+
+// CakeService wire a cake service with a SQL repositories of a database dialect, it's shared by
+// the api and a command which write cakes without it (see seed)
+func CakeService(db *sql.DB, dialect repository.Dialect) *service.Cake {
 	repoCake := &repository.Cake{
-		DB: db,
+		DB:      db,
+		Dialect: dialect,
 	}
 
-	srv := &service.Cake{
-		Repo: repoCake,
+	repoCakeRevision := &repository.CakeRevision{
+		DB:      db,
+		Dialect: dialect,
 	}
 
-	server := &HTTPServer{
-		Router:      r,
-		DB:          db,
-		CakeHandler: &handler.Cake{Service: srv},
+	repoCategory := &repository.Category{
+		DB:      db,
+		Dialect: dialect,
 	}
 
-	server.routes()
+	repoTag := &repository.Tag{
+		DB:      db,
+		Dialect: dialect,
+	}
 
-	return server
+	repoCakeImage := &repository.CakeImage{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	repoCakePrice := &repository.CakePrice{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	repoCakeVariant := &repository.CakeVariant{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	repoAllergen := &repository.Allergen{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	repoNutrition := &repository.Nutrition{
+		DB:      db,
+		Dialect: dialect,
+	}
+
+	return &service.Cake{
+		Repo:       repoCake,
+		Revisions:  repoCakeRevision,
+		Categories: repoCategory,
+		Tags:       repoTag,
+		Images:     repoCakeImage,
+		Prices:     repoCakePrice,
+		Variants:   repoCakeVariant,
+		Allergens:  repoAllergen,
+		Nutrition:  repoNutrition,
+		Tx:         &repository.Transactor{DB: db},
+	}
+}
+
+// memory wire the cake handler with an in-memory repository, it's a demo backend of a cake CRUD, trash
+// and export only. a cake relations and the other resources need a database, so their routes answer 501
+// and a filter on them is rejected with 422
+func (hs *HTTPServer) memory() {
+	srv := &service.Cake{Repo: &repository.CakeMemory{}}
+
+	hs.CakeHandler = &handler.Cake{Service: srv, StrictIfMatch: strictIfMatch()}
+	hs.TrashPurger = srv
 }
 
 type HTTPServer struct {
@@ -77,6 +264,16 @@ type HTTPServer struct {
 	DB     *sql.DB
 
 	CakeHandler CakeHandler
+	TrashPurger TrashPurger
+
+	CategoryHandler CategoryHandler
+	TagHandler      TagHandler
+	StockHandler    StockHandler
+	OrderHandler    OrderHandler
+	ReviewHandler   ReviewHandler
+	ImageHandler    ImageHandler
+	VariantHandler  VariantHandler
+	FileHandler     FileHandler
 }
 
 func (hs *HTTPServer) Run(ctx context.Context) error {
@@ -97,6 +294,8 @@ func (hs *HTTPServer) Run(ctx context.Context) error {
 		},
 	}
 
+	go hs.purgeTrash(ctx)
+
 	go func() {
 		log.Printf("start cake api")
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -117,6 +316,10 @@ func (hs *HTTPServer) Run(ctx context.Context) error {
 
 	log.Printf("server shutdown properly")
 
+	if hs.DB == nil {
+		return nil
+	}
+
 	if err := hs.DB.Close(); err != nil {
 		log.Fatal("unable close db connection")
 	}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/zufzuf/cake-store/libs/storage"
+)
+
+func newStorage() storage.Storage {
+	root, ok := os.LookupEnv("STORAGE_LOCAL_ROOT")
+	if !ok {
+		root = "./uploads"
+	}
+
+	baseURL, ok := os.LookupEnv("FILES_BASE_URL")
+	if !ok {
+		baseURL = "/files"
+	}
+
+	return &storage.Local{Root: root, BaseURL: baseURL}
+}
+
+func int64Env(key string, def int64) int64 {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || n <= 0 {
+		log.Printf("invalid %s value %q, fallback to %d", key, val, def)
+		return def
+	}
+
+	return n
+}
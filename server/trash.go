@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/zufzuf/cake-store/libs/logger"
+	"go.uber.org/zap"
+)
+
+// purgeTrash permanently delete a trashed cakes which older than TRASH_RETENTION (default 30 days),
+// it's running on every TRASH_PURGE_INTERVAL (default 1 hour) until the context is done
+func (hs *HTTPServer) purgeTrash(ctx context.Context) {
+	retention := durationEnv("TRASH_RETENTION", 30*24*time.Hour)
+	interval := durationEnv("TRASH_PURGE_INTERVAL", time.Hour)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := hs.TrashPurger.PurgeTrashed(ctx, retention)
+			if err != nil {
+				logger.Log.With(zap.Error(err)).Error("purge trashed cakes, an error occurred")
+				continue
+			}
+			if n > 0 {
+				logger.Log.With(zap.Int64("purged", n)).Info("purge trashed cakes")
+			}
+		}
+	}
+}
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Printf("invalid %s value %q, fallback to %s", key, val, def)
+		return def
+	}
+
+	return d
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+)
+
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+const (
+	BatchStatusCreated    = "created"
+	BatchStatusUpdated    = "updated"
+	BatchStatusDeleted    = "deleted"
+	BatchStatusInvalid    = "invalid"
+	BatchStatusFailed     = "failed"
+	BatchStatusRolledBack = "rolled_back"
+)
+
+var ErrBatchFailed = eris.New("batch failed, every operations are rolled back")
+
+// BatchOperation is a create, update or delete of a cake. Cake is a body of POST /cakes for a create
+// and PATCH /cakes/{id} for an update, Version is only applied on the same version like If-Match.
+// a rating is not writable by a batch, it's only computed from the reviews
+type BatchOperation struct {
+	Op      string       `json:"op" validate:"required,oneof=create update delete"`
+	ID      int          `json:"id" validate:"min=0"`
+	Version int          `json:"version" validate:"min=0"`
+	Cake    *CakeRequest `json:"cake"`
+}
+
+// validate validate an operation, id is required by an update and a delete and cake by a create and an update
+func (op *BatchOperation) validate() []util.ValidationError {
+	errs := util.Validation(op)
+
+	if (op.Op == BatchOpUpdate || op.Op == BatchOpDelete) && op.ID == 0 {
+		errs = append(errs, util.ValidationError{
+			Key:     "id",
+			Rule:    "required",
+			Message: "id is a required field of an update and a delete",
+		})
+	}
+	if (op.Op == BatchOpCreate || op.Op == BatchOpUpdate) && op.Cake == nil {
+		errs = append(errs, util.ValidationError{
+			Key:     "cake",
+			Rule:    "required",
+			Message: "cake is a required field of a create and an update",
+		})
+	}
+
+	return errs
+}
+
+type BatchRequest struct {
+	// Atomic roll back every operations on the first failure, otherwise only the failed operation is
+	// rolled back and the others are committed. it's true when omitted
+	Atomic *bool `json:"atomic"`
+
+	// Operations is applied in order, a batch has at most 100 operations
+	Operations []BatchOperation `json:"operations" validate:"required,min=1,max=100"`
+}
+
+func (r *BatchRequest) atomic() bool {
+	return r.Atomic == nil || *r.Atomic
+}
+
+type BatchResult struct {
+	Index   int                    `json:"index"`
+	Op      string                 `json:"op"`
+	ID      int                    `json:"id,omitempty"`
+	Version int                    `json:"version,omitempty"`
+	Status  string                 `json:"status"`
+	Errors  []util.ValidationError `json:"errors,omitempty"`
+}
+
+type BatchReport struct {
+	Atomic    bool          `json:"atomic"`
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// Batch apply the operations in order on a single transaction, each operation is validated on it's own.
+// an atomic batch stop applying after the first failure and it's rolled back, the remaining operations
+// are still validated so every invalid operations is reported. the report is returned with ErrBatchFailed
+// when it's rolled back. otherwise each operation run in a savepoint, so a failed one is rolled back alone
+func (s *Cake) Batch(ctx context.Context, req *BatchRequest) (*BatchReport, error) {
+	if req == nil {
+		return nil, ErrRequestNil
+	}
+
+	var res *BatchReport
+	err := withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		// the transaction may be retried, so the report start over
+		res = &BatchReport{Atomic: req.atomic(), Results: []BatchResult{}}
+
+		for i, op := range req.Operations {
+			res.Total++
+			result := BatchResult{Index: i, Op: op.Op, ID: op.ID, Status: BatchStatusInvalid}
+
+			result.Errors = op.validate()
+			switch {
+			case len(result.Errors) > 0:
+			case res.Atomic && res.Failed > 0:
+				// the operation is valid, but nothing is applied anymore
+				result.Status = BatchStatusRolledBack
+			default:
+				var err error
+				if res.Atomic {
+					err = s.applyBatch(ctx, op, &result)
+				} else {
+					// a failed operation is rolled back alone
+					err = withinSavepoint(ctx, s.Tx, func(ctx context.Context) error {
+						return s.applyBatch(ctx, op, &result)
+					})
+				}
+
+				// an unexpected error abort the whole batch since the transaction may not continue,
+				// e.g. a deadlock which is retried
+				if err != nil && result.Status != BatchStatusFailed {
+					return err
+				}
+			}
+
+			switch result.Status {
+			case BatchStatusInvalid, BatchStatusFailed:
+				res.Failed++
+			case BatchStatusRolledBack:
+			default:
+				res.Succeeded++
+			}
+			res.Results = append(res.Results, result)
+		}
+
+		if res.Atomic && res.Failed > 0 {
+			return ErrBatchFailed
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrBatchFailed) {
+			return nil, err
+		}
+
+		for i := range res.Results {
+			switch res.Results[i].Status {
+			case BatchStatusCreated:
+				res.Results[i].ID, res.Results[i].Status = 0, BatchStatusRolledBack
+			case BatchStatusUpdated, BatchStatusDeleted:
+				res.Results[i].Version, res.Results[i].Status = 0, BatchStatusRolledBack
+			}
+		}
+		res.Succeeded = 0
+
+		return res, ErrBatchFailed
+	}
+
+	return res, nil
+}
+
+// applyBatch apply an operation into it's result, the error is returned so a savepoint is rolled back.
+// the result is only failed by an error of the operation, e.g. a version conflict
+func (s *Cake) applyBatch(ctx context.Context, op BatchOperation, result *BatchResult) error {
+	var err error
+	switch op.Op {
+	case BatchOpCreate:
+		req := *op.Cake
+		if err = s.Insert(ctx, &req); err == nil {
+			result.ID, result.Status = req.ID, BatchStatusCreated
+		}
+	case BatchOpUpdate:
+		req := *op.Cake
+		req.ID, req.Version = op.ID, op.Version
+		if err = s.Update(ctx, &req); err == nil {
+			result.Version, result.Status = req.Version, BatchStatusUpdated
+		}
+	case BatchOpDelete:
+		if err = s.Delete(ctx, op.ID, op.Version); err == nil {
+			result.Status = BatchStatusDeleted
+		}
+	}
+
+	if errs := batchErrors(err); errs != nil {
+		result.Status, result.Errors = BatchStatusFailed, errs
+	}
+	return err
+}
+
+// batchErrors describe an error of an operation, nil is returned for an unexpected error
+func batchErrors(err error) []util.ValidationError {
+	switch {
+	case errors.Is(err, ErrCategoryNotFound):
+		return insertErrors(err)
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return []util.ValidationError{{
+			Key:     "id",
+			Rule:    "exists",
+			Message: "id must be an existing cake",
+		}}
+	case errors.Is(err, repository.ErrVersionConflict):
+		return []util.ValidationError{{
+			Key:     "version",
+			Rule:    "version",
+			Message: "version must be the current version of the cake",
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Cake_Service_Batch(t *testing.T) {
+	util.NewValidator()
+
+	var (
+		repo = &repository.CakeMock{Mock: mock.Mock{}}
+		tx   = &repository.TransactorMock{Mock: mock.Mock{}}
+		srv  = &service.Cake{Repo: repo, Tx: tx}
+	)
+
+	cake := func(title string) *service.CakeRequest {
+		return &service.CakeRequest{
+			Title:       title,
+			Description: "Test Description",
+			Image:       "https://example.com/cake.jpeg",
+		}
+	}
+	atomic := func(v bool) *bool { return &v }
+
+	cur := &schema.Cake{ID: 3, Title: "Test Title", Image: "https://example.com/cake.jpeg", Currency: "IDR", Version: 2}
+	inserted := func(args mock.Arguments) {
+		args.Get(1).(*schema.Cake).ID = 7
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Times(4)
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted).Once()
+		repo.Mock.On("Find", context.Background(), 3).Return(cur, nil).Twice()
+		repo.Mock.On("Update", context.Background(), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).Version = 3
+		}).Once()
+		repo.Mock.On("Delete", context.Background(), 3, 0).Return(nil).Once()
+
+		res, err := srv.Batch(context.Background(), &service.BatchRequest{Operations: []service.BatchOperation{
+			{Op: service.BatchOpCreate, Cake: cake("Lemon")},
+			{Op: service.BatchOpUpdate, ID: 3, Version: 2, Cake: cake("Carrot")},
+			{Op: service.BatchOpDelete, ID: 3},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, &service.BatchReport{Atomic: true, Total: 3, Succeeded: 3, Results: []service.BatchResult{
+			{Index: 0, Op: service.BatchOpCreate, ID: 7, Status: service.BatchStatusCreated},
+			{Index: 1, Op: service.BatchOpUpdate, ID: 3, Version: 3, Status: service.BatchStatusUpdated},
+			{Index: 2, Op: service.BatchOpDelete, ID: 3, Status: service.BatchStatusDeleted},
+		}}, res)
+	})
+
+	t.Run("Atomic_Rolled_Back", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Times(3)
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted).Once()
+		repo.Mock.On("Find", context.Background(), 3).Return(cur, nil).Once()
+
+		// every operations is still validated after the first failure, but nothing is applied
+		res, err := srv.Batch(context.Background(), &service.BatchRequest{Atomic: atomic(true), Operations: []service.BatchOperation{
+			{Op: service.BatchOpCreate, Cake: cake("Lemon")},
+			{Op: service.BatchOpUpdate, ID: 3, Version: 1, Cake: cake("Carrot")},
+			{Op: service.BatchOpCreate},
+			{Op: service.BatchOpDelete, ID: 3},
+		}})
+		assert.ErrorIs(t, err, service.ErrBatchFailed)
+		assert.Equal(t, 4, res.Total)
+		assert.Equal(t, 0, res.Succeeded)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, []string{
+			service.BatchStatusRolledBack,
+			service.BatchStatusFailed,
+			service.BatchStatusInvalid,
+			service.BatchStatusRolledBack,
+		}, []string{res.Results[0].Status, res.Results[1].Status, res.Results[2].Status, res.Results[3].Status})
+		assert.Zero(t, res.Results[0].ID)
+		assert.Equal(t, "version", res.Results[1].Errors[0].Key)
+		assert.Equal(t, []util.ValidationError{{Key: "cake", Rule: "required", Message: "cake is a required field of a create and an update"}}, res.Results[2].Errors)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Times(4)
+		tx.Mock.On("WithinSavepoint", context.Background()).Return(nil).Times(3)
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted).Once()
+		repo.Mock.On("Find", context.Background(), 4).Return(nil, repository.ErrRecordNotFound).Once()
+		repo.Mock.On("Find", context.Background(), 3).Return(cur, nil).Once()
+		repo.Mock.On("Update", context.Background(), mock.Anything).Return(nil).Once()
+
+		// a failed operation does not stop the next ones
+		res, err := srv.Batch(context.Background(), &service.BatchRequest{Atomic: atomic(false), Operations: []service.BatchOperation{
+			{Op: service.BatchOpCreate, Cake: cake("Lemon")},
+			{Op: service.BatchOpDelete, ID: 4},
+			{Op: "rename", ID: 3},
+			{Op: service.BatchOpUpdate, ID: 3, Cake: cake("Carrot")},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, service.BatchStatusCreated, res.Results[0].Status)
+		assert.Equal(t, []util.ValidationError{{Key: "id", Rule: "exists", Message: "id must be an existing cake"}}, res.Results[1].Errors)
+		assert.Equal(t, service.BatchStatusInvalid, res.Results[2].Status)
+		assert.Equal(t, service.BatchStatusUpdated, res.Results[3].Status)
+	})
+
+	t.Run("Unexpected_Error", func(t *testing.T) {
+		failed := errors.New("insert failed")
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Twice()
+		tx.Mock.On("WithinSavepoint", context.Background()).Return(nil).Once()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(failed).Once()
+
+		// an unexpected error abort the whole batch
+		res, err := srv.Batch(context.Background(), &service.BatchRequest{Atomic: atomic(false), Operations: []service.BatchOperation{
+			{Op: service.BatchOpCreate, Cake: cake("Lemon")},
+			{Op: service.BatchOpDelete, ID: 3},
+		}})
+		assert.ErrorIs(t, err, failed)
+		assert.Nil(t, res)
+	})
+
+	t.Run("Request_Nil", func(t *testing.T) {
+		res, err := srv.Batch(context.Background(), nil)
+		assert.ErrorIs(t, err, service.ErrRequestNil)
+		assert.Nil(t, res)
+	})
+
+	repo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
@@ -2,36 +2,180 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
 	"github.com/zufzuf/cake-store/repository"
 	"github.com/zufzuf/cake-store/schema"
 )
 
 var (
 	ErrRequestNil = eris.New("request is nil")
+
+	ErrCategoryNotFound = eris.New("category not found")
+
+	// ErrNotSupported is returned by a read of an optional dependency which is nil
+	ErrNotSupported = eris.New("not supported")
 )
 
+// DefaultCurrency is used for a new cake without a currency
+const DefaultCurrency = "IDR"
+
 type CakeRepository interface {
 	Find(ctx context.Context, id int) (*schema.Cake, error)
 	FindAll(ctx context.Context, fil *repository.FindAllFilter) ([]schema.Cake, error)
 	Insert(ctx context.Context, rec *schema.Cake) error
 	Update(ctx context.Context, rec *schema.Cake) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int, version int) error
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error
+	PurgeTrashed(ctx context.Context, before time.Time) (int64, error)
+	Truncate(ctx context.Context) error
+	Stream(ctx context.Context, fil *repository.FindAllFilter, fn func(rec *schema.Cake) error) error
+}
+
+type CakeRevisionRepository interface {
+	Find(ctx context.Context, cakeID, id int) (*schema.CakeRevision, error)
+	FindAll(ctx context.Context, cakeID int) ([]schema.CakeRevision, error)
+	Insert(ctx context.Context, rec *schema.CakeRevision) error
+	Truncate(ctx context.Context) error
+}
+
+type CakePriceRepository interface {
+	FindAll(ctx context.Context, fil *repository.PriceFilter) ([]schema.CakePrice, error)
+	Insert(ctx context.Context, rec *schema.CakePrice) error
+}
+
+type AllergenRepository interface {
+	FindByCakeIDs(ctx context.Context, ids []int) (map[int][]string, error)
+	SetCakeAllergens(ctx context.Context, cakeID int, allergens []string) error
+}
+
+type NutritionRepository interface {
+	FindByCakeIDs(ctx context.Context, ids []int) (map[int]*schema.Nutrition, error)
+	SetCakeNutrition(ctx context.Context, cakeID int, rec *schema.Nutrition) error
 }
 
 type Cake struct {
 	Repo CakeRepository
+
+	// Revisions record an audit trail of every insert, update, delete, restore and purge, it's skipped when nil
+	Revisions CakeRevisionRepository
+
+	// Categories and Tags classify a cakes, it's skipped when nil
+	Categories CategoryRepository
+	Tags       TagRepository
+
+	// Images is a cake gallery, an image which is set through the request become the primary image,
+	// it's skipped when nil
+	Images CakeImageRepository
+
+	// Prices record a price history of every price change, it's skipped when nil
+	Prices CakePriceRepository
+
+	// Allergens and Nutrition is a dietary information of a cakes, it's skipped when nil
+	Allergens AllergenRepository
+	Nutrition NutritionRepository
+
+	// Variants is only loaded by Find when EmbedVariants is requested, it's skipped when nil
+	Variants CakeVariantRepository
+
+	// Tx make a write and it's relations atomic, a write is not transactional when nil
+	Tx Transactor
 }
 
-func (s *Cake) Find(ctx context.Context, id int) (*schema.Cake, error) {
-	return s.Repo.Find(ctx, id)
+// EmbedVariants is an embed option of Find to load a variants of the cake
+const EmbedVariants = "variants"
+
+// Embeds is a supported embed options of Find
+var Embeds = []string{EmbedVariants}
+
+// Find find a cake with it's relations, embed load an optional relations such as EmbedVariants
+func (s *Cake) Find(ctx context.Context, id int, embed ...string) (*schema.Cake, error) {
+	res, err := s.Repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cakes := []schema.Cake{*res}
+	if err := s.loadRelations(ctx, cakes); err != nil {
+		return nil, err
+	}
+
+	for _, e := range embed {
+		if e != EmbedVariants || s.Variants == nil {
+			continue
+		}
+
+		variants, err := s.Variants.FindAll(ctx, id)
+		if err != nil && !eris.Is(err, repository.ErrRecordNotFound) {
+			return nil, err
+		}
+		cakes[0].Variants = append([]schema.CakeVariant{}, variants...)
+	}
+
+	return &cakes[0], nil
 }
 
 type FindAllRequest struct {
+	Trashed bool `json:"-"`
+
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	Search      string `json:"q"`
+	SearchMode  string `json:"search_mode"`
+	Category    string `json:"category"`
+	Tag         string `json:"tag"`
+	InStock     bool   `json:"in_stock"`
+
+	ExcludeAllergens []string `json:"exclude_allergens"`
+
+	RatingMin     *float64  `json:"rating_min"`
+	RatingMax     *float64  `json:"rating_max"`
+	Currency      string    `json:"currency"`
+	PriceMin      *int64    `json:"price_min"`
+	PriceMax      *int64    `json:"price_max"`
+	CreatedAfter  time.Time `json:"created_after"`
+	CreatedBefore time.Time `json:"created_before"`
+	UpdatedSince  time.Time `json:"updated_since"`
+
+	Sort   []repository.SortField `json:"sort"`
+	Limit  int                    `json:"limit"`
+	Cursor string                 `json:"cursor"`
+
+	// NextCursor and HasMore is filled by FindAll
+	NextCursor string `json:"-"`
+	HasMore    bool   `json:"-"`
+}
+
+// filter convert a request into a repository filter
+func (r *FindAllRequest) filter() repository.FindAllFilter {
+	return repository.FindAllFilter{
+		Trashed:          r.Trashed,
+		Title:            r.Title,
+		Description:      r.Description,
+		Search:           r.Search,
+		SearchMode:       r.SearchMode,
+		Category:         r.Category,
+		Tag:              r.Tag,
+		InStock:          r.InStock,
+		ExcludeAllergens: r.ExcludeAllergens,
+		RatingMin:        r.RatingMin,
+		RatingMax:        r.RatingMax,
+		Currency:         r.Currency,
+		PriceMin:         r.PriceMin,
+		PriceMax:         r.PriceMax,
+		CreatedAfter:     r.CreatedAfter,
+		CreatedBefore:    r.CreatedBefore,
+		UpdatedSince:     r.UpdatedSince,
+		Sort:             r.Sort,
+		Limit:            r.Limit,
+		Cursor:           r.Cursor,
+	}
 }
 
 func (s *Cake) FindAll(ctx context.Context, req *FindAllRequest) ([]schema.Cake, error) {
@@ -39,20 +183,193 @@ func (s *Cake) FindAll(ctx context.Context, req *FindAllRequest) ([]schema.Cake,
 		return nil, ErrRequestNil
 	}
 
-	fil := repository.FindAllFilter{
-		Title:       req.Title,
-		Description: req.Description,
+	fil := req.filter()
+	res, err := s.Repo.FindAll(ctx, &fil)
+	if err != nil {
+		return nil, err
+	}
+	req.NextCursor, req.HasMore = fil.NextCursor, fil.HasMore
+
+	if err := s.loadRelations(ctx, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// loadRelations load a categories, tags, images, allergens and nutrition of the cakes
+func (s *Cake) loadRelations(ctx context.Context, cakes []schema.Cake) error {
+	ids := make([]int, len(cakes))
+	for i := range cakes {
+		ids[i] = cakes[i].ID
+	}
+
+	if s.Categories != nil {
+		categories, err := s.Categories.FindByCakeIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range cakes {
+			cakes[i].Categories = append([]schema.Category{}, categories[cakes[i].ID]...)
+		}
+	}
+
+	if s.Tags != nil {
+		tags, err := s.Tags.FindByCakeIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range cakes {
+			cakes[i].Tags = append([]schema.Tag{}, tags[cakes[i].ID]...)
+		}
+	}
+
+	if s.Images != nil {
+		images, err := s.Images.FindByCakeIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range cakes {
+			cakes[i].Images = append([]schema.CakeImage{}, images[cakes[i].ID]...)
+		}
 	}
 
-	return s.Repo.FindAll(ctx, &fil)
+	if s.Allergens != nil {
+		allergens, err := s.Allergens.FindByCakeIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range cakes {
+			cakes[i].Allergens = append([]string{}, allergens[cakes[i].ID]...)
+		}
+	}
+
+	if s.Nutrition != nil {
+		nutrition, err := s.Nutrition.FindByCakeIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range cakes {
+			cakes[i].Nutrition = nutrition[cakes[i].ID]
+		}
+	}
+
+	return nil
+}
+
+// checkCategories make sure every category is exist
+func (s *Cake) checkCategories(ctx context.Context, ids []int) error {
+	if s.Categories == nil {
+		return nil
+	}
+
+	for _, id := range ids {
+		if _, err := s.Categories.Find(ctx, id); err != nil {
+			if eris.Is(err, repository.ErrRecordNotFound) {
+				return ErrCategoryNotFound
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// classify replace a categories and tags of a cake, a nil value keep the current one
+func (s *Cake) classify(ctx context.Context, id int, req *CakeRequest) error {
+	if s.Categories != nil && req.CategoryIDs != nil {
+		if err := s.Categories.SetCakeCategories(ctx, id, req.CategoryIDs); err != nil {
+			return err
+		}
+	}
+
+	if s.Tags != nil && req.Tags != nil {
+		if err := s.Tags.SetCakeTags(ctx, id, normalizeTags(req.Tags)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// label replace an allergens and nutrition facts of a cake, a nil value keep the current one
+func (s *Cake) label(ctx context.Context, id int, req *CakeRequest) error {
+	if s.Allergens != nil && req.Allergens != nil {
+		if err := s.Allergens.SetCakeAllergens(ctx, id, req.Allergens); err != nil {
+			return err
+		}
+	}
+
+	if s.Nutrition != nil && req.Nutrition != nil {
+		if err := s.Nutrition.SetCakeNutrition(ctx, id, req.Nutrition.nutrition()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setPrimaryImage add an image url of a cake into it's gallery as the primary image,
+// the cake image is already the url so the cake is not changed again
+func (s *Cake) setPrimaryImage(ctx context.Context, rec *schema.Cake) error {
+	if s.Images == nil || len(rec.Image) == 0 {
+		return nil
+	}
+
+	return s.Images.Insert(ctx, &schema.CakeImage{
+		CakeID:    rec.ID,
+		Image:     schema.Image{URL: rec.Image, Thumbnails: []schema.Thumbnail{}},
+		Alt:       rec.Title,
+		Primary:   true,
+		CreatedAt: time.Now(),
+	})
 }
 
 type CakeRequest struct {
-	ID          int     `json:"-"`
-	Title       string  `json:"title" validate:"required"`
-	Description string  `json:"description" validate:"required"`
-	Rating      float64 `json:"rating" validate:"required"`
-	Image       string  `json:"image" validate:"required"`
+	ID          int    `json:"-"`
+	Version     int    `json:"-"`
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description" validate:"required"`
+	Image       string `json:"image" validate:"required"`
+
+	// Price is in a minor unit of the Currency, omit it to keep the current one.
+	// a new cake fallback to a zero price in DefaultCurrency
+	Price    *int64 `json:"price" validate:"omitempty,min=0"`
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+
+	// CategoryIDs and Tags replace a classifications of the cake, omit it to keep the current one
+	CategoryIDs []int    `json:"category_ids" validate:"omitempty,unique,dive,min=1"`
+	Tags        []string `json:"tags" validate:"omitempty,dive,required,max=64"`
+
+	// Allergens and Nutrition replace a dietary information of the cake, omit it to keep the current one.
+	// an allergen must be one of schema.Allergens
+	Allergens []string          `json:"allergens" validate:"omitempty,unique,dive,allergen"`
+	Nutrition *NutritionRequest `json:"nutrition"`
+}
+
+// NutritionRequest is a nutrition facts per serving, see schema.Nutrition
+type NutritionRequest struct {
+	ServingSize  float64 `json:"serving_size" validate:"gt=0"`
+	Energy       float64 `json:"energy" validate:"min=0"`
+	Fat          float64 `json:"fat" validate:"min=0"`
+	SaturatedFat float64 `json:"saturated_fat" validate:"min=0,ltefield=Fat"`
+	Carbohydrate float64 `json:"carbohydrate" validate:"min=0"`
+	Sugars       float64 `json:"sugars" validate:"min=0,ltefield=Carbohydrate"`
+	Protein      float64 `json:"protein" validate:"min=0"`
+	Salt         float64 `json:"salt" validate:"min=0"`
+}
+
+func (r *NutritionRequest) nutrition() *schema.Nutrition {
+	return &schema.Nutrition{
+		ServingSize:  r.ServingSize,
+		Energy:       r.Energy,
+		Fat:          r.Fat,
+		SaturatedFat: r.SaturatedFat,
+		Carbohydrate: r.Carbohydrate,
+		Sugars:       r.Sugars,
+		Protein:      r.Protein,
+		Salt:         r.Salt,
+	}
 }
 
 func (s *Cake) Insert(ctx context.Context, req *CakeRequest) error {
@@ -60,46 +377,336 @@ func (s *Cake) Insert(ctx context.Context, req *CakeRequest) error {
 		return ErrRequestNil
 	}
 
-	timeNow := time.Now()
-	rec := schema.Cake{
-		Title:       req.Title,
-		Description: req.Description,
-		Rating:      req.Rating,
-		Image:       req.Image,
-		CreatedAt:   timeNow,
-		UpdatedAt:   timeNow,
-	}
-	if err := s.Repo.Insert(ctx, &rec); err != nil {
+	var id int
+	err := withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		if err := s.checkCategories(ctx, req.CategoryIDs); err != nil {
+			return err
+		}
+
+		timeNow := time.Now()
+		rec := schema.Cake{
+			Title:       req.Title,
+			Description: req.Description,
+			Image:       req.Image,
+			Currency:    DefaultCurrency,
+			CreatedAt:   timeNow,
+			UpdatedAt:   timeNow,
+		}
+		priced(&rec, req)
+		if err := s.Repo.Insert(ctx, &rec); err != nil {
+			return err
+		}
+		id = rec.ID
+
+		if err := s.reprice(ctx, &rec); err != nil {
+			return err
+		}
+
+		if err := s.classify(ctx, rec.ID, req); err != nil {
+			return err
+		}
+
+		if err := s.label(ctx, rec.ID, req); err != nil {
+			return err
+		}
+
+		if err := s.setPrimaryImage(ctx, &rec); err != nil {
+			return err
+		}
+
+		return s.revise(ctx, schema.RevisionActionInsert, rec.ID, nil, &rec)
+	})
+	if err != nil {
 		return err
 	}
-	req.ID = rec.ID
+	req.ID = id
 
 	return nil
 }
 
+// Update update a cake, when req.Version is set the update is only applied on the same version
+// and req.Version is increased, otherwise repository.ErrVersionConflict is returned
 func (s *Cake) Update(ctx context.Context, req *CakeRequest) error {
 	if req == nil {
 		return ErrRequestNil
 	}
 
-	if _, err := s.Repo.Find(ctx, req.ID); err != nil {
+	// req is only changed after a commit, so a retried transaction see the requested version
+	var version int
+	err := withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		cur, err := s.Repo.Find(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		if req.Version > 0 && cur.Version != req.Version {
+			return repository.ErrVersionConflict
+		}
+
+		if err := s.checkCategories(ctx, req.CategoryIDs); err != nil {
+			return err
+		}
+
+		rec := schema.Cake{
+			ID:          req.ID,
+			Title:       req.Title,
+			Description: req.Description,
+			Image:       req.Image,
+			Price:       cur.Price,
+			Currency:    cur.Currency,
+			UpdatedAt:   time.Now(),
+			Version:     req.Version,
+		}
+		priced(&rec, req)
+		if err := s.Repo.Update(ctx, &rec); err != nil {
+			return err
+		}
+		version = rec.Version
+
+		if rec.Price != cur.Price || rec.Currency != cur.Currency {
+			if err := s.reprice(ctx, &rec); err != nil {
+				return err
+			}
+		}
+
+		if err := s.classify(ctx, rec.ID, req); err != nil {
+			return err
+		}
+
+		if err := s.label(ctx, rec.ID, req); err != nil {
+			return err
+		}
+
+		if rec.Image != cur.Image {
+			if err := s.setPrimaryImage(ctx, &rec); err != nil {
+				return err
+			}
+		}
+
+		return s.revise(ctx, schema.RevisionActionUpdate, rec.ID, cur, updated(cur, &rec))
+	})
+	if err != nil {
 		return err
 	}
+	req.Version = version
+
+	return nil
+}
+
+// Delete soft delete a cake, when version is set the delete is only applied on the same version
+func (s *Cake) Delete(ctx context.Context, id int, version int) error {
+	return withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		cur, err := s.Repo.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version > 0 && cur.Version != version {
+			return repository.ErrVersionConflict
+		}
+		if err := s.Repo.Delete(ctx, id, version); err != nil {
+			return err
+		}
 
-	rec := schema.Cake{
-		ID:          req.ID,
-		Title:       req.Title,
-		Description: req.Description,
-		Rating:      req.Rating,
-		Image:       req.Image,
-		UpdatedAt:   time.Now(),
+		return s.revise(ctx, schema.RevisionActionDelete, id, cur, nil)
+	})
+}
+
+// updated merge an updated fields into a copy of the current record, as an after snapshot
+func updated(cur *schema.Cake, rec *schema.Cake) *schema.Cake {
+	if cur == nil {
+		return rec
 	}
-	return s.Repo.Update(ctx, &rec)
+
+	res := *cur
+	res.Title = rec.Title
+	res.Description = rec.Description
+	res.Image = rec.Image
+	res.Price = rec.Price
+	res.Currency = rec.Currency
+	res.UpdatedAt = rec.UpdatedAt
+	res.Version = cur.Version + 1
+
+	return &res
 }
 
-func (s *Cake) Delete(ctx context.Context, id int) error {
-	if _, err := s.Repo.Find(ctx, id); err != nil {
-		return err
+// priced apply a requested price and currency on a record, an omitted one keep the record value
+func priced(rec *schema.Cake, req *CakeRequest) {
+	if req.Price != nil {
+		rec.Price = *req.Price
+	}
+	if len(req.Currency) > 0 {
+		rec.Currency = req.Currency
+	}
+}
+
+// reprice record a current price of a cake into it's price history, effective from now
+func (s *Cake) reprice(ctx context.Context, rec *schema.Cake) error {
+	if s.Prices == nil {
+		return nil
+	}
+
+	return s.Prices.Insert(ctx, &schema.CakePrice{
+		CakeID:        rec.ID,
+		Price:         rec.Price,
+		Currency:      rec.Currency,
+		EffectiveFrom: time.Now(),
+		TrackerID:     util.CTXTracker(ctx),
+	})
+}
+
+type PriceHistoryRequest struct {
+	CakeID int `json:"-"`
+	Limit  int `json:"limit"`
+	Before int `json:"before"`
+}
+
+// PriceHistory list a price history of a cake, the latest price first
+func (s *Cake) PriceHistory(ctx context.Context, req *PriceHistoryRequest) ([]schema.CakePrice, error) {
+	if req == nil {
+		return nil, ErrRequestNil
+	}
+	if s.Prices == nil {
+		return nil, ErrNotSupported
+	}
+
+	return s.Prices.FindAll(ctx, &repository.PriceFilter{
+		CakeID: req.CakeID,
+		Limit:  req.Limit,
+		Before: req.Before,
+	})
+}
+
+// revise write an immutable revision of a change with it's tracker id
+func (s *Cake) revise(ctx context.Context, action string, id int, before, after *schema.Cake) error {
+	if s.Revisions == nil {
+		return nil
+	}
+
+	rev := schema.CakeRevision{
+		CakeID:    id,
+		Action:    action,
+		TrackerID: util.CTXTracker(ctx),
+		CreatedAt: time.Now(),
+	}
+
+	var err error
+	if before != nil {
+		if rev.Before, err = json.Marshal(before); err != nil {
+			return eris.Wrap(err, "revise cake, an error occurred")
+		}
+	}
+	if after != nil {
+		if rev.After, err = json.Marshal(after); err != nil {
+			return eris.Wrap(err, "revise cake, an error occurred")
+		}
+	}
+
+	return s.Revisions.Insert(ctx, &rev)
+}
+
+// History list a revisions of a cake, it's still available after the cake is deleted
+func (s *Cake) History(ctx context.Context, id int) ([]schema.CakeRevision, error) {
+	if s.Revisions == nil {
+		return nil, ErrNotSupported
 	}
-	return s.Repo.Delete(ctx, id)
+	return s.Revisions.FindAll(ctx, id)
+}
+
+// Diff compare a cake state after the from revision with a state after the to revision,
+// a deleted state is an empty state
+func (s *Cake) Diff(ctx context.Context, id int, from, to int) ([]schema.FieldChange, error) {
+	if s.Revisions == nil {
+		return nil, ErrNotSupported
+	}
+
+	revFrom, err := s.Revisions.Find(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+
+	revTo, err := s.Revisions.Find(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stateFrom, err := snapshot(revFrom.After)
+	if err != nil {
+		return nil, err
+	}
+
+	stateTo, err := snapshot(revTo.After)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for k := range stateFrom {
+		fields = append(fields, k)
+	}
+	for k := range stateTo {
+		if _, ok := stateFrom[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	res := []schema.FieldChange{}
+	for _, f := range fields {
+		if reflect.DeepEqual(stateFrom[f], stateTo[f]) {
+			continue
+		}
+		res = append(res, schema.FieldChange{
+			Field: f,
+			From:  stateFrom[f],
+			To:    stateTo[f],
+		})
+	}
+
+	return res, nil
+}
+
+func snapshot(data json.RawMessage) (map[string]any, error) {
+	res := map[string]any{}
+	if len(data) == 0 {
+		return res, nil
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, eris.Wrap(err, "decode cake snapshot, an error occurred")
+	}
+	return res, nil
+}
+
+// Restore restore a trashed cake, it's revised with the restored cake as an after snapshot
+func (s *Cake) Restore(ctx context.Context, id int) error {
+	return withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		if err := s.Repo.Restore(ctx, id); err != nil {
+			return err
+		}
+		if s.Revisions == nil {
+			return nil
+		}
+
+		rec, err := s.Repo.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.revise(ctx, schema.RevisionActionRestore, id, nil, rec)
+	})
+}
+
+// Purge permanently delete a trashed cake, it's revised without a snapshot since the last state is
+// recorded by the delete revision
+func (s *Cake) Purge(ctx context.Context, id int) error {
+	return withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		if err := s.Repo.Purge(ctx, id); err != nil {
+			return err
+		}
+
+		return s.revise(ctx, schema.RevisionActionPurge, id, nil, nil)
+	})
+}
+
+// PurgeTrashed permanently delete every cakes which stay in a trash longer than the retention
+func (s *Cake) PurgeTrashed(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.Repo.PurgeTrashed(ctx, time.Now().Add(-retention))
 }
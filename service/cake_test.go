@@ -2,11 +2,14 @@ package service_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/libs/util"
 	"github.com/zufzuf/cake-store/repository"
 	"github.com/zufzuf/cake-store/schema"
 	"github.com/zufzuf/cake-store/service"
@@ -21,6 +24,8 @@ var cake = schema.Cake{
 	Description: "Test Description",
 	Rating:      7,
 	Image:       "https://cdn.lorem.space/images/movie/.cache/150x220/totoro-1988.jpeg",
+	Price:       150000,
+	Currency:    "IDR",
 	CreatedAt:   time.Now(),
 	UpdatedAt:   time.Now(),
 }
@@ -122,6 +127,19 @@ func Test_Cake_Service_Find_All(t *testing.T) {
 			ExpectedResult: cakes,
 			ExpectedError:  nil,
 		},
+		{
+			Name: "Found_With_Pagination",
+			Filter: repository.FindAllFilter{
+				Limit:  1,
+				Cursor: "cursor",
+			},
+			Request: service.FindAllRequest{
+				Limit:  1,
+				Cursor: "cursor",
+			},
+			ExpectedResult: cakes[:1],
+			ExpectedError:  nil,
+		},
 		{
 			Name: "Found_With_All_Filter",
 			Filter: repository.FindAllFilter{
@@ -158,6 +176,8 @@ func Test_Cake_Service_Insert(t *testing.T) {
 	record.ID = 0
 	record.CreatedAt = time.Time{}
 	record.UpdatedAt = time.Time{}
+	// a rating is computed from the reviews, it's never written from a request
+	record.Rating = 0
 
 	tests := []struct {
 		Name          string
@@ -171,8 +191,8 @@ func Test_Cake_Service_Insert(t *testing.T) {
 			Request: &service.CakeRequest{
 				Title:       record.Title,
 				Description: record.Description,
-				Rating:      record.Rating,
 				Image:       record.Image,
+				Price:       &record.Price,
 			},
 			ExpectedError: nil,
 		},
@@ -202,6 +222,7 @@ func Test_Cake_Service_Update(t *testing.T) {
 	record := cake
 	record.CreatedAt = time.Time{}
 	record.UpdatedAt = time.Time{}
+	record.Rating = 0
 
 	tests := []struct {
 		Name              string
@@ -219,7 +240,6 @@ func Test_Cake_Service_Update(t *testing.T) {
 				ID:          record.ID,
 				Title:       record.Title,
 				Description: record.Description,
-				Rating:      record.Rating,
 				Image:       record.Image,
 			},
 			ExpectedError:     nil,
@@ -284,9 +304,9 @@ func Test_Cake_Service_Delete(t *testing.T) {
 		t.Run(test.Name, func(t *testing.T) {
 			notfound := test.Name == "Delete_Not_Found"
 			CakeRepository.Mock.On("Find", context.Background(), test.Request).Return(nil, test.ExpectedFindError).Once()
-			CakeRepository.Mock.On("Delete", context.Background(), test.Request).Return(test.ExpectedError).Once()
+			CakeRepository.Mock.On("Delete", context.Background(), test.Request, 0).Return(test.ExpectedError).Once()
 
-			err := CakeService.Delete(context.Background(), test.Request)
+			err := CakeService.Delete(context.Background(), test.Request, 0)
 			if notfound {
 				assert.Error(t, err)
 			} else {
@@ -295,3 +315,546 @@ func Test_Cake_Service_Delete(t *testing.T) {
 		})
 	}
 }
+
+func Test_Cake_Service_Version_Conflict(t *testing.T) {
+	current := cake
+	current.ID = 3
+	current.Version = 2
+
+	t.Run("Update", func(t *testing.T) {
+		CakeRepository.Mock.On("Find", context.Background(), current.ID).Return(&current, nil).Once()
+
+		err := CakeService.Update(context.Background(), &service.CakeRequest{
+			ID:      current.ID,
+			Version: 1,
+			Title:   current.Title,
+		})
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		CakeRepository.Mock.On("Find", context.Background(), current.ID).Return(&current, nil).Once()
+
+		err := CakeService.Delete(context.Background(), current.ID, 1)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	})
+
+	t.Run("Update_Versioned", func(t *testing.T) {
+		record := current
+		record.CreatedAt = time.Time{}
+		record.UpdatedAt = time.Time{}
+		record.Rating = 0
+
+		CakeRepository.Mock.On("Find", context.Background(), current.ID).Return(&current, nil).Once()
+		CakeRepository.Mock.On("Update", context.Background(), &record).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).Version++
+		}).Once()
+
+		req := service.CakeRequest{
+			ID:          current.ID,
+			Version:     current.Version,
+			Title:       current.Title,
+			Description: current.Description,
+			Image:       current.Image,
+		}
+		assert.NoError(t, CakeService.Update(context.Background(), &req))
+		assert.Equal(t, current.Version+1, req.Version)
+	})
+}
+
+func Test_Cake_Service_Transaction(t *testing.T) {
+	var (
+		repo = &repository.CakeMock{Mock: mock.Mock{}}
+		tx   = &repository.TransactorMock{Mock: mock.Mock{}}
+		srv  = &service.Cake{Repo: repo, Tx: tx}
+	)
+
+	t.Run("Update", func(t *testing.T) {
+		current := cake
+		current.Version = 2
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Once()
+		repo.Mock.On("Find", context.Background(), current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", context.Background(), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).Version = 3
+		}).Once()
+
+		req := service.CakeRequest{ID: current.ID, Title: "Test Title", Version: 2}
+		assert.NoError(t, srv.Update(context.Background(), &req))
+		assert.Equal(t, 3, req.Version)
+	})
+
+	t.Run("Update_Rolled_Back", func(t *testing.T) {
+		current := cake
+		current.Version = 2
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Once()
+		repo.Mock.On("Find", context.Background(), current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", context.Background(), mock.Anything).Return(repository.ErrVersionConflict).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).Version = 3
+		}).Once()
+
+		// a request is kept as it is, so a retry see the requested version
+		req := service.CakeRequest{ID: current.ID, Title: "Test Title", Version: 2}
+		assert.ErrorIs(t, srv.Update(context.Background(), &req), repository.ErrVersionConflict)
+		assert.Equal(t, 2, req.Version)
+	})
+
+	t.Run("Delete_Begin_Failed", func(t *testing.T) {
+		failed := errors.New("begin failed")
+		tx.Mock.On("WithinTx", context.Background()).Return(failed).Once()
+
+		assert.ErrorIs(t, srv.Delete(context.Background(), cake.ID, 0), failed)
+	})
+
+	repo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Restore(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Request       int
+		ExpectedError error
+	}{
+		{
+			Name:          "Restore_Success",
+			Request:       1,
+			ExpectedError: nil,
+		},
+		{
+			Name:          "Restore_Not_Found",
+			Request:       2,
+			ExpectedError: repository.ErrRecordNotFound,
+		},
+	}
+	for _, v := range tests {
+		test := v
+		t.Run(test.Name, func(t *testing.T) {
+			CakeRepository.Mock.On("Restore", context.Background(), test.Request).Return(test.ExpectedError).Once()
+
+			err := CakeService.Restore(context.Background(), test.Request)
+			assert.ErrorIs(t, err, test.ExpectedError)
+		})
+	}
+}
+
+func Test_Cake_Service_Purge(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Request       int
+		ExpectedError error
+	}{
+		{
+			Name:          "Purge_Success",
+			Request:       1,
+			ExpectedError: nil,
+		},
+		{
+			Name:          "Purge_Not_Found",
+			Request:       2,
+			ExpectedError: repository.ErrRecordNotFound,
+		},
+	}
+	for _, v := range tests {
+		test := v
+		t.Run(test.Name, func(t *testing.T) {
+			CakeRepository.Mock.On("Purge", context.Background(), test.Request).Return(test.ExpectedError).Once()
+
+			err := CakeService.Purge(context.Background(), test.Request)
+			assert.ErrorIs(t, err, test.ExpectedError)
+		})
+	}
+}
+
+func Test_Cake_Service_Purge_Trashed(t *testing.T) {
+	retention := 24 * time.Hour
+
+	CakeRepository.Mock.On("PurgeTrashed", context.Background(), mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= retention
+	})).Return(int64(2), nil).Once()
+
+	n, err := CakeService.PurgeTrashed(context.Background(), retention)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func Test_Cake_Service_Revision(t *testing.T) {
+	var (
+		repo      = &repository.CakeMock{Mock: mock.Mock{}}
+		revisions = &repository.CakeRevisionMock{Mock: mock.Mock{}}
+		srv       = &service.Cake{Repo: repo, Revisions: revisions}
+	)
+
+	t.Run("Insert", func(t *testing.T) {
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).ID = 9
+		}).Once()
+		revisions.Mock.On("Insert", context.Background(), mock.MatchedBy(func(rev *schema.CakeRevision) bool {
+			return rev.CakeID == 9 && rev.Action == schema.RevisionActionInsert && rev.Before == nil && rev.After != nil
+		})).Return(nil).Once()
+
+		err := srv.Insert(context.Background(), &service.CakeRequest{Title: "Test Title"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", context.Background(), current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Delete", context.Background(), current.ID, 0).Return(nil).Once()
+		revisions.Mock.On("Insert", context.Background(), mock.MatchedBy(func(rev *schema.CakeRevision) bool {
+			return rev.CakeID == current.ID && rev.Action == schema.RevisionActionDelete && rev.Before != nil && rev.After == nil
+		})).Return(nil).Once()
+
+		err := srv.Delete(context.Background(), current.ID, 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		restored := cake
+		repo.Mock.On("Restore", context.Background(), restored.ID).Return(nil).Once()
+		repo.Mock.On("Find", context.Background(), restored.ID).Return(&restored, nil).Once()
+		revisions.Mock.On("Insert", context.Background(), mock.MatchedBy(func(rev *schema.CakeRevision) bool {
+			return rev.CakeID == restored.ID && rev.Action == schema.RevisionActionRestore && rev.Before == nil && rev.After != nil
+		})).Return(nil).Once()
+
+		err := srv.Restore(context.Background(), restored.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		repo.Mock.On("Purge", context.Background(), cake.ID).Return(nil).Once()
+		revisions.Mock.On("Insert", context.Background(), mock.MatchedBy(func(rev *schema.CakeRevision) bool {
+			return rev.CakeID == cake.ID && rev.Action == schema.RevisionActionPurge && rev.Before == nil && rev.After == nil
+		})).Return(nil).Once()
+
+		err := srv.Purge(context.Background(), cake.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Purge_Not_Found", func(t *testing.T) {
+		// nothing is revised when the cake is not trashed
+		repo.Mock.On("Purge", context.Background(), 2).Return(repository.ErrRecordNotFound).Once()
+
+		err := srv.Purge(context.Background(), 2)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		revisions.Mock.On("Find", context.Background(), 1, 1).Return(&schema.CakeRevision{
+			After: json.RawMessage(`{"id":1,"title":"Lemon","rating":7,"version":1}`),
+		}, nil).Once()
+		revisions.Mock.On("Find", context.Background(), 1, 2).Return(&schema.CakeRevision{
+			After: json.RawMessage(`{"id":1,"title":"Lemon Cheesecake","rating":7,"version":2}`),
+		}, nil).Once()
+
+		res, err := srv.Diff(context.Background(), 1, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []schema.FieldChange{
+			{Field: "title", From: "Lemon", To: "Lemon Cheesecake"},
+			{Field: "version", From: float64(1), To: float64(2)},
+		}, res)
+	})
+
+	t.Run("Diff_Not_Found", func(t *testing.T) {
+		revisions.Mock.On("Find", context.Background(), 1, 3).Return(nil, repository.ErrRecordNotFound).Once()
+
+		res, err := srv.Diff(context.Background(), 1, 3, 2)
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+	})
+
+	t.Run("Not_Supported", func(t *testing.T) {
+		srv := &service.Cake{Repo: repo}
+
+		_, err := srv.History(context.Background(), 1)
+		assert.ErrorIs(t, err, service.ErrNotSupported)
+
+		_, err = srv.Diff(context.Background(), 1, 1, 2)
+		assert.ErrorIs(t, err, service.ErrNotSupported)
+	})
+
+	repo.AssertExpectations(t)
+	revisions.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Classification(t *testing.T) {
+	var (
+		repo       = &repository.CakeMock{Mock: mock.Mock{}}
+		categories = &repository.CategoryMock{}
+		tags       = &repository.TagMock{}
+		srv        = &service.Cake{Repo: repo, Categories: categories, Tags: tags}
+		ctx        = context.Background()
+	)
+
+	t.Run("Insert", func(t *testing.T) {
+		repo.Mock.On("Insert", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).ID = 9
+		}).Once()
+		categories.On("Find", ctx, 1).Return(&schema.Category{ID: 1}, nil).Once()
+		categories.On("SetCakeCategories", ctx, 9, []int{1}).Return(nil).Once()
+		tags.On("SetCakeTags", ctx, 9, []string{"vegan"}).Return(nil).Once()
+
+		err := srv.Insert(ctx, &service.CakeRequest{
+			Title:       "Test Title",
+			CategoryIDs: []int{1},
+			Tags:        []string{"Vegan", " vegan", ""},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Insert_Category_Not_Found", func(t *testing.T) {
+		categories.On("Find", ctx, 5).Return(nil, repository.ErrRecordNotFound).Once()
+
+		err := srv.Insert(ctx, &service.CakeRequest{Title: "Test Title", CategoryIDs: []int{5}})
+		assert.ErrorIs(t, err, service.ErrCategoryNotFound)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		categories.On("FindByCakeIDs", ctx, []int{current.ID}).Return(map[int][]schema.Category{
+			current.ID: {{ID: 1, Name: "Birthday", Slug: "birthday"}},
+		}, nil).Once()
+		tags.On("FindByCakeIDs", ctx, []int{current.ID}).Return(map[int][]schema.Tag{}, nil).Once()
+
+		res, err := srv.Find(ctx, current.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "birthday", res.Categories[0].Slug)
+		assert.Empty(t, res.Tags)
+	})
+
+	repo.AssertExpectations(t)
+	categories.AssertExpectations(t)
+	tags.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Dietary(t *testing.T) {
+	var (
+		repo      = &repository.CakeMock{Mock: mock.Mock{}}
+		allergens = &repository.AllergenMock{}
+		nutrition = &repository.NutritionMock{}
+		srv       = &service.Cake{Repo: repo, Allergens: allergens, Nutrition: nutrition}
+		ctx       = context.Background()
+		facts     = schema.Nutrition{ServingSize: 120, Energy: 410, Fat: 24.5, SaturatedFat: 14.2, Carbohydrate: 42, Sugars: 30.1}
+	)
+
+	t.Run("Insert", func(t *testing.T) {
+		repo.Mock.On("Insert", ctx, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).ID = 9
+		}).Once()
+		allergens.On("SetCakeAllergens", ctx, 9, []string{"dairy", "gluten"}).Return(nil).Once()
+		nutrition.On("SetCakeNutrition", ctx, 9, &facts).Return(nil).Once()
+
+		err := srv.Insert(ctx, &service.CakeRequest{
+			Title:     "Test Title",
+			Allergens: []string{"dairy", "gluten"},
+			Nutrition: &service.NutritionRequest{ServingSize: 120, Energy: 410, Fat: 24.5, SaturatedFat: 14.2, Carbohydrate: 42, Sugars: 30.1},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Update_Keep_Current", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", ctx, mock.Anything).Return(nil).Once()
+
+		err := srv.Update(ctx, &service.CakeRequest{ID: current.ID, Title: current.Title})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		allergens.On("FindByCakeIDs", ctx, []int{current.ID}).Return(map[int][]string{
+			current.ID: {"dairy", "gluten"},
+		}, nil).Once()
+		nutrition.On("FindByCakeIDs", ctx, []int{current.ID}).Return(map[int]*schema.Nutrition{
+			current.ID: &facts,
+		}, nil).Once()
+
+		res, err := srv.Find(ctx, current.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"dairy", "gluten"}, res.Allergens)
+		assert.Equal(t, &facts, res.Nutrition)
+	})
+
+	t.Run("Allergen_Vocabulary", func(t *testing.T) {
+		req := service.CakeRequest{Title: "Test Title", Description: "Test Description", Image: "a.png", Allergens: schema.Allergens}
+		assert.Empty(t, util.Validation(&req))
+
+		req.Allergens = []string{"nuts", "chocolate"}
+		errs := util.Validation(&req)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "allergen", errs[0].Rule)
+		assert.Contains(t, errs[0].Message, "must be one of [celery crustaceans")
+	})
+
+	repo.AssertExpectations(t)
+	allergens.AssertExpectations(t)
+	nutrition.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Images(t *testing.T) {
+	var (
+		repo   = &repository.CakeMock{Mock: mock.Mock{}}
+		images = &repository.CakeImageMock{}
+		srv    = &service.Cake{Repo: repo, Images: images}
+		ctx    = context.Background()
+	)
+
+	t.Run("Update_Image_Become_Primary", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", ctx, mock.Anything).Return(nil).Once()
+		images.On("Insert", ctx, mock.MatchedBy(func(rec *schema.CakeImage) bool {
+			return rec.CakeID == current.ID && rec.URL == "https://img.test/new.png" && rec.Primary
+		})).Return(nil).Once()
+
+		err := srv.Update(ctx, &service.CakeRequest{
+			ID:    current.ID,
+			Title: current.Title,
+			Image: "https://img.test/new.png",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Update_Same_Image", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", ctx, mock.Anything).Return(nil).Once()
+
+		err := srv.Update(ctx, &service.CakeRequest{
+			ID:    current.ID,
+			Title: current.Title,
+			Image: current.Image,
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		images.On("FindByCakeIDs", ctx, []int{current.ID}).Return(map[int][]schema.CakeImage{
+			current.ID: {{ID: 1, CakeID: current.ID, Primary: true}},
+		}, nil).Once()
+
+		res, err := srv.Find(ctx, current.ID)
+		assert.NoError(t, err)
+		assert.Len(t, res.Images, 1)
+	})
+
+	repo.AssertExpectations(t)
+	images.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Embed_Variants(t *testing.T) {
+	var (
+		repo     = &repository.CakeMock{Mock: mock.Mock{}}
+		variants = &repository.CakeVariantMock{}
+		srv      = &service.Cake{Repo: repo, Variants: variants}
+		ctx      = context.Background()
+	)
+
+	t.Run("Not_Embedded", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+
+		res, err := srv.Find(ctx, current.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, res.Variants)
+	})
+
+	t.Run("Embedded", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		variants.On("FindAll", ctx, current.ID).Return([]schema.CakeVariant{
+			{ID: 1, CakeID: current.ID, SKU: "CHEESE-8IN"},
+		}, nil).Once()
+
+		res, err := srv.Find(ctx, current.ID, service.EmbedVariants)
+		assert.NoError(t, err)
+		assert.Len(t, res.Variants, 1)
+	})
+
+	t.Run("Embedded_Without_Variants", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		variants.On("FindAll", ctx, current.ID).Return(nil, repository.ErrRecordNotFound).Once()
+
+		res, err := srv.Find(ctx, current.ID, service.EmbedVariants)
+		assert.NoError(t, err)
+		assert.Empty(t, res.Variants)
+	})
+
+	repo.AssertExpectations(t)
+	variants.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Prices(t *testing.T) {
+	var (
+		repo   = &repository.CakeMock{Mock: mock.Mock{}}
+		prices = &repository.CakePriceMock{}
+		srv    = &service.Cake{Repo: repo, Prices: prices}
+		ctx    = context.Background()
+	)
+
+	t.Run("Insert_Default_Currency", func(t *testing.T) {
+		repo.Mock.On("Insert", ctx, mock.MatchedBy(func(rec *schema.Cake) bool {
+			return rec.Price == 0 && rec.Currency == service.DefaultCurrency
+		})).Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).ID = 9
+		}).Once()
+		prices.On("Insert", ctx, &schema.CakePrice{CakeID: 9, Currency: service.DefaultCurrency}).Return(nil).Once()
+
+		err := srv.Insert(ctx, &service.CakeRequest{Title: "Test Title", Image: "https://img.test/a.png"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Update_Price_Changed", func(t *testing.T) {
+		current, price := cake, int64(175000)
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", ctx, mock.MatchedBy(func(rec *schema.Cake) bool {
+			return rec.Price == price && rec.Currency == "USD"
+		})).Return(nil).Once()
+		prices.On("Insert", ctx, &schema.CakePrice{CakeID: current.ID, Price: price, Currency: "USD"}).Return(nil).Once()
+
+		err := srv.Update(ctx, &service.CakeRequest{
+			ID:       current.ID,
+			Title:    current.Title,
+			Image:    current.Image,
+			Price:    &price,
+			Currency: "USD",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Update_Price_Kept", func(t *testing.T) {
+		current := cake
+		repo.Mock.On("Find", ctx, current.ID).Return(&current, nil).Once()
+		repo.Mock.On("Update", ctx, mock.MatchedBy(func(rec *schema.Cake) bool {
+			return rec.Price == current.Price && rec.Currency == current.Currency
+		})).Return(nil).Once()
+
+		err := srv.Update(ctx, &service.CakeRequest{ID: current.ID, Title: "New Title", Image: current.Image})
+		assert.NoError(t, err)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		history := []schema.CakePrice{{ID: 2, CakeID: cake.ID, Price: 175000, Currency: "IDR"}}
+		prices.On("FindAll", ctx, &repository.PriceFilter{CakeID: cake.ID, Limit: 10}).Return(history, nil).Once()
+
+		res, err := srv.PriceHistory(ctx, &service.PriceHistoryRequest{CakeID: cake.ID, Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, history, res)
+	})
+
+	t.Run("History_Not_Supported", func(t *testing.T) {
+		srv := &service.Cake{Repo: repo}
+
+		res, err := srv.PriceHistory(ctx, &service.PriceHistoryRequest{CakeID: cake.ID})
+		assert.Nil(t, res)
+		assert.ErrorIs(t, err, service.ErrNotSupported)
+	})
+
+	repo.AssertExpectations(t)
+	prices.AssertExpectations(t)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var (
+	ErrCategoryCycle = eris.New("category parent cycle")
+)
+
+type CategoryRepository interface {
+	Find(ctx context.Context, id int) (*schema.Category, error)
+	FindAll(ctx context.Context) ([]schema.Category, error)
+	FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.Category, error)
+	Insert(ctx context.Context, rec *schema.Category) error
+	Update(ctx context.Context, rec *schema.Category) error
+	Delete(ctx context.Context, id int) error
+	SetCakeCategories(ctx context.Context, cakeID int, ids []int) error
+}
+
+type Category struct {
+	Repo CategoryRepository
+}
+
+func (s *Category) Find(ctx context.Context, id int) (*schema.Category, error) {
+	return s.Repo.Find(ctx, id)
+}
+
+func (s *Category) FindAll(ctx context.Context) ([]schema.Category, error) {
+	return s.Repo.FindAll(ctx)
+}
+
+type CategoryRequest struct {
+	ID       int    `json:"-"`
+	ParentID *int   `json:"parent_id" validate:"omitempty,min=1"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Slug     string `json:"slug" validate:"omitempty,max=120"`
+}
+
+func (s *Category) Insert(ctx context.Context, req *CategoryRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	if req.ParentID != nil {
+		if _, err := s.Repo.Find(ctx, *req.ParentID); err != nil {
+			return err
+		}
+	}
+
+	timeNow := time.Now()
+	rec := schema.Category{
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		Slug:      Slugify(req.Slug, req.Name),
+		CreatedAt: timeNow,
+		UpdatedAt: timeNow,
+	}
+	if err := s.Repo.Insert(ctx, &rec); err != nil {
+		return err
+	}
+	req.ID = rec.ID
+
+	return nil
+}
+
+// Update update a category, a parent can not be the category itself or one of it's descendants
+func (s *Category) Update(ctx context.Context, req *CategoryRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	if _, err := s.Repo.Find(ctx, req.ID); err != nil {
+		return err
+	}
+
+	// walk up from the new parent, the category must not be found on the way to a root
+	for parentID := req.ParentID; parentID != nil; {
+		if *parentID == req.ID {
+			return ErrCategoryCycle
+		}
+
+		parent, err := s.Repo.Find(ctx, *parentID)
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+
+	rec := schema.Category{
+		ID:        req.ID,
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		Slug:      Slugify(req.Slug, req.Name),
+		UpdatedAt: time.Now(),
+	}
+	return s.Repo.Update(ctx, &rec)
+}
+
+func (s *Category) Delete(ctx context.Context, id int) error {
+	return s.Repo.Delete(ctx, id)
+}
+
+// Slugify make a url friendly slug from the first non empty value, e.g. "Kids Birthday" become "kids-birthday"
+func Slugify(values ...string) string {
+	for _, v := range values {
+		var (
+			b    strings.Builder
+			dash = false
+		)
+		for _, r := range strings.ToLower(strings.TrimSpace(v)) {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				b.WriteRune(r)
+				dash = false
+			} else if !dash && b.Len() > 0 {
+				b.WriteRune('-')
+				dash = true
+			}
+		}
+
+		if slug := strings.TrimSuffix(b.String(), "-"); len(slug) > 0 {
+			return slug
+		}
+	}
+
+	return ""
+}
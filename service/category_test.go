@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func Test_Category_Service_Insert(t *testing.T) {
+	var (
+		repo = &repository.CategoryMock{}
+		svc  = &service.Category{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	repo.On("Find", ctx, 1).Return(&schema.Category{ID: 1, Name: "Birthday", Slug: "birthday"}, nil)
+	repo.On("Find", ctx, 9).Return(nil, repository.ErrRecordNotFound)
+	repo.On("Insert", ctx, &schema.Category{ParentID: intPtr(1), Name: "Kids Birthday", Slug: "kids-birthday"}).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Category).ID = 2
+		}).
+		Return(nil)
+
+	req := &service.CategoryRequest{ParentID: intPtr(1), Name: "Kids Birthday"}
+	assert.NoError(t, svc.Insert(ctx, req))
+	assert.Equal(t, 2, req.ID)
+
+	err := svc.Insert(ctx, &service.CategoryRequest{ParentID: intPtr(9), Name: "Orphan"})
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	assert.ErrorIs(t, svc.Insert(ctx, nil), service.ErrRequestNil)
+}
+
+func Test_Category_Service_Update(t *testing.T) {
+	var (
+		repo = &repository.CategoryMock{}
+		svc  = &service.Category{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	// birthday(1) -> kids(2) -> toddler(3)
+	repo.On("Find", ctx, 1).Return(&schema.Category{ID: 1, Name: "Birthday", Slug: "birthday"}, nil)
+	repo.On("Find", ctx, 2).Return(&schema.Category{ID: 2, ParentID: intPtr(1), Name: "Kids", Slug: "kids"}, nil)
+	repo.On("Find", ctx, 3).Return(&schema.Category{ID: 3, ParentID: intPtr(2), Name: "Toddler", Slug: "toddler"}, nil)
+	repo.On("Update", ctx, mock.AnythingOfType("*schema.Category")).Return(nil)
+
+	tests := []struct {
+		Name          string
+		Request       *service.CategoryRequest
+		ExpectedError error
+	}{
+		{
+			Name:    "Move",
+			Request: &service.CategoryRequest{ID: 3, ParentID: intPtr(1), Name: "Toddler"},
+		},
+		{
+			Name:    "Root",
+			Request: &service.CategoryRequest{ID: 2, Name: "Kids"},
+		},
+		{
+			Name:          "Self_Parent",
+			Request:       &service.CategoryRequest{ID: 2, ParentID: intPtr(2), Name: "Kids"},
+			ExpectedError: service.ErrCategoryCycle,
+		},
+		{
+			Name:          "Descendant_Parent",
+			Request:       &service.CategoryRequest{ID: 1, ParentID: intPtr(3), Name: "Birthday"},
+			ExpectedError: service.ErrCategoryCycle,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := svc.Update(ctx, test.Request)
+			if test.ExpectedError != nil {
+				assert.ErrorIs(t, err, test.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_Category_Service_Slugify(t *testing.T) {
+	assert.Equal(t, "kids-birthday", service.Slugify("", "  Kids  Birthday! "))
+	assert.Equal(t, "custom", service.Slugify("Custom", "Kids Birthday"))
+	assert.Equal(t, "", service.Slugify("--", ""))
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"github.com/zufzuf/cake-store/schema"
+)
+
+// exportBatch is a number of streamed cakes which relations are loaded together
+const exportBatch = 100
+
+// Export call fn on each cakes of a filter with it's relations, req.Limit and req.Cursor is ignored.
+// the cakes are streamed from the repository and their relations are loaded by a batch of exportBatch
+// cakes, so an export hold at most a batch of cakes in memory
+func (s *Cake) Export(ctx context.Context, req *FindAllRequest, fn func(rec *schema.Cake) error) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	fil := req.filter()
+	fil.Limit, fil.Cursor = 0, ""
+
+	batch := make([]schema.Cake, 0, exportBatch)
+	flush := func() error {
+		if err := s.loadRelations(ctx, batch); err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	err := s.Repo.Stream(ctx, &fil, func(rec *schema.Cake) error {
+		if batch = append(batch, *rec); len(batch) < exportBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return flush()
+}
@@ -0,0 +1,67 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Cake_Service_Export(t *testing.T) {
+	var (
+		repo = &repository.CakeMock{Mock: mock.Mock{}}
+		tags = &repository.TagMock{Mock: mock.Mock{}}
+		srv  = &service.Cake{Repo: repo, Tags: tags}
+	)
+
+	streamed := make([]schema.Cake, 250)
+	for i := range streamed {
+		streamed[i] = schema.Cake{ID: i + 1, Title: "Test Title"}
+	}
+
+	t.Run("Batched", func(t *testing.T) {
+		repo.Mock.On("Stream", context.Background(), &repository.FindAllFilter{Currency: "IDR"}).Return(streamed, nil).Once()
+
+		// the relations are loaded by a batch of 100 cakes
+		tags.Mock.On("FindByCakeIDs", context.Background(), mock.MatchedBy(func(ids []int) bool {
+			return len(ids) == 100
+		})).Return(map[int][]schema.Tag{1: {{ID: 1, Name: "citrus"}}}, nil).Twice()
+		tags.Mock.On("FindByCakeIDs", context.Background(), mock.MatchedBy(func(ids []int) bool {
+			return len(ids) == 50 && ids[0] == 201
+		})).Return(map[int][]schema.Tag{}, nil).Once()
+
+		res := []schema.Cake{}
+		err := srv.Export(context.Background(), &service.FindAllRequest{Currency: "IDR", Limit: 10, Cursor: "ignored"}, func(rec *schema.Cake) error {
+			res = append(res, *rec)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, res, 250)
+		assert.Equal(t, 250, res[249].ID)
+		assert.Equal(t, []schema.Tag{{ID: 1, Name: "citrus"}}, res[0].Tags)
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		stopped := errors.New("stopped")
+		repo.Mock.On("Stream", context.Background(), &repository.FindAllFilter{}).Return(streamed[:3], nil).Once()
+		tags.Mock.On("FindByCakeIDs", context.Background(), []int{1, 2, 3}).Return(map[int][]schema.Tag{}, nil).Once()
+
+		err := srv.Export(context.Background(), &service.FindAllRequest{}, func(rec *schema.Cake) error {
+			return stopped
+		})
+		assert.ErrorIs(t, err, stopped)
+	})
+
+	t.Run("Request_Nil", func(t *testing.T) {
+		err := srv.Export(context.Background(), nil, func(rec *schema.Cake) error { return nil })
+		assert.ErrorIs(t, err, service.ErrRequestNil)
+	})
+
+	repo.AssertExpectations(t)
+	tags.AssertExpectations(t)
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/rs/xid"
+	"github.com/zufzuf/cake-store/libs/imaging"
+	"github.com/zufzuf/cake-store/libs/storage"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+
+	// register a webp decoder for image.Decode
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	DefaultMaxImageSize = 5 << 20
+
+	// DefaultMaxImagePixels is a maximum width × height of an image, a small compressed file can
+	// decode into a huge image so the dimension is checked before the image is decoded
+	DefaultMaxImagePixels = 40_000_000
+)
+
+var (
+	ErrUnsupportedImage = eris.New("unsupported image type")
+	ErrImageTooLarge    = eris.New("image is too large")
+)
+
+// ImageTypes is an accepted content type of an uploaded image and it's file extension,
+// the content type is sniffed from the file content instead of trusting the client
+var ImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// ThumbnailWidths is a widths of a thumbnails which are generated on upload
+var ThumbnailWidths = []int{150, 300, 600}
+
+type CakeImageRepository interface {
+	FindAll(ctx context.Context, cakeID int) ([]schema.CakeImage, error)
+	FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.CakeImage, error)
+	Insert(ctx context.Context, rec *schema.CakeImage) error
+	Update(ctx context.Context, rec *schema.CakeImage) error
+	Reorder(ctx context.Context, cakeID int, ids []int) error
+	Delete(ctx context.Context, cakeID, id int) (*schema.CakeImage, error)
+}
+
+type Image struct {
+	Repo    CakeImageRepository
+	Storage storage.Storage
+	Cakes   CakeRepository
+
+	// MaxSize is a maximum image size in bytes, DefaultMaxImageSize is used when it's zero
+	MaxSize int64
+
+	// MaxPixels is a maximum width × height of an image, DefaultMaxImagePixels is used when it's zero
+	MaxPixels int
+}
+
+func (s *Image) FindAll(ctx context.Context, cakeID int) ([]schema.CakeImage, error) {
+	if _, err := s.Cakes.Find(ctx, cakeID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindAll(ctx, cakeID)
+}
+
+type UploadRequest struct {
+	CakeID  int
+	Body    io.Reader
+	Alt     string
+	Primary bool
+
+	// Image is filled by Upload
+	Image *schema.CakeImage
+}
+
+// Upload store an image of an active cake with it's thumbnails and append it into the cake gallery,
+// the first image of a cake become the primary image
+func (s *Image) Upload(ctx context.Context, req *UploadRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	if _, err := s.Cakes.Find(ctx, req.CakeID); err != nil {
+		return err
+	}
+
+	img, err := s.store(ctx, req.CakeID, req.Body)
+	if err != nil {
+		return err
+	}
+
+	rec := schema.CakeImage{
+		CakeID:    req.CakeID,
+		Image:     *img,
+		Alt:       req.Alt,
+		Primary:   req.Primary,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Repo.Insert(ctx, &rec); err != nil {
+		s.remove(ctx, &rec)
+		return err
+	}
+	req.Image = &rec
+
+	return nil
+}
+
+type CakeImageRequest struct {
+	ID     int `json:"-"`
+	CakeID int `json:"-"`
+
+	// Alt is kept when it's omitted, Primary make the image the primary image
+	Alt     *string `json:"alt" validate:"omitempty,max=255"`
+	Primary bool    `json:"primary"`
+}
+
+func (s *Image) Update(ctx context.Context, req *CakeImageRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	images, err := s.Repo.FindAll(ctx, req.CakeID)
+	if err != nil {
+		return err
+	}
+
+	for _, cur := range images {
+		if cur.ID != req.ID {
+			continue
+		}
+
+		rec := cur
+		if req.Alt != nil {
+			rec.Alt = *req.Alt
+		}
+		rec.Primary = req.Primary
+		return s.Repo.Update(ctx, &rec)
+	}
+
+	return repository.ErrRecordNotFound
+}
+
+type ReorderRequest struct {
+	CakeID int   `json:"-"`
+	IDs    []int `json:"ids" validate:"required,min=1,unique,dive,min=1"`
+}
+
+// Reorder set an order of the cake gallery, IDs must contain every image of the cake
+func (s *Image) Reorder(ctx context.Context, req *ReorderRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	return s.Repo.Reorder(ctx, req.CakeID, req.IDs)
+}
+
+// Delete remove an image from the cake gallery then remove it's files
+func (s *Image) Delete(ctx context.Context, cakeID, id int) error {
+	rec, err := s.Repo.Delete(ctx, cakeID, id)
+	if err != nil {
+		return err
+	}
+
+	s.remove(ctx, rec)
+
+	return nil
+}
+
+// remove delete a stored files of an image, the image is already removed from the gallery
+// so a failure only leave an orphan file behind and it's logged instead of returned
+func (s *Image) remove(ctx context.Context, rec *schema.CakeImage) {
+	if len(rec.Key) == 0 {
+		return
+	}
+
+	keys := []string{rec.Key}
+	for _, thumb := range rec.Thumbnails {
+		keys = append(keys, thumb.Key)
+	}
+
+	for _, key := range keys {
+		if err := s.Storage.Delete(ctx, key); err != nil {
+			log.Printf("remove image %s, %v", key, err)
+		}
+	}
+}
+
+// store validate an image then write it and it's thumbnails into the storage, every written file
+// is removed when a later write fails
+func (s *Image) store(ctx context.Context, cakeID int, body io.Reader) (_ *schema.Image, err error) {
+	maxSize := s.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxImageSize
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, eris.Wrap(err, "read image, an error occurred")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := ImageTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	maxPixels := s.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxImagePixels
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxPixels/cfg.Height {
+		return nil, eris.Wrapf(ErrImageTooLarge, "image of %dx%d pixels", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	var (
+		name = xid.New().String()
+		img  = schema.Image{
+			Key:         fmt.Sprintf("cakes/%d/%s%s", cakeID, name, ext),
+			ContentType: contentType,
+			Size:        int64(len(data)),
+			Width:       src.Bounds().Dx(),
+			Height:      src.Bounds().Dy(),
+			Thumbnails:  make([]schema.Thumbnail, 0, len(ThumbnailWidths)),
+		}
+	)
+
+	if err := s.Storage.Put(ctx, img.Key, bytes.NewReader(data), contentType); err != nil {
+		return nil, err
+	}
+	img.URL = s.Storage.URL(img.Key)
+
+	defer func() {
+		if err != nil {
+			s.remove(ctx, &schema.CakeImage{Image: img})
+		}
+	}()
+
+	// a png thumbnail keep the transparency, any other type is encoded as jpeg
+	thumbType, thumbExt := "image/jpeg", ".jpg"
+	if contentType == "image/png" {
+		thumbType, thumbExt = contentType, ext
+	}
+
+	for _, width := range ThumbnailWidths {
+		thumb := imaging.Thumbnail(src, width)
+
+		buf := bytes.Buffer{}
+		if thumbType == "image/png" {
+			err = png.Encode(&buf, thumb)
+		} else {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, eris.Wrap(err, "encode thumbnail, an error occurred")
+		}
+
+		key := fmt.Sprintf("cakes/%d/%s_%d%s", cakeID, name, width, thumbExt)
+		if err := s.Storage.Put(ctx, key, &buf, thumbType); err != nil {
+			return nil, err
+		}
+
+		img.Thumbnails = append(img.Thumbnails, schema.Thumbnail{
+			Key:    key,
+			URL:    s.Storage.URL(key),
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+		})
+	}
+
+	return &img, nil
+}
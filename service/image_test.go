@@ -0,0 +1,202 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/libs/storage"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func pngImage(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// failingStorage fail a Put after a number of successful puts
+type failingStorage struct {
+	storage.Storage
+	puts int
+}
+
+func (f *failingStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if f.puts == 0 {
+		return errors.New("put failed")
+	}
+	f.puts--
+	return f.Storage.Put(ctx, key, r, contentType)
+}
+
+func Test_Image_Service_Upload(t *testing.T) {
+	var (
+		root     = t.TempDir()
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.CakeImageMock{Mock: mock.Mock{}}
+		svc      = &service.Image{
+			Repo:    repo,
+			Storage: &storage.Local{Root: root, BaseURL: "/files"},
+			Cakes:   cakeRepo,
+			MaxSize: 1 << 20,
+		}
+		ctx = context.Background()
+	)
+
+	cakeRepo.On("Find", ctx, cake.ID).Return(&cake, nil)
+	cakeRepo.On("Find", ctx, 9).Return(nil, repository.ErrRecordNotFound)
+
+	t.Run("Uploaded", func(t *testing.T) {
+		repo.On("Insert", ctx, mock.MatchedBy(func(rec *schema.CakeImage) bool {
+			return rec.CakeID == cake.ID && rec.Alt == "Side view" && strings.HasPrefix(rec.URL, "/files/cakes/1/") && strings.HasSuffix(rec.URL, ".png")
+		})).Return(nil).Once()
+
+		req := &service.UploadRequest{CakeID: cake.ID, Body: bytes.NewReader(pngImage(t, 800, 400)), Alt: "Side view"}
+		assert.NoError(t, svc.Upload(ctx, req))
+
+		assert.Equal(t, "image/png", req.Image.ContentType)
+		assert.Equal(t, 800, req.Image.Width)
+		assert.FileExists(t, filepath.Join(root, filepath.FromSlash(req.Image.Key)))
+
+		assert.Len(t, req.Image.Thumbnails, len(service.ThumbnailWidths))
+		for i, thumb := range req.Image.Thumbnails {
+			assert.Equal(t, service.ThumbnailWidths[i], thumb.Width)
+			assert.Equal(t, service.ThumbnailWidths[i]/2, thumb.Height)
+			assert.FileExists(t, filepath.Join(root, filepath.FromSlash(thumb.Key)))
+		}
+	})
+
+	t.Run("Small_Image_Is_Not_Upscaled", func(t *testing.T) {
+		repo.On("Insert", ctx, mock.Anything).Return(nil).Once()
+
+		req := &service.UploadRequest{CakeID: cake.ID, Body: bytes.NewReader(pngImage(t, 200, 100))}
+		assert.NoError(t, svc.Upload(ctx, req))
+		assert.Equal(t, []int{150, 200, 200}, []int{
+			req.Image.Thumbnails[0].Width,
+			req.Image.Thumbnails[1].Width,
+			req.Image.Thumbnails[2].Width,
+		})
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		req := &service.UploadRequest{CakeID: cake.ID, Body: strings.NewReader("<html>not an image</html>")}
+		assert.ErrorIs(t, svc.Upload(ctx, req), service.ErrUnsupportedImage)
+	})
+
+	t.Run("Too_Large", func(t *testing.T) {
+		req := &service.UploadRequest{CakeID: cake.ID, Body: bytes.NewReader(make([]byte, 1<<20+1))}
+		assert.ErrorIs(t, svc.Upload(ctx, req), service.ErrImageTooLarge)
+	})
+
+	t.Run("Too_Many_Pixels", func(t *testing.T) {
+		svc := *svc
+		svc.MaxPixels = 100 * 100
+
+		req := &service.UploadRequest{CakeID: cake.ID, Body: bytes.NewReader(pngImage(t, 101, 100))}
+		assert.ErrorIs(t, svc.Upload(ctx, req), service.ErrImageTooLarge)
+	})
+
+	t.Run("Put_Failed_Remove_Files", func(t *testing.T) {
+		// the original and the first thumbnail are written before the second thumbnail fails
+		svc := *svc
+		svc.Storage = &failingStorage{Storage: svc.Storage, puts: 2}
+
+		req := &service.UploadRequest{CakeID: cake.ID, Body: bytes.NewReader(pngImage(t, 10, 10))}
+		assert.Error(t, svc.Upload(ctx, req))
+	})
+
+	t.Run("Cake_Not_Found", func(t *testing.T) {
+		req := &service.UploadRequest{CakeID: 9, Body: bytes.NewReader(pngImage(t, 10, 10))}
+		assert.ErrorIs(t, svc.Upload(ctx, req), repository.ErrRecordNotFound)
+	})
+
+	t.Run("Insert_Failed_Remove_Files", func(t *testing.T) {
+		repo.On("Insert", ctx, mock.Anything).Return(repository.ErrRecordNotFound).Once()
+
+		req := &service.UploadRequest{CakeID: cake.ID, Body: bytes.NewReader(pngImage(t, 10, 10))}
+		assert.ErrorIs(t, svc.Upload(ctx, req), repository.ErrRecordNotFound)
+	})
+
+	entries, err := os.ReadDir(filepath.Join(root, "cakes", "1"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2*(1+len(service.ThumbnailWidths)))
+
+	cakeRepo.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func Test_Image_Service_Update(t *testing.T) {
+	var (
+		repo = &repository.CakeImageMock{Mock: mock.Mock{}}
+		svc  = &service.Image{Repo: repo}
+		ctx  = context.Background()
+		alt  = "Top view"
+	)
+
+	images := []schema.CakeImage{
+		{ID: 1, CakeID: cake.ID, Alt: "Side view", Primary: true},
+		{ID: 2, CakeID: cake.ID, Alt: "Slice", Position: 1},
+	}
+	repo.On("FindAll", ctx, cake.ID).Return(images, nil)
+
+	t.Run("Updated", func(t *testing.T) {
+		repo.On("Update", ctx, &schema.CakeImage{ID: 2, CakeID: cake.ID, Alt: alt, Position: 1, Primary: true}).Return(nil).Once()
+		assert.NoError(t, svc.Update(ctx, &service.CakeImageRequest{ID: 2, CakeID: cake.ID, Alt: &alt, Primary: true}))
+	})
+
+	t.Run("Alt_Is_Kept", func(t *testing.T) {
+		repo.On("Update", ctx, &schema.CakeImage{ID: 2, CakeID: cake.ID, Alt: "Slice", Position: 1}).Return(nil).Once()
+		assert.NoError(t, svc.Update(ctx, &service.CakeImageRequest{ID: 2, CakeID: cake.ID}))
+	})
+
+	t.Run("Not_Found", func(t *testing.T) {
+		assert.ErrorIs(t, svc.Update(ctx, &service.CakeImageRequest{ID: 9, CakeID: cake.ID}), repository.ErrRecordNotFound)
+	})
+
+	repo.AssertExpectations(t)
+}
+
+func Test_Image_Service_Delete(t *testing.T) {
+	var (
+		root = t.TempDir()
+		repo = &repository.CakeImageMock{Mock: mock.Mock{}}
+		svc  = &service.Image{Repo: repo, Storage: &storage.Local{Root: root, BaseURL: "/files"}}
+		ctx  = context.Background()
+	)
+
+	rec := &schema.CakeImage{ID: 1, CakeID: cake.ID, Image: schema.Image{
+		Key:        "cakes/1/a.png",
+		Thumbnails: []schema.Thumbnail{{Key: "cakes/1/a_150.png"}},
+	}}
+	for _, key := range []string{rec.Key, rec.Thumbnails[0].Key} {
+		assert.NoError(t, svc.Storage.Put(ctx, key, strings.NewReader("img"), "image/png"))
+	}
+
+	repo.On("Delete", ctx, cake.ID, rec.ID).Return(rec, nil).Once()
+	repo.On("Delete", ctx, cake.ID, 9).Return(nil, repository.ErrRecordNotFound).Once()
+
+	assert.NoError(t, svc.Delete(ctx, cake.ID, rec.ID))
+	assert.NoFileExists(t, filepath.Join(root, "cakes", "1", "a.png"))
+	assert.NoFileExists(t, filepath.Join(root, "cakes", "1", "a_150.png"))
+
+	assert.ErrorIs(t, svc.Delete(ctx, cake.ID, 9), repository.ErrRecordNotFound)
+
+	repo.AssertExpectations(t)
+}
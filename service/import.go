@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+)
+
+const (
+	// ImportModeAtomic insert every rows or nothing, it's the default mode
+	ImportModeAtomic = "atomic"
+	// ImportModeBestEffort insert every valid rows, each row is inserted on it's own transaction
+	ImportModeBestEffort = "best_effort"
+)
+
+var ImportModes = []string{ImportModeAtomic, ImportModeBestEffort}
+
+const (
+	ImportStatusInserted   = "inserted"
+	ImportStatusInvalid    = "invalid"
+	ImportStatusFailed     = "failed"
+	ImportStatusRolledBack = "rolled_back"
+)
+
+var (
+	ErrImportFailed       = eris.New("import failed, every rows are rolled back")
+	ErrImportNotRetryable = eris.New("import can not be retried, the rows have been read")
+)
+
+// ImportRow is a cake of an import, Errors is filled when the row can not be decoded into a request
+// and the row is reported without being validated
+type ImportRow struct {
+	Line    int
+	Request CakeRequest
+	Errors  []util.ValidationError
+}
+
+// CakeReader read a rows of an import one by one, so a large import is never fully buffered.
+// Read return io.EOF after the last row, another error abort the import
+type CakeReader interface {
+	Read() (*ImportRow, error)
+}
+
+type ImportRowResult struct {
+	Line   int                    `json:"line"`
+	ID     int                    `json:"id,omitempty"`
+	Title  string                 `json:"title"`
+	Status string                 `json:"status"`
+	Errors []util.ValidationError `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	Mode     string            `json:"mode"`
+	Total    int               `json:"total"`
+	Inserted int               `json:"inserted"`
+	Failed   int               `json:"failed"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+// Import insert a cakes of the rows, each row is validated like a body of POST /cakes. an atomic import
+// run in a single transaction which is rolled back when a row fails, the remaining rows are still validated
+// so every invalid rows is reported. the report is returned with ErrImportFailed when it's rolled back.
+// a best effort import commit each row, so the report of the rows before a read error is returned with the error
+func (s *Cake) Import(ctx context.Context, rows CakeReader, mode string) (*ImportReport, error) {
+	if rows == nil {
+		return nil, ErrRequestNil
+	}
+
+	res := &ImportReport{Mode: mode, Rows: []ImportRowResult{}}
+	if mode == ImportModeBestEffort {
+		if err := s.importRows(ctx, rows, res, false); err != nil {
+			return res, err
+		}
+		return res, nil
+	}
+	res.Mode = ImportModeAtomic
+
+	// the rows can only be read once, so the transaction is not retried on a deadlock
+	var read bool
+	err := withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		if read {
+			return ErrImportNotRetryable
+		}
+		read = true
+
+		if err := s.importRows(ctx, rows, res, true); err != nil {
+			return err
+		}
+		if res.Failed > 0 {
+			return ErrImportFailed
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrImportFailed) {
+			return nil, err
+		}
+
+		for i := range res.Rows {
+			if res.Rows[i].Status == ImportStatusInserted {
+				res.Rows[i].ID, res.Rows[i].Status = 0, ImportStatusRolledBack
+			}
+		}
+		res.Inserted = 0
+
+		return res, ErrImportFailed
+	}
+
+	return res, nil
+}
+
+// importRows insert each rows into the report, an atomic import stop inserting after the first failure
+// since a failed statement may abort the whole transaction
+func (s *Cake) importRows(ctx context.Context, rows CakeReader, res *ImportReport, atomic bool) error {
+	for {
+		row, err := rows.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return eris.Wrap(err, "read import rows, an error occurred")
+		}
+
+		res.Total++
+		result := ImportRowResult{Line: row.Line, Title: row.Request.Title, Status: ImportStatusInvalid}
+
+		result.Errors = row.Errors
+		if len(result.Errors) == 0 {
+			result.Errors = util.Validation(&row.Request)
+		}
+
+		switch {
+		case len(result.Errors) > 0:
+		case atomic && res.Failed > 0:
+			// the row is valid, but nothing is inserted anymore
+			result.Status = ImportStatusRolledBack
+		default:
+			if err := s.Insert(ctx, &row.Request); err != nil {
+				if !errors.Is(err, ErrCategoryNotFound) {
+					// an unexpected error is masked in the report, so it's logged to be diagnosed
+					util.LogError(ctx, err)
+				}
+				result.Status, result.Errors = ImportStatusFailed, insertErrors(err)
+				break
+			}
+			result.ID, result.Status = row.Request.ID, ImportStatusInserted
+			res.Inserted++
+		}
+
+		if result.Status != ImportStatusInserted && result.Status != ImportStatusRolledBack {
+			res.Failed++
+		}
+		res.Rows = append(res.Rows, result)
+	}
+}
+
+// insertErrors describe an insert error of a row, an unexpected error is not exposed
+func insertErrors(err error) []util.ValidationError {
+	if errors.Is(err, ErrCategoryNotFound) {
+		return []util.ValidationError{{
+			Key:     "category_ids",
+			Rule:    "exists",
+			Message: "category_ids must contain an existing category",
+		}}
+	}
+	return []util.ValidationError{{
+		Key:     "row",
+		Rule:    "insert",
+		Message: "row can not be inserted",
+	}}
+}
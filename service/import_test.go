@@ -0,0 +1,148 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+// importRows read a rows from a slice, err is returned after the last row instead of io.EOF when it's set
+type importRows struct {
+	rows []service.ImportRow
+	err  error
+}
+
+func (r *importRows) Read() (*service.ImportRow, error) {
+	if len(r.rows) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, io.EOF
+	}
+
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return &row, nil
+}
+
+func Test_Cake_Service_Import(t *testing.T) {
+	util.NewValidator()
+
+	var (
+		repo = &repository.CakeMock{Mock: mock.Mock{}}
+		tx   = &repository.TransactorMock{Mock: mock.Mock{}}
+		srv  = &service.Cake{Repo: repo, Tx: tx}
+	)
+
+	row := func(line int, title string) service.ImportRow {
+		return service.ImportRow{Line: line, Request: service.CakeRequest{
+			Title:       title,
+			Description: "Test Description",
+			Image:       "https://example.com/cake.jpeg",
+		}}
+	}
+	invalid := row(3, "Invalid")
+	invalid.Request.Image = ""
+	malformed := service.ImportRow{Line: 4, Errors: []util.ValidationError{{Key: "price", Rule: "number", Message: "price must be a valid integer"}}}
+
+	inserted := func(id int) func(args mock.Arguments) {
+		return func(args mock.Arguments) {
+			args.Get(1).(*schema.Cake).ID = id
+		}
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Times(3)
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted(7)).Once()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted(8)).Once()
+
+		res, err := srv.Import(context.Background(), &importRows{rows: []service.ImportRow{row(2, "Lemon"), row(3, "Carrot")}}, "")
+		assert.NoError(t, err)
+		assert.Equal(t, &service.ImportReport{Mode: service.ImportModeAtomic, Total: 2, Inserted: 2, Rows: []service.ImportRowResult{
+			{Line: 2, ID: 7, Title: "Lemon", Status: service.ImportStatusInserted},
+			{Line: 3, ID: 8, Title: "Carrot", Status: service.ImportStatusInserted},
+		}}, res)
+	})
+
+	t.Run("Atomic_Rolled_Back", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Twice()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted(7)).Once()
+
+		// every rows is still validated after the first invalid row, but nothing is inserted
+		res, err := srv.Import(context.Background(), &importRows{rows: []service.ImportRow{
+			row(2, "Lemon"), invalid, malformed, row(5, "Carrot"),
+		}}, service.ImportModeAtomic)
+		assert.ErrorIs(t, err, service.ErrImportFailed)
+		assert.Equal(t, 4, res.Total)
+		assert.Equal(t, 0, res.Inserted)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, []string{
+			service.ImportStatusRolledBack,
+			service.ImportStatusInvalid,
+			service.ImportStatusInvalid,
+			service.ImportStatusRolledBack,
+		}, []string{res.Rows[0].Status, res.Rows[1].Status, res.Rows[2].Status, res.Rows[3].Status})
+		assert.Zero(t, res.Rows[0].ID)
+		assert.Equal(t, "required", res.Rows[1].Errors[0].Rule)
+		assert.Equal(t, malformed.Errors, res.Rows[2].Errors)
+	})
+
+	t.Run("Best_Effort", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Twice()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted(7)).Once()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(errors.New("insert failed")).Once()
+
+		res, err := srv.Import(context.Background(), &importRows{rows: []service.ImportRow{
+			row(2, "Lemon"), invalid, row(4, "Carrot"),
+		}}, service.ImportModeBestEffort)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Inserted)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, service.ImportStatusInserted, res.Rows[0].Status)
+		assert.Equal(t, service.ImportStatusInvalid, res.Rows[1].Status)
+		assert.Equal(t, service.ImportStatusFailed, res.Rows[2].Status)
+	})
+
+	t.Run("Read_Failed", func(t *testing.T) {
+		failed := errors.New("read failed")
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Once()
+
+		res, err := srv.Import(context.Background(), &importRows{err: failed}, service.ImportModeAtomic)
+		assert.ErrorIs(t, err, failed)
+		assert.Nil(t, res)
+	})
+
+	t.Run("Best_Effort_Read_Failed", func(t *testing.T) {
+		failed := errors.New("read failed")
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Once()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Run(inserted(7)).Once()
+
+		// the inserted rows are committed, so they're still reported
+		res, err := srv.Import(context.Background(), &importRows{rows: []service.ImportRow{row(2, "Lemon")}, err: failed}, service.ImportModeBestEffort)
+		assert.ErrorIs(t, err, failed)
+		assert.Equal(t, &service.ImportReport{Mode: service.ImportModeBestEffort, Total: 1, Inserted: 1, Rows: []service.ImportRowResult{
+			{Line: 2, ID: 7, Title: "Lemon", Status: service.ImportStatusInserted},
+		}}, res)
+	})
+
+	t.Run("Unknown_Allergen", func(t *testing.T) {
+		unknown := row(2, "Lemon")
+		unknown.Request.Allergens = []string{"dairy", "chocolate"}
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Once()
+
+		res, err := srv.Import(context.Background(), &importRows{rows: []service.ImportRow{unknown}}, service.ImportModeAtomic)
+		assert.ErrorIs(t, err, service.ErrImportFailed)
+		assert.Equal(t, "allergen", res.Rows[0].Errors[0].Rule)
+	})
+
+	repo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var (
+	ErrOrderCakeNotFound = eris.New("ordered cake not found")
+	ErrCurrencyMismatch  = eris.New("ordered cakes are priced in a different currency")
+	ErrInvalidTransition = eris.New("invalid order status transition")
+)
+
+// OrderTransitions is a status machine of an order, a status can only move into one of it's next statuses.
+// picked_up and cancelled is a final status
+var OrderTransitions = map[string][]string{
+	schema.OrderStatusPending:   {schema.OrderStatusConfirmed, schema.OrderStatusCancelled},
+	schema.OrderStatusConfirmed: {schema.OrderStatusBaking, schema.OrderStatusCancelled},
+	schema.OrderStatusBaking:    {schema.OrderStatusReady, schema.OrderStatusCancelled},
+	schema.OrderStatusReady:     {schema.OrderStatusPickedUp, schema.OrderStatusCancelled},
+}
+
+// CanTransition report whether an order can move from a status into another one
+func CanTransition(from, to string) bool {
+	for _, next := range OrderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderRepository interface {
+	Find(ctx context.Context, id int) (*schema.Order, error)
+	FindAll(ctx context.Context, fil *repository.OrderFilter) ([]schema.Order, error)
+	Insert(ctx context.Context, rec *schema.Order) error
+	UpdateStatus(ctx context.Context, id int, from, to string, updatedAt time.Time) error
+}
+
+type Order struct {
+	Repo  OrderRepository
+	Cakes CakeRepository
+
+	// Tx lock the ordered cakes until the order is inserted, so a cake can not be deleted in between.
+	// an order is not transactional when nil
+	Tx Transactor
+}
+
+func (s *Order) Find(ctx context.Context, id int) (*schema.Order, error) {
+	return s.Repo.Find(ctx, id)
+}
+
+type FindAllOrderRequest struct {
+	Status        string    `json:"status"`
+	CakeID        int       `json:"cake_id"`
+	CreatedAfter  time.Time `json:"created_after"`
+	CreatedBefore time.Time `json:"created_before"`
+	Limit         int       `json:"limit"`
+	Before        int       `json:"before"`
+}
+
+func (s *Order) FindAll(ctx context.Context, req *FindAllOrderRequest) ([]schema.Order, error) {
+	if req == nil {
+		return nil, ErrRequestNil
+	}
+
+	return s.Repo.FindAll(ctx, &repository.OrderFilter{
+		Status:        req.Status,
+		CakeID:        req.CakeID,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Limit:         req.Limit,
+		Before:        req.Before,
+	})
+}
+
+type OrderLineRequest struct {
+	CakeID   int `json:"cake_id" validate:"required,min=1"`
+	Quantity int `json:"quantity" validate:"required,min=1"`
+}
+
+type OrderRequest struct {
+	CustomerName  string             `json:"customer_name" validate:"required,max=100"`
+	CustomerPhone string             `json:"customer_phone" validate:"max=32"`
+	Note          string             `json:"note" validate:"max=255"`
+	Lines         []OrderLineRequest `json:"lines" validate:"required,min=1,dive"`
+
+	// ID and Status is filled by Insert
+	ID     int    `json:"-"`
+	Status string `json:"-"`
+}
+
+// Insert create a pending order, every ordered cake must be an active cake priced in the same currency,
+// it's title and price is kept on the line as a snapshot
+func (s *Order) Insert(ctx context.Context, req *OrderRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	timeNow := time.Now()
+	rec := schema.Order{
+		CustomerName:  req.CustomerName,
+		CustomerPhone: req.CustomerPhone,
+		Note:          req.Note,
+		Status:        schema.OrderStatusPending,
+		Lines:         make([]schema.OrderLine, len(req.Lines)),
+		CreatedAt:     timeNow,
+		UpdatedAt:     timeNow,
+	}
+
+	// a cake is locked by Find within the transaction
+	err := withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		rec.Currency, rec.Total = "", 0
+		for i, line := range req.Lines {
+			cake, err := s.Cakes.Find(ctx, line.CakeID)
+			if err != nil {
+				if eris.Is(err, repository.ErrRecordNotFound) {
+					return ErrOrderCakeNotFound
+				}
+				return err
+			}
+
+			if i == 0 {
+				rec.Currency = cake.Currency
+			}
+			if cake.Currency != rec.Currency {
+				return ErrCurrencyMismatch
+			}
+
+			rec.Lines[i] = schema.OrderLine{
+				CakeID:    cake.ID,
+				CakeTitle: cake.Title,
+				Quantity:  line.Quantity,
+				UnitPrice: cake.Price,
+			}
+			rec.Total += int64(line.Quantity) * cake.Price
+		}
+
+		return s.Repo.Insert(ctx, &rec)
+	})
+	if err != nil {
+		return err
+	}
+	req.ID, req.Status = rec.ID, rec.Status
+
+	return nil
+}
+
+type TransitionRequest struct {
+	ID     int    `json:"-"`
+	Status string `json:"status" validate:"required,oneof=pending confirmed baking ready picked_up cancelled"`
+}
+
+// Transition move an order into the next status, a move which is not on OrderTransitions is rejected
+func (s *Order) Transition(ctx context.Context, req *TransitionRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	cur, err := s.Repo.Find(ctx, req.ID)
+	if err != nil {
+		return err
+	}
+
+	if !CanTransition(cur.Status, req.Status) {
+		return ErrInvalidTransition
+	}
+
+	return s.Repo.UpdateStatus(ctx, req.ID, cur.Status, req.Status, time.Now())
+}
@@ -0,0 +1,117 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Order_Service_Insert(t *testing.T) {
+	var (
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.OrderMock{}
+		tx       = &repository.TransactorMock{Mock: mock.Mock{}}
+		svc      = &service.Order{Repo: repo, Cakes: cakeRepo, Tx: tx}
+		ctx      = context.Background()
+	)
+
+	// the cakes are checked and the order is inserted in a single transaction
+	tx.On("WithinTx", ctx).Return(nil).Times(3)
+	cakeRepo.On("Find", ctx, 1).Return(&cake, nil)
+	cakeRepo.On("Find", ctx, 3).Return(nil, repository.ErrRecordNotFound)
+	cakeRepo.On("Find", ctx, 4).Return(&schema.Cake{ID: 4, Title: "Brownies", Price: 5, Currency: "USD"}, nil)
+	repo.On("Insert", ctx, &schema.Order{
+		CustomerName: "Budi",
+		Status:       schema.OrderStatusPending,
+		Total:        300000,
+		Currency:     "IDR",
+		Lines: []schema.OrderLine{
+			{CakeID: 1, CakeTitle: cake.Title, Quantity: 2, UnitPrice: 150000},
+		},
+	}).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*schema.Order).ID = 5
+	})
+
+	req := &service.OrderRequest{
+		CustomerName: "Budi",
+		Lines:        []service.OrderLineRequest{{CakeID: 1, Quantity: 2}},
+	}
+	assert.NoError(t, svc.Insert(ctx, req))
+	assert.Equal(t, 5, req.ID)
+	assert.Equal(t, schema.OrderStatusPending, req.Status)
+
+	err := svc.Insert(ctx, &service.OrderRequest{
+		CustomerName: "Budi",
+		Lines:        []service.OrderLineRequest{{CakeID: 3, Quantity: 1}},
+	})
+	assert.ErrorIs(t, err, service.ErrOrderCakeNotFound)
+
+	err = svc.Insert(ctx, &service.OrderRequest{
+		CustomerName: "Budi",
+		Lines:        []service.OrderLineRequest{{CakeID: 1, Quantity: 1}, {CakeID: 4, Quantity: 1}},
+	})
+	assert.ErrorIs(t, err, service.ErrCurrencyMismatch)
+	tx.AssertExpectations(t)
+}
+
+func Test_Order_Service_Transition(t *testing.T) {
+	var (
+		repo = &repository.OrderMock{}
+		svc  = &service.Order{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	repo.On("Find", ctx, 1).Return(&schema.Order{ID: 1, Status: schema.OrderStatusPending}, nil)
+	repo.On("Find", ctx, 2).Return(&schema.Order{ID: 2, Status: schema.OrderStatusPickedUp}, nil)
+	repo.On("UpdateStatus", ctx, 1, schema.OrderStatusPending, schema.OrderStatusConfirmed).Return(nil)
+	repo.On("UpdateStatus", ctx, 1, schema.OrderStatusPending, schema.OrderStatusCancelled).Return(repository.ErrStatusConflict)
+
+	tests := []struct {
+		Name          string
+		Request       *service.TransitionRequest
+		ExpectedError error
+	}{
+		{
+			Name:    "Confirm",
+			Request: &service.TransitionRequest{ID: 1, Status: schema.OrderStatusConfirmed},
+		},
+		{
+			Name:          "Skip_Status",
+			Request:       &service.TransitionRequest{ID: 1, Status: schema.OrderStatusReady},
+			ExpectedError: service.ErrInvalidTransition,
+		},
+		{
+			Name:          "Final_Status",
+			Request:       &service.TransitionRequest{ID: 2, Status: schema.OrderStatusCancelled},
+			ExpectedError: service.ErrInvalidTransition,
+		},
+		{
+			Name:          "Concurrent_Change",
+			Request:       &service.TransitionRequest{ID: 1, Status: schema.OrderStatusCancelled},
+			ExpectedError: repository.ErrStatusConflict,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := svc.Transition(ctx, test.Request)
+			if test.ExpectedError != nil {
+				assert.ErrorIs(t, err, test.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_Order_Service_Can_Transition(t *testing.T) {
+	assert.True(t, service.CanTransition(schema.OrderStatusReady, schema.OrderStatusPickedUp))
+	assert.True(t, service.CanTransition(schema.OrderStatusBaking, schema.OrderStatusCancelled))
+	assert.False(t, service.CanTransition(schema.OrderStatusPending, schema.OrderStatusPickedUp))
+	assert.False(t, service.CanTransition(schema.OrderStatusCancelled, schema.OrderStatusPending))
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type ReviewRepository interface {
+	FindAll(ctx context.Context, fil *repository.ReviewFilter) ([]schema.Review, error)
+	Stars(ctx context.Context, cakeID int) (map[int]int, error)
+	Insert(ctx context.Context, rec *schema.Review) error
+	Delete(ctx context.Context, cakeID, id int) error
+}
+
+type Review struct {
+	Repo  ReviewRepository
+	Cakes CakeRepository
+}
+
+type FindAllReviewRequest struct {
+	CakeID int `json:"-"`
+	Score  int `json:"score"`
+	Limit  int `json:"limit"`
+	Before int `json:"before"`
+}
+
+func (s *Review) FindAll(ctx context.Context, req *FindAllReviewRequest) ([]schema.Review, error) {
+	if req == nil {
+		return nil, ErrRequestNil
+	}
+
+	if _, err := s.Cakes.Find(ctx, req.CakeID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindAll(ctx, &repository.ReviewFilter{
+		CakeID: req.CakeID,
+		Score:  req.Score,
+		Limit:  req.Limit,
+		Before: req.Before,
+	})
+}
+
+// Summary report a rating breakdown of a cake, every star from 1 to 5 is present on the breakdown
+func (s *Review) Summary(ctx context.Context, cakeID int) (*schema.RatingSummary, error) {
+	cake, err := s.Cakes.Find(ctx, cakeID)
+	if err != nil {
+		return nil, err
+	}
+
+	stars, err := s.Repo.Stars(ctx, cakeID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := schema.RatingSummary{
+		CakeID: cakeID,
+		Rating: cake.Rating,
+		Stars:  make(map[int]int, 5),
+	}
+
+	sum := 0
+	for score := 1; score <= 5; score++ {
+		res.Stars[score] = stars[score]
+		res.Count += stars[score]
+		sum += score * stars[score]
+	}
+	if res.Count > 0 {
+		res.Average = float64(sum) / float64(res.Count)
+	}
+
+	return &res, nil
+}
+
+type ReviewRequest struct {
+	CakeID int    `json:"-"`
+	Author string `json:"author" validate:"required,max=100"`
+	Score  int    `json:"score" validate:"required,min=1,max=5"`
+	Body   string `json:"body" validate:"max=2000"`
+
+	// ID is filled by Insert
+	ID int `json:"-"`
+}
+
+// Insert write a review of an active cake, the cake rating is recomputed with it
+func (s *Review) Insert(ctx context.Context, req *ReviewRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	rec := schema.Review{
+		CakeID:    req.CakeID,
+		Author:    req.Author,
+		Score:     req.Score,
+		Body:      req.Body,
+		CreatedAt: time.Now(),
+	}
+	if err := s.Repo.Insert(ctx, &rec); err != nil {
+		return err
+	}
+	req.ID = rec.ID
+
+	return nil
+}
+
+func (s *Review) Delete(ctx context.Context, cakeID, id int) error {
+	return s.Repo.Delete(ctx, cakeID, id)
+}
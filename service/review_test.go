@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Review_Service_Insert(t *testing.T) {
+	var (
+		repo = &repository.ReviewMock{}
+		svc  = &service.Review{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	repo.On("Insert", ctx, &schema.Review{CakeID: 1, Author: "Siti", Score: 4}).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*schema.Review).ID = 3
+	})
+	repo.On("Insert", ctx, &schema.Review{CakeID: 2, Author: "Siti", Score: 4}).Return(repository.ErrRecordNotFound)
+
+	req := &service.ReviewRequest{CakeID: 1, Author: "Siti", Score: 4}
+	assert.NoError(t, svc.Insert(ctx, req))
+	assert.Equal(t, 3, req.ID)
+
+	err := svc.Insert(ctx, &service.ReviewRequest{CakeID: 2, Author: "Siti", Score: 4})
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Review_Service_Summary(t *testing.T) {
+	var (
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.ReviewMock{}
+		svc      = &service.Review{Repo: repo, Cakes: cakeRepo}
+		ctx      = context.Background()
+	)
+
+	rated := cake
+	rated.Rating = 3.6
+	cakeRepo.On("Find", ctx, rated.ID).Return(&rated, nil)
+	cakeRepo.On("Find", ctx, 9).Return(nil, repository.ErrRecordNotFound)
+	repo.On("Stars", ctx, rated.ID).Return(map[int]int{5: 3, 2: 1}, nil)
+
+	res, err := svc.Summary(ctx, rated.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &schema.RatingSummary{
+		CakeID:  rated.ID,
+		Rating:  3.6,
+		Average: 4.25,
+		Count:   4,
+		Stars:   map[int]int{1: 0, 2: 1, 3: 0, 4: 0, 5: 3},
+	}, res)
+
+	res, err = svc.Summary(ctx, 9)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Review_Service_Find_All(t *testing.T) {
+	var (
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.ReviewMock{}
+		svc      = &service.Review{Repo: repo, Cakes: cakeRepo}
+		ctx      = context.Background()
+	)
+
+	cakeRepo.On("Find", ctx, cake.ID).Return(&cake, nil)
+	repo.On("FindAll", ctx, &repository.ReviewFilter{CakeID: cake.ID, Limit: 5}).Return([]schema.Review{{ID: 1}}, nil)
+
+	res, err := svc.FindAll(ctx, &service.FindAllReviewRequest{CakeID: cake.ID, Limit: 5})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+}
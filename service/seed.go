@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+)
+
+var (
+	ErrInvalidFixture = eris.New("invalid fixture")
+)
+
+type SeedResult struct {
+	Inserted int `json:"inserted"`
+	Skipped  int `json:"skipped"`
+}
+
+// Seed insert a cakes of a fixtures which does not exist yet, a cake is identified by it's title
+// (case-insensitive) so seeding the same fixtures again insert nothing. when reset is set every cakes
+// and their revisions is deleted first on the same transaction. a fixture is validated like a request
+// of the api, nothing is inserted when one of them is invalid
+func (s *Cake) Seed(ctx context.Context, reqs []CakeRequest, reset bool) (*SeedResult, error) {
+	for i := range reqs {
+		if errs := util.Validation(&reqs[i]); len(errs) > 0 {
+			return nil, eris.Wrapf(ErrInvalidFixture, "cake %q: %s %s", reqs[i].Title, errs[0].Key, errs[0].Message)
+		}
+	}
+
+	var res *SeedResult
+	err := withinTx(ctx, s.Tx, func(ctx context.Context) error {
+		res = &SeedResult{}
+
+		if reset {
+			if err := s.Repo.Truncate(ctx); err != nil {
+				return err
+			}
+
+			// a revision does not reference a cake with a foreign key, so it's not deleted by a cascade
+			if s.Revisions != nil {
+				if err := s.Revisions.Truncate(ctx); err != nil {
+					return err
+				}
+			}
+		}
+
+		titles, err := s.titles(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, req := range reqs {
+			key := strings.ToLower(req.Title)
+			if titles[key] {
+				res.Skipped++
+				continue
+			}
+
+			// a fixture is kept as it is, so a retried transaction insert the same cakes
+			if err := s.Insert(ctx, &req); err != nil {
+				return eris.Wrapf(err, "seed cake %q", req.Title)
+			}
+			titles[key] = true
+			res.Inserted++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// titles list a lower case titles of every cakes which is not trashed
+func (s *Cake) titles(ctx context.Context) (map[string]bool, error) {
+	var (
+		res = map[string]bool{}
+		fil = repository.FindAllFilter{Limit: repository.MaxLimit}
+	)
+	for {
+		cakes, err := s.Repo.FindAll(ctx, &fil)
+		if err != nil {
+			if eris.Is(err, repository.ErrRecordNotFound) {
+				return res, nil
+			}
+			return nil, err
+		}
+
+		for _, c := range cakes {
+			res[strings.ToLower(c.Title)] = true
+		}
+
+		if !fil.HasMore {
+			return res, nil
+		}
+		fil.Cursor = fil.NextCursor
+	}
+}
@@ -0,0 +1,117 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/fixtures"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Cake_Service_Seed(t *testing.T) {
+	util.NewValidator()
+
+	var (
+		repo = &repository.CakeMock{Mock: mock.Mock{}}
+		tx   = &repository.TransactorMock{Mock: mock.Mock{}}
+		srv  = &service.Cake{Repo: repo, Tx: tx}
+	)
+
+	fixture := func(title string) service.CakeRequest {
+		return service.CakeRequest{Title: title, Description: "Test Description", Image: "https://example.com/cake.jpeg"}
+	}
+
+	t.Run("Skip_Existing", func(t *testing.T) {
+		// an insert join the transaction of the seed
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Twice()
+		repo.Mock.On("FindAll", context.Background(), mock.MatchedBy(func(fil *repository.FindAllFilter) bool {
+			return fil.Cursor == ""
+		})).Return([]schema.Cake{cake}, nil).Run(func(args mock.Arguments) {
+			fil := args.Get(1).(*repository.FindAllFilter)
+			fil.NextCursor, fil.HasMore = "next", true
+		}).Once()
+		repo.Mock.On("FindAll", context.Background(), mock.MatchedBy(func(fil *repository.FindAllFilter) bool {
+			return fil.Cursor == "next"
+		})).Return([]schema.Cake{{ID: 2, Title: "Lemon Cheesecake"}}, nil).Run(func(args mock.Arguments) {
+			fil := args.Get(1).(*repository.FindAllFilter)
+			fil.NextCursor, fil.HasMore = "", false
+		}).Once()
+		repo.Mock.On("Insert", context.Background(), mock.MatchedBy(func(rec *schema.Cake) bool {
+			return rec.Title == "Carrot Cake"
+		})).Return(nil).Once()
+
+		// a title is compared case-insensitively, a repeated fixture is inserted once
+		res, err := srv.Seed(context.Background(), []service.CakeRequest{
+			fixture("test title"),
+			fixture("Lemon Cheesecake"),
+			fixture("Carrot Cake"),
+			fixture("CARROT CAKE"),
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, &service.SeedResult{Inserted: 1, Skipped: 3}, res)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Times(3)
+		repo.Mock.On("Truncate", context.Background()).Return(nil).Once()
+		repo.Mock.On("FindAll", context.Background(), mock.Anything).Return([]schema.Cake(nil), repository.ErrRecordNotFound).Once()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Twice()
+
+		res, err := srv.Seed(context.Background(), []service.CakeRequest{fixture("Test Title"), fixture("Carrot Cake")}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, &service.SeedResult{Inserted: 2}, res)
+	})
+
+	t.Run("Reset_Revisions", func(t *testing.T) {
+		var (
+			revisions = &repository.CakeRevisionMock{Mock: mock.Mock{}}
+			srv       = &service.Cake{Repo: repo, Tx: tx, Revisions: revisions}
+		)
+
+		// a revision of a deleted cake is not left behind, a seeded cake has it's own insert revision
+		tx.Mock.On("WithinTx", context.Background()).Return(nil).Twice()
+		repo.Mock.On("Truncate", context.Background()).Return(nil).Once()
+		revisions.Mock.On("Truncate", context.Background()).Return(nil).Once()
+		repo.Mock.On("FindAll", context.Background(), mock.Anything).Return([]schema.Cake(nil), repository.ErrRecordNotFound).Once()
+		repo.Mock.On("Insert", context.Background(), mock.Anything).Return(nil).Once()
+		revisions.Mock.On("Insert", context.Background(), mock.MatchedBy(func(rec *schema.CakeRevision) bool {
+			return rec.Action == schema.RevisionActionInsert
+		})).Return(nil).Once()
+
+		res, err := srv.Seed(context.Background(), []service.CakeRequest{fixture("Test Title")}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, &service.SeedResult{Inserted: 1}, res)
+		revisions.AssertExpectations(t)
+	})
+
+	t.Run("Invalid_Fixture", func(t *testing.T) {
+		invalid := fixture("Test Title")
+		invalid.Image = ""
+
+		res, err := srv.Seed(context.Background(), []service.CakeRequest{fixture("Carrot Cake"), invalid}, false)
+		assert.ErrorIs(t, err, service.ErrInvalidFixture)
+		assert.Nil(t, res)
+	})
+
+	repo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func Test_Cake_Service_Seed_Fixtures(t *testing.T) {
+	json := `{"cakes": [{"title": "Lemon Cheesecake", "description": "A cheesecake", "image": "https://example.com/a.jpeg", "price": 1000, "tags": ["citrus"]}]}`
+	yaml := "cakes:\n  - title: Lemon Cheesecake\n    description: A cheesecake\n    image: https://example.com/a.jpeg\n    price: 1000\n    tags: [citrus]\n"
+
+	fromJSON, err := fixtures.Decode("cakes.json", []byte(json))
+	assert.NoError(t, err)
+	fromYAML, err := fixtures.Decode("cakes.yml", []byte(yaml))
+	assert.NoError(t, err)
+	assert.Equal(t, fromJSON, fromYAML)
+
+	_, err = fixtures.Decode("cakes.csv", []byte(json))
+	assert.ErrorIs(t, err, fixtures.ErrUnknownFormat)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/zufzuf/cake-store/libs/util"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+)
+
+var (
+	ErrInvalidMovement = eris.New("invalid stock movement quantity")
+)
+
+type StockRepository interface {
+	Find(ctx context.Context, cakeID int) (*schema.Stock, error)
+	FindAll(ctx context.Context, fil *repository.StockFilter) ([]schema.Stock, error)
+	FindMovements(ctx context.Context, fil *repository.MovementFilter) ([]schema.StockMovement, error)
+	Move(ctx context.Context, mov *schema.StockMovement) error
+}
+
+type Stock struct {
+	Repo  StockRepository
+	Cakes CakeRepository
+}
+
+// Find find a stock of a cake, a cake without any movement has zero quantity
+func (s *Stock) Find(ctx context.Context, cakeID int) (*schema.Stock, error) {
+	if _, err := s.Cakes.Find(ctx, cakeID); err != nil {
+		return nil, err
+	}
+
+	res, err := s.Repo.Find(ctx, cakeID)
+	if err != nil {
+		if eris.Is(err, repository.ErrRecordNotFound) {
+			return &schema.Stock{CakeID: cakeID}, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+type StockRequest struct {
+	Below *int `json:"below"`
+}
+
+func (s *Stock) FindAll(ctx context.Context, req *StockRequest) ([]schema.Stock, error) {
+	if req == nil {
+		return nil, ErrRequestNil
+	}
+
+	return s.Repo.FindAll(ctx, &repository.StockFilter{
+		Below: req.Below,
+	})
+}
+
+type MovementRequest struct {
+	CakeID int    `json:"-"`
+	Kind   string `json:"kind"`
+	Limit  int    `json:"limit"`
+	Before int    `json:"before"`
+}
+
+func (s *Stock) FindMovements(ctx context.Context, req *MovementRequest) ([]schema.StockMovement, error) {
+	if req == nil {
+		return nil, ErrRequestNil
+	}
+
+	if _, err := s.Cakes.Find(ctx, req.CakeID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindMovements(ctx, &repository.MovementFilter{
+		CakeID: req.CakeID,
+		Kind:   req.Kind,
+		Limit:  req.Limit,
+		Before: req.Before,
+	})
+}
+
+type MoveRequest struct {
+	CakeID int `json:"-"`
+
+	// Quantity is a positive amount for baked, sold and wasted,
+	// adjusted take a signed amount to correct a stock count
+	Kind     string `json:"kind" validate:"required,oneof=baked sold wasted adjusted"`
+	Quantity int    `json:"quantity" validate:"required"`
+	Note     string `json:"note" validate:"max=255"`
+
+	// ID and Balance is filled by Move
+	ID      int `json:"-"`
+	Balance int `json:"-"`
+}
+
+// Move record a stock movement of an active cake, sold and wasted decrease the stock
+func (s *Stock) Move(ctx context.Context, req *MoveRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	quantity := req.Quantity
+	switch req.Kind {
+	case schema.StockMovementBaked:
+	case schema.StockMovementSold, schema.StockMovementWasted:
+		quantity = -quantity
+	case schema.StockMovementAdjusted:
+	default:
+		return ErrInvalidMovement
+	}
+	if req.Kind != schema.StockMovementAdjusted && req.Quantity <= 0 {
+		return ErrInvalidMovement
+	}
+
+	// the cake is checked by the repository in the same transaction as the movement
+	mov := schema.StockMovement{
+		CakeID:    req.CakeID,
+		Kind:      req.Kind,
+		Quantity:  quantity,
+		Note:      req.Note,
+		TrackerID: util.CTXTracker(ctx),
+		CreatedAt: time.Now(),
+	}
+	if err := s.Repo.Move(ctx, &mov); err != nil {
+		return err
+	}
+	req.ID, req.Balance = mov.ID, mov.Balance
+
+	return nil
+}
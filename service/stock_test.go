@@ -0,0 +1,109 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Stock_Service_Find(t *testing.T) {
+	var (
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.StockMock{}
+		svc      = &service.Stock{Repo: repo, Cakes: cakeRepo}
+		ctx      = context.Background()
+	)
+
+	cakeRepo.On("Find", ctx, 1).Return(&cake, nil)
+	cakeRepo.On("Find", ctx, 2).Return(&cakes[1], nil)
+	cakeRepo.On("Find", ctx, 3).Return(nil, repository.ErrRecordNotFound)
+	repo.On("Find", ctx, 1).Return(&schema.Stock{CakeID: 1, Quantity: 4}, nil)
+	repo.On("Find", ctx, 2).Return(nil, repository.ErrRecordNotFound)
+
+	res, err := svc.Find(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, res.Quantity)
+
+	res, err = svc.Find(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &schema.Stock{CakeID: 2}, res)
+
+	res, err = svc.Find(ctx, 3)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+}
+
+func Test_Stock_Service_Move(t *testing.T) {
+	var (
+		repo = &repository.StockMock{}
+		svc  = &service.Stock{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	tests := []struct {
+		Name             string
+		Request          *service.MoveRequest
+		ExpectedQuantity int
+		ExpectedError    error
+	}{
+		{
+			Name:             "Baked",
+			Request:          &service.MoveRequest{CakeID: 1, Kind: schema.StockMovementBaked, Quantity: 10},
+			ExpectedQuantity: 10,
+		},
+		{
+			Name:             "Sold",
+			Request:          &service.MoveRequest{CakeID: 1, Kind: schema.StockMovementSold, Quantity: 3},
+			ExpectedQuantity: -3,
+		},
+		{
+			Name:             "Adjusted",
+			Request:          &service.MoveRequest{CakeID: 1, Kind: schema.StockMovementAdjusted, Quantity: -1},
+			ExpectedQuantity: -1,
+		},
+		{
+			Name:          "Negative_Wasted",
+			Request:       &service.MoveRequest{CakeID: 1, Kind: schema.StockMovementWasted, Quantity: -1},
+			ExpectedError: service.ErrInvalidMovement,
+		},
+		{
+			Name:             "Insufficient",
+			Request:          &service.MoveRequest{CakeID: 1, Kind: schema.StockMovementSold, Quantity: 99},
+			ExpectedQuantity: -99,
+			ExpectedError:    repository.ErrInsufficientStock,
+		},
+		{
+			Name:             "Cake_Deleted",
+			Request:          &service.MoveRequest{CakeID: 1, Kind: schema.StockMovementBaked, Quantity: 2},
+			ExpectedQuantity: 2,
+			ExpectedError:    repository.ErrRecordNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.ExpectedQuantity != 0 {
+				repo.On("Move", ctx, mock.MatchedBy(func(mov *schema.StockMovement) bool {
+					return mov.Quantity == test.ExpectedQuantity && mov.Kind == test.Request.Kind
+				})).Return(test.ExpectedError).Run(func(args mock.Arguments) {
+					args.Get(1).(*schema.StockMovement).Balance = 7
+				}).Once()
+			}
+
+			err := svc.Move(ctx, test.Request)
+			if test.ExpectedError != nil {
+				assert.ErrorIs(t, err, test.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 7, test.Request.Balance)
+		})
+	}
+
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type TagRepository interface {
+	Find(ctx context.Context, id int) (*schema.Tag, error)
+	FindAll(ctx context.Context) ([]schema.Tag, error)
+	FindByCakeIDs(ctx context.Context, ids []int) (map[int][]schema.Tag, error)
+	Insert(ctx context.Context, rec *schema.Tag) error
+	Update(ctx context.Context, rec *schema.Tag) error
+	Delete(ctx context.Context, id int) error
+	SetCakeTags(ctx context.Context, cakeID int, names []string) error
+}
+
+type Tag struct {
+	Repo TagRepository
+}
+
+func (s *Tag) Find(ctx context.Context, id int) (*schema.Tag, error) {
+	return s.Repo.Find(ctx, id)
+}
+
+func (s *Tag) FindAll(ctx context.Context) ([]schema.Tag, error) {
+	return s.Repo.FindAll(ctx)
+}
+
+type TagRequest struct {
+	ID   int    `json:"-"`
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+func (s *Tag) Insert(ctx context.Context, req *TagRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	rec := schema.Tag{
+		Name:      NormalizeTag(req.Name),
+		CreatedAt: time.Now(),
+	}
+	if err := s.Repo.Insert(ctx, &rec); err != nil {
+		return err
+	}
+	req.ID = rec.ID
+
+	return nil
+}
+
+func (s *Tag) Update(ctx context.Context, req *TagRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	if _, err := s.Repo.Find(ctx, req.ID); err != nil {
+		return err
+	}
+
+	rec := schema.Tag{
+		ID:   req.ID,
+		Name: NormalizeTag(req.Name),
+	}
+	return s.Repo.Update(ctx, &rec)
+}
+
+func (s *Tag) Delete(ctx context.Context, id int) error {
+	return s.Repo.Delete(ctx, id)
+}
+
+// NormalizeTag trim and lower a tag name, so "Vegan " and "vegan" is the same tag
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeTags normalize a tag names and remove an empty or duplicate one
+func normalizeTags(names []string) []string {
+	var (
+		res  = make([]string, 0, len(names))
+		seen = map[string]bool{}
+	)
+	for _, name := range names {
+		name = NormalizeTag(name)
+		if len(name) == 0 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, name)
+	}
+	return res
+}
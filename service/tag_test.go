@@ -0,0 +1,49 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Tag_Service_Insert(t *testing.T) {
+	var (
+		repo = &repository.TagMock{}
+		svc  = &service.Tag{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	repo.On("Insert", ctx, &schema.Tag{Name: "vegan"}).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.Tag).ID = 1
+		}).
+		Return(nil)
+
+	req := &service.TagRequest{Name: " Vegan "}
+	assert.NoError(t, svc.Insert(ctx, req))
+	assert.Equal(t, 1, req.ID)
+}
+
+func Test_Tag_Service_Update(t *testing.T) {
+	var (
+		repo = &repository.TagMock{}
+		svc  = &service.Tag{Repo: repo}
+		ctx  = context.Background()
+	)
+
+	repo.On("Find", ctx, 1).Return(&schema.Tag{ID: 1, Name: "vegan"}, nil)
+	repo.On("Find", ctx, 2).Return(nil, repository.ErrRecordNotFound)
+	repo.On("Update", ctx, &schema.Tag{ID: 1, Name: "plant-based"}).Return(nil)
+
+	assert.NoError(t, svc.Update(ctx, &service.TagRequest{ID: 1, Name: "Plant-Based"}))
+	assert.ErrorIs(t, svc.Update(ctx, &service.TagRequest{ID: 2, Name: "x"}), repository.ErrRecordNotFound)
+}
+
+func Test_Tag_Service_Normalize(t *testing.T) {
+	assert.Equal(t, "gluten-free", service.NormalizeTag("  Gluten-Free "))
+}
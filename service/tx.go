@@ -0,0 +1,28 @@
+package service
+
+import "context"
+
+// Transactor run a unit of work in a transaction which is passed through the context, a repository
+// called with the context run on the transaction. fn may be retried, e.g. on a deadlock
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// WithinSavepoint run fn in a savepoint of the transaction of ctx, a failed fn only roll back it's own changes
+	WithinSavepoint(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// withinTx run fn in a transaction of tx, fn is run without a transaction when tx is nil
+func withinTx(ctx context.Context, tx Transactor, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.WithinTx(ctx, fn)
+}
+
+// withinSavepoint run fn in a savepoint of tx, fn is run without a savepoint when tx is nil
+func withinSavepoint(ctx context.Context, tx Transactor, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.WithinSavepoint(ctx, fn)
+}
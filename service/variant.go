@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/zufzuf/cake-store/schema"
+)
+
+type CakeVariantRepository interface {
+	Find(ctx context.Context, cakeID, id int) (*schema.CakeVariant, error)
+	FindAll(ctx context.Context, cakeID int) ([]schema.CakeVariant, error)
+	Insert(ctx context.Context, rec *schema.CakeVariant) error
+	Update(ctx context.Context, rec *schema.CakeVariant) error
+	Delete(ctx context.Context, cakeID, id int) error
+}
+
+type Variant struct {
+	Repo  CakeVariantRepository
+	Cakes CakeRepository
+}
+
+// Find find a variant of an active cake, a variant of a deleted cake is not found
+func (s *Variant) Find(ctx context.Context, cakeID, id int) (*schema.CakeVariant, error) {
+	if _, err := s.Cakes.Find(ctx, cakeID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.Find(ctx, cakeID, id)
+}
+
+func (s *Variant) FindAll(ctx context.Context, cakeID int) ([]schema.CakeVariant, error) {
+	if _, err := s.Cakes.Find(ctx, cakeID); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindAll(ctx, cakeID)
+}
+
+// VariantRequest is a size or flavour of a cake, Options is a free form attributes
+// such as {"size": "8\"", "flavour": "chocolate"}
+type VariantRequest struct {
+	ID      int               `json:"-"`
+	CakeID  int               `json:"-"`
+	SKU     string            `json:"sku" validate:"required,max=64"`
+	Name    string            `json:"name" validate:"required,max=100"`
+	Options map[string]string `json:"options" validate:"omitempty,max=8,dive,keys,required,max=32,endkeys,required,max=64"`
+	Price   int64             `json:"price" validate:"min=0"`
+
+	// Stock is set as is, it's a display only quantity which is not tracked by the stock ledger
+	Stock int `json:"stock" validate:"min=0"`
+}
+
+func (s *Variant) Insert(ctx context.Context, req *VariantRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	if _, err := s.Cakes.Find(ctx, req.CakeID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rec := schema.CakeVariant{
+		CakeID:    req.CakeID,
+		SKU:       NormalizeSKU(req.SKU),
+		Name:      req.Name,
+		Options:   req.Options,
+		Price:     req.Price,
+		Stock:     req.Stock,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.Repo.Insert(ctx, &rec); err != nil {
+		return err
+	}
+	req.ID = rec.ID
+
+	return nil
+}
+
+func (s *Variant) Update(ctx context.Context, req *VariantRequest) error {
+	if req == nil {
+		return ErrRequestNil
+	}
+
+	if _, err := s.Cakes.Find(ctx, req.CakeID); err != nil {
+		return err
+	}
+
+	cur, err := s.Repo.Find(ctx, req.CakeID, req.ID)
+	if err != nil {
+		return err
+	}
+
+	rec := *cur
+	rec.SKU = NormalizeSKU(req.SKU)
+	rec.Name = req.Name
+	rec.Options = req.Options
+	rec.Price = req.Price
+	rec.Stock = req.Stock
+	rec.UpdatedAt = time.Now()
+
+	return s.Repo.Update(ctx, &rec)
+}
+
+func (s *Variant) Delete(ctx context.Context, cakeID, id int) error {
+	if _, err := s.Cakes.Find(ctx, cakeID); err != nil {
+		return err
+	}
+
+	return s.Repo.Delete(ctx, cakeID, id)
+}
+
+// NormalizeSKU trim and upper a sku, so "ck-08 " and "CK-08" is the same sku
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zufzuf/cake-store/repository"
+	"github.com/zufzuf/cake-store/schema"
+	"github.com/zufzuf/cake-store/service"
+)
+
+func Test_Variant_Service_Insert(t *testing.T) {
+	var (
+		repo  = &repository.CakeVariantMock{Mock: mock.Mock{}}
+		cakes = &repository.CakeMock{Mock: mock.Mock{}}
+		svc   = &service.Variant{Repo: repo, Cakes: cakes}
+		ctx   = context.Background()
+	)
+
+	t.Run("Inserted", func(t *testing.T) {
+		cakes.On("Find", ctx, cake.ID).Return(&cake, nil).Once()
+		repo.On("Insert", ctx, &schema.CakeVariant{
+			CakeID:  cake.ID,
+			SKU:     "CHEESE-8IN",
+			Name:    "8 inch",
+			Options: map[string]string{"size": `8"`},
+			Price:   250000,
+			Stock:   4,
+		}).Run(func(args mock.Arguments) {
+			args.Get(1).(*schema.CakeVariant).ID = 3
+		}).Return(nil).Once()
+
+		req := service.VariantRequest{
+			CakeID:  cake.ID,
+			SKU:     " cheese-8in ",
+			Name:    "8 inch",
+			Options: map[string]string{"size": `8"`},
+			Price:   250000,
+			Stock:   4,
+		}
+		assert.NoError(t, svc.Insert(ctx, &req))
+		assert.Equal(t, 3, req.ID)
+	})
+
+	t.Run("Cake_Not_Found", func(t *testing.T) {
+		cakes.On("Find", ctx, 9).Return(nil, repository.ErrRecordNotFound).Once()
+		assert.ErrorIs(t, svc.Insert(ctx, &service.VariantRequest{CakeID: 9, SKU: "X", Name: "X"}), repository.ErrRecordNotFound)
+	})
+
+	t.Run("Duplicate_SKU", func(t *testing.T) {
+		cakes.On("Find", ctx, cake.ID).Return(&cake, nil).Once()
+		repo.On("Insert", ctx, mock.Anything).Return(repository.ErrDuplicateRecord).Once()
+		assert.ErrorIs(t, svc.Insert(ctx, &service.VariantRequest{CakeID: cake.ID, SKU: "CHEESE-8IN", Name: "8 inch"}), repository.ErrDuplicateRecord)
+	})
+
+	repo.AssertExpectations(t)
+	cakes.AssertExpectations(t)
+}
+
+func Test_Variant_Service_Update(t *testing.T) {
+	var (
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.CakeVariantMock{Mock: mock.Mock{}}
+		svc      = &service.Variant{Repo: repo, Cakes: cakeRepo}
+		ctx      = context.Background()
+	)
+
+	cakeRepo.On("Find", ctx, cake.ID).Return(&cake, nil).Twice()
+
+	current := &schema.CakeVariant{ID: 1, CakeID: cake.ID, SKU: "CHEESE-8IN", Name: "8 inch", Options: map[string]string{}, Price: 250000}
+	repo.On("Find", ctx, cake.ID, 1).Return(current, nil).Once()
+	repo.On("Find", ctx, cake.ID, 9).Return(nil, repository.ErrRecordNotFound).Once()
+	repo.On("Update", ctx, &schema.CakeVariant{
+		ID:      1,
+		CakeID:  cake.ID,
+		SKU:     "CHEESE-8IN-CHOCO",
+		Name:    "8 inch chocolate",
+		Options: map[string]string{"size": `8"`, "flavour": "chocolate"},
+		Price:   275000,
+		Stock:   2,
+	}).Return(nil).Once()
+
+	err := svc.Update(ctx, &service.VariantRequest{
+		ID:      1,
+		CakeID:  cake.ID,
+		SKU:     "cheese-8in-choco",
+		Name:    "8 inch chocolate",
+		Options: map[string]string{"size": `8"`, "flavour": "chocolate"},
+		Price:   275000,
+		Stock:   2,
+	})
+	assert.NoError(t, err)
+
+	err = svc.Update(ctx, &service.VariantRequest{ID: 9, CakeID: cake.ID, SKU: "X", Name: "X"})
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	repo.AssertExpectations(t)
+}
+
+func Test_Variant_Service_Cake_Deleted(t *testing.T) {
+	var (
+		cakeRepo = &repository.CakeMock{Mock: mock.Mock{}}
+		repo     = &repository.CakeVariantMock{Mock: mock.Mock{}}
+		svc      = &service.Variant{Repo: repo, Cakes: cakeRepo}
+		ctx      = context.Background()
+	)
+
+	// a variant of a deleted cake can not be read or changed
+	cakeRepo.On("Find", ctx, 9).Return(nil, repository.ErrRecordNotFound).Times(3)
+
+	_, err := svc.Find(ctx, 9, 1)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	err = svc.Update(ctx, &service.VariantRequest{ID: 1, CakeID: 9, SKU: "X", Name: "X"})
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	err = svc.Delete(ctx, 9, 1)
+	assert.ErrorIs(t, err, repository.ErrRecordNotFound)
+
+	cakeRepo.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}